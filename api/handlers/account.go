@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+)
+
+const (
+	// SessionCookieName is the cookie holding a web UI login.
+	SessionCookieName = "session"
+
+	dashboardPageSize = 20
+
+	// ssoFlowCookieName holds the state of a single sign-on in progress.
+	ssoFlowCookieName = "sso_flow"
+	ssoFlowMaxAge     = 10 * time.Minute
+)
+
+type AccountHandler struct {
+	users   *application.UserService
+	service *application.ShortenerService
+	tmpl    *template.Template
+
+	sso     *application.SSOService
+	ssoName string
+
+	secureCookies      bool
+	allowRegistration  bool
+	allowPasswordLogin bool
+}
+
+type AccountHandlerOption func(*AccountHandler)
+
+// WithSecureCookies only sends the session cookie over HTTPS.
+func WithSecureCookies() AccountHandlerOption {
+	return func(h *AccountHandler) {
+		h.secureCookies = true
+	}
+}
+
+// WithoutRegistration hides the register page, for instances whose accounts
+// are created some other way.
+func WithoutRegistration() AccountHandlerOption {
+	return func(h *AccountHandler) {
+		h.allowRegistration = false
+	}
+}
+
+// WithoutPasswordLogin hides the password form on the login page and refuses
+// password logins, for instances whose users log in through single sign-on.
+func WithoutPasswordLogin() AccountHandlerOption {
+	return func(h *AccountHandler) {
+		h.allowPasswordLogin = false
+	}
+}
+
+// WithSSO offers logging in through an OpenID Connect provider, named on the
+// login page.
+func WithSSO(sso *application.SSOService, name string) AccountHandlerOption {
+	return func(h *AccountHandler) {
+		h.sso = sso
+		h.ssoName = name
+	}
+}
+
+func NewAccountHandler(users *application.UserService, service *application.ShortenerService, tmpl *template.Template, opts ...AccountHandlerOption) *AccountHandler {
+	h := &AccountHandler{
+		users:   users,
+		service: service,
+		tmpl:    tmpl,
+
+		allowRegistration:  true,
+		allowPasswordLogin: true,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type accountPageData struct {
+	Email              string
+	Next               string
+	Error              string
+	AllowRegistration  bool
+	AllowPasswordLogin bool
+	SSOName            string // empty without single sign-on
+}
+
+type dashboardLink struct {
+	ShortCode string
+	ShortURL  string
+	LongURL   string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+	Disabled  bool
+	Protected bool
+	ManageURL string
+}
+
+type dashboardPageData struct {
+	Email    string
+	Links    []dashboardLink
+	Page     int
+	PrevPage int // zero on the first page
+	NextPage int // zero on the last page
+}
+
+// Login serves /login. Only local paths are accepted as the next page, so the
+// form cannot be used to redirect visitors elsewhere.
+func (h *AccountHandler) Login(w http.ResponseWriter, r *http.Request) {
+	data := accountPageData{
+		Next:               localPath(r.FormValue("next")),
+		AllowRegistration:  h.allowRegistration,
+		AllowPasswordLogin: h.allowPasswordLogin,
+		SSOName:            h.ssoName,
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.render(w, "login.html", http.StatusOK, data)
+	case http.MethodPost:
+		if !h.allowPasswordLogin {
+			http.Error(w, "Password login is disabled", http.StatusForbidden)
+			return
+		}
+		data.Email = r.PostFormValue("email")
+		session, token, err := h.users.Login(r.Context(), data.Email, r.PostFormValue("password"))
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			data.Error = "Invalid email or password"
+			h.render(w, "login.html", http.StatusUnauthorized, data)
+			return
+		}
+		if err != nil {
+			log.Printf("Error logging in: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		h.setSessionCookie(w, token, session.ExpiresAt)
+		http.Redirect(w, r, data.Next, http.StatusSeeOther)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Register serves /register and logs new users in right away.
+func (h *AccountHandler) Register(w http.ResponseWriter, r *http.Request) {
+	if !h.allowRegistration {
+		http.NotFound(w, r)
+		return
+	}
+	data := accountPageData{Next: "/dashboard", AllowRegistration: true, AllowPasswordLogin: true}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.render(w, "register.html", http.StatusOK, data)
+	case http.MethodPost:
+		data.Email = r.PostFormValue("email")
+		password := r.PostFormValue("password")
+		if password != r.PostFormValue("password_confirm") {
+			data.Error = "Passwords do not match"
+			h.render(w, "register.html", http.StatusBadRequest, data)
+			return
+		}
+
+		user, err := h.users.Register(r.Context(), data.Email, password)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, domain.ErrInvalidEmail):
+				data.Error = "Enter a valid email address"
+			case errors.Is(err, domain.ErrWeakPassword):
+				data.Error = "Use a password of 8 to 72 characters"
+			case errors.Is(err, domain.ErrUserExists):
+				status = http.StatusConflict
+				data.Error = "An account with this email already exists"
+			default:
+				log.Printf("Error registering user: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			h.render(w, "register.html", status, data)
+			return
+		}
+
+		session, token, err := h.users.StartSession(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("Error logging in new user: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		h.setSessionCookie(w, token, session.ExpiresAt)
+		http.Redirect(w, r, data.Next, http.StatusSeeOther)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Logout serves /logout. It only accepts POST, so a link or image on another
+// page cannot log visitors out.
+func (h *AccountHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if cookie, err := r.Cookie(SessionCookieName); err == nil {
+		if err := h.users.Logout(r.Context(), cookie.Value); err != nil {
+			log.Printf("Error logging out: %v", err)
+		}
+	}
+	h.setSessionCookie(w, "", time.Unix(0, 0))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+type ssoFlowCookie struct {
+	application.SSOFlow
+	Next string
+}
+
+// SSOLogin serves /auth/oidc/login, sending the browser to the provider.
+func (h *AccountHandler) SSOLogin(w http.ResponseWriter, r *http.Request) {
+	if h.sso == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flow, authURL, err := h.sso.Begin()
+	if err != nil {
+		log.Printf("Error starting single sign-on: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	value, err := json.Marshal(ssoFlowCookie{SSOFlow: *flow, Next: localPath(r.URL.Query().Get("next"))})
+	if err != nil {
+		log.Printf("Error starting single sign-on: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// SameSite=Lax still sends the cookie on the provider's redirect back,
+	// which is a top-level navigation.
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoFlowCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     "/auth/oidc/",
+		MaxAge:   int(ssoFlowMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// SSOCallback serves /auth/oidc/callback, where the provider sends the
+// browser back after the user logged in there.
+func (h *AccountHandler) SSOCallback(w http.ResponseWriter, r *http.Request) {
+	if h.sso == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var flow *application.SSOFlow
+	data := accountPageData{
+		Next:               "/dashboard",
+		AllowRegistration:  h.allowRegistration,
+		AllowPasswordLogin: h.allowPasswordLogin,
+		SSOName:            h.ssoName,
+	}
+	if cookie, err := r.Cookie(ssoFlowCookieName); err == nil {
+		var stored ssoFlowCookie
+		if value, err := base64.RawURLEncoding.DecodeString(cookie.Value); err == nil && json.Unmarshal(value, &stored) == nil {
+			flow, data.Next = &stored.SSOFlow, localPath(stored.Next)
+		}
+	}
+	// Each flow is good for one attempt.
+	http.SetCookie(w, &http.Cookie{Name: ssoFlowCookieName, Path: "/auth/oidc/", MaxAge: -1, HttpOnly: true, Secure: h.secureCookies})
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		data.Error = "Single sign-on was cancelled or failed"
+		h.render(w, "login.html", http.StatusUnauthorized, data)
+		return
+	}
+
+	session, token, err := h.sso.Complete(r.Context(), flow, query.Get("state"), query.Get("code"))
+	if err != nil {
+		status := http.StatusForbidden
+		switch {
+		case errors.Is(err, domain.ErrUnauthorized):
+			status = http.StatusBadRequest
+			data.Error = "Your sign-on attempt expired, please try again"
+		case errors.Is(err, domain.ErrAccessDenied), errors.Is(err, domain.ErrInvalidEmail):
+			log.Printf("Single sign-on denied: %v", err)
+			data.Error = "Your account is not allowed to use this service"
+		default:
+			log.Printf("Error completing single sign-on: %v", err)
+			status = http.StatusBadGateway
+			data.Error = "Single sign-on failed, please try again"
+		}
+		h.render(w, "login.html", status, data)
+		return
+	}
+
+	h.setSessionCookie(w, token, session.ExpiresAt)
+	http.Redirect(w, r, data.Next, http.StatusSeeOther)
+}
+
+// Dashboard serves /dashboard, listing the logged in user's links.
+func (h *AccountHandler) Dashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	principal := domain.PrincipalFromContext(r.Context())
+	if principal == nil || principal.UserID == "" {
+		http.Redirect(w, r, "/login?next=/dashboard", http.StatusFound)
+		return
+	}
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	// One extra link tells whether there is a next page.
+	urls, err := h.service.ListURLs(r.Context(), dashboardPageSize+1, (page-1)*dashboardPageSize)
+	if err != nil {
+		log.Printf("Error listing links for dashboard: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := dashboardPageData{
+		Email: principal.Email,
+		Page:  page,
+		Links: make([]dashboardLink, 0, min(len(urls), dashboardPageSize)),
+	}
+	if page > 1 {
+		data.PrevPage = page - 1
+	}
+	if len(urls) > dashboardPageSize {
+		data.NextPage = page + 1
+		urls = urls[:dashboardPageSize]
+	}
+	for _, u := range urls {
+		data.Links = append(data.Links, dashboardLink{
+			ShortCode: u.ShortCode,
+			ShortURL:  buildShortURL(r, u.ShortCode),
+			LongURL:   u.LongURL,
+			CreatedAt: u.CreatedAt,
+			ExpiresAt: u.ExpiresAt,
+			Disabled:  u.IsDisabled(),
+			Protected: u.IsProtected(),
+			ManageURL: buildManageURL(r, u.ShortCode, h.service.ManageToken(u)),
+		})
+	}
+
+	// the page carries manage tokens, so keep it out of caches and Referer headers
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	h.render(w, "dashboard.html", http.StatusOK, data)
+}
+
+func (h *AccountHandler) render(w http.ResponseWriter, name string, status int, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.tmpl.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error rendering %s: %v", name, err)
+	}
+}
+
+// setSessionCookie keeps the session token away from scripts and, through
+// SameSite, out of cross-site form posts.
+func (h *AccountHandler) setSessionCookie(w http.ResponseWriter, token string, expires time.Time) {
+	cookie := &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   h.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if token == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// localPath returns next if it is a path on this site, and the dashboard
+// otherwise. "//host" and "/\host" are treated as other sites by browsers.
+func localPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, `/\`) {
+		return "/dashboard"
+	}
+	return next
+}
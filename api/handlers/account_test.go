@@ -0,0 +1,378 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"url-shortener/api/handlers"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var accountTemplates = template.Must(template.New("").Parse(`
+{{define "login.html"}}login next={{.Next}} error={{.Error}} register={{.AllowRegistration}} password={{.AllowPasswordLogin}} sso={{.SSOName}}{{end}}
+{{define "register.html"}}register error={{.Error}}{{end}}
+{{define "dashboard.html"}}{{.Email}} page={{.Page}} prev={{.PrevPage}} next={{.NextPage}}{{range .Links}} {{.ShortCode}}{{end}}{{end}}
+`))
+
+func newAccountHandler(t *testing.T, opts ...handlers.AccountHandlerOption) (*handlers.AccountHandler, *application.UserService, *application.ShortenerService) {
+	t.Helper()
+	return newAccountHandlerWithUsers(t, newUsers(), opts...)
+}
+
+func newUsers() *application.UserService {
+	return application.NewUserService(repository.NewMemoryUserRepository(), repository.NewMemorySessionRepository(),
+		application.WithPasswordCost(bcrypt.MinCost))
+}
+
+func newAccountHandlerWithUsers(t *testing.T, users *application.UserService, opts ...handlers.AccountHandlerOption) (*handlers.AccountHandler, *application.UserService, *application.ShortenerService) {
+	t.Helper()
+	service := application.NewShortenerService(repository.NewMemoryURLRepository(0), &sequenceGenerator{})
+	return handlers.NewAccountHandler(users, service, accountTemplates, opts...), users, service
+}
+
+type sequenceGenerator struct{ n int }
+
+func (g *sequenceGenerator) Generate() string {
+	g.n++
+	return fmt.Sprintf("code%02d", g.n)
+}
+
+func postForm(path string, values url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	return findCookie(w, handlers.SessionCookieName)
+}
+
+func findCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestAccountHandler_Register(t *testing.T) {
+	handler, users, _ := newAccountHandler(t)
+
+	w := httptest.NewRecorder()
+	handler.Register(w, httptest.NewRequest(http.MethodGet, "/register", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	tests := []struct {
+		name           string
+		form           url.Values
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "passwords differ",
+			form:           url.Values{"email": {"ada@example.com"}, "password": {"correct horse"}, "password_confirm": {"correct house"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Passwords do not match",
+		},
+		{
+			name:           "invalid email",
+			form:           url.Values{"email": {"ada"}, "password": {"correct horse"}, "password_confirm": {"correct horse"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Enter a valid email address",
+		},
+		{
+			name:           "weak password",
+			form:           url.Values{"email": {"ada@example.com"}, "password": {"short"}, "password_confirm": {"short"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Use a password of 8 to 72 characters",
+		},
+		{
+			name:           "success",
+			form:           url.Values{"email": {"ada@example.com"}, "password": {"correct horse"}, "password_confirm": {"correct horse"}},
+			expectedStatus: http.StatusSeeOther,
+		},
+		{
+			name:           "email taken",
+			form:           url.Values{"email": {"Ada@example.com"}, "password": {"correct horse"}, "password_confirm": {"correct horse"}},
+			expectedStatus: http.StatusConflict,
+			expectedError:  "An account with this email already exists",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.Register(w, postForm("/register", tt.form))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+				assert.Nil(t, sessionCookie(t, w))
+				return
+			}
+
+			assert.Equal(t, "/dashboard", w.Header().Get("Location"))
+			cookie := sessionCookie(t, w)
+			require.NotNil(t, cookie, "new users are logged in")
+			assert.True(t, cookie.HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+			user, err := users.Authenticate(context.Background(), cookie.Value)
+			require.NoError(t, err)
+			assert.Equal(t, "ada@example.com", user.Email)
+		})
+	}
+}
+
+func TestAccountHandler_RegistrationDisabled(t *testing.T) {
+	handler, _, _ := newAccountHandler(t, handlers.WithoutRegistration())
+
+	w := httptest.NewRecorder()
+	handler.Register(w, postForm("/register", url.Values{"email": {"ada@example.com"}, "password": {"correct horse"}, "password_confirm": {"correct horse"}}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	assert.Contains(t, w.Body.String(), "register=false")
+}
+
+func TestAccountHandler_PasswordLoginDisabled(t *testing.T) {
+	handler, users, _ := newAccountHandler(t, handlers.WithoutRegistration(), handlers.WithoutPasswordLogin())
+	_, err := users.Register(context.Background(), "ada@example.com", "correct horse")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.Login(w, postForm("/login", url.Values{"email": {"ada@example.com"}, "password": {"correct horse"}}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Nil(t, sessionCookie(t, w))
+
+	w = httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "password=false")
+}
+
+func TestAccountHandler_LoginLogout(t *testing.T) {
+	handler, users, _ := newAccountHandler(t, handlers.WithSecureCookies())
+	_, err := users.Register(context.Background(), "ada@example.com", "correct horse")
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodGet, "/login?next=/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "next=/ ")
+
+	w = httptest.NewRecorder()
+	handler.Login(w, postForm("/login", url.Values{"email": {"ada@example.com"}, "password": {"wrong horse"}}))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid email or password")
+	assert.Nil(t, sessionCookie(t, w))
+
+	for next, want := range map[string]string{
+		"":                   "/dashboard",
+		"/":                  "/",
+		"https://evil.test/": "/dashboard",
+		"//evil.test/":       "/dashboard",
+		`/\evil.test/`:       "/dashboard",
+	} {
+		w = httptest.NewRecorder()
+		handler.Login(w, postForm("/login", url.Values{"email": {"ada@example.com"}, "password": {"correct horse"}, "next": {next}}))
+		assert.Equal(t, http.StatusSeeOther, w.Code)
+		assert.Equal(t, want, w.Header().Get("Location"), "next %q", next)
+	}
+	cookie := sessionCookie(t, w)
+	require.NotNil(t, cookie)
+	assert.True(t, cookie.Secure)
+
+	w = httptest.NewRecorder()
+	handler.Logout(w, httptest.NewRequest(http.MethodGet, "/logout", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	handler.Logout(w, req)
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	cleared := sessionCookie(t, w)
+	require.NotNil(t, cleared)
+	assert.Empty(t, cleared.Value)
+	assert.Negative(t, cleared.MaxAge)
+
+	_, err = users.Authenticate(context.Background(), cookie.Value)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized, "logging out ends the session")
+}
+
+func TestAccountHandler_Dashboard(t *testing.T) {
+	handler, _, service := newAccountHandler(t)
+
+	w := httptest.NewRecorder()
+	handler.Dashboard(w, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/login?next=/dashboard", w.Header().Get("Location"))
+
+	ada := domain.ContextWithPrincipal(context.Background(), &domain.Principal{OwnerID: "u1", UserID: "u1", Email: "ada@example.com"})
+	grace := domain.ContextWithPrincipal(context.Background(), &domain.Principal{OwnerID: "u2", UserID: "u2", Email: "grace@example.com"})
+	for i := 0; i < 21; i++ {
+		_, err := service.CreateShortURL(ada, fmt.Sprintf("https://example.com/%d", i), application.CreateOptions{})
+		require.NoError(t, err)
+	}
+	_, err := service.CreateShortURL(grace, "https://example.org", application.CreateOptions{})
+	require.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	handler.Dashboard(w, httptest.NewRequest(http.MethodGet, "/dashboard", nil).WithContext(ada))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	body := w.Body.String()
+	assert.True(t, strings.HasPrefix(body, "ada@example.com page=1 prev=0 next=2"), body)
+	assert.Equal(t, 20, strings.Count(body, " code"))
+	assert.NotContains(t, body, "code22", "other users' links are not listed")
+
+	w = httptest.NewRecorder()
+	handler.Dashboard(w, httptest.NewRequest(http.MethodGet, "/dashboard?page=2", nil).WithContext(ada))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "ada@example.com page=2 prev=1 next=0"), w.Body.String())
+	assert.Equal(t, 1, strings.Count(w.Body.String(), " code"))
+}
+
+// stubIdentityProvider logs in as the identity registered for each code.
+type stubIdentityProvider map[string]*domain.ExternalIdentity
+
+func (p stubIdentityProvider) AuthCodeURL(state, nonce, verifier string) string {
+	return "https://idp.example.com/authorize?" + url.Values{"state": {state}}.Encode()
+}
+
+func (p stubIdentityProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*domain.ExternalIdentity, error) {
+	identity, ok := p[code]
+	if !ok || verifier == "" || nonce == "" {
+		return nil, errors.New("invalid_grant")
+	}
+	return identity, nil
+}
+
+func TestAccountHandler_SSO(t *testing.T) {
+	handler, _, _ := newAccountHandler(t)
+	w := httptest.NewRecorder()
+	handler.SSOLogin(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code, "single sign-on is off by default")
+
+	provider := stubIdentityProvider{
+		"ada":     {Issuer: "https://idp.example.com", Subject: "1", Email: "ada@example.com", EmailVerified: true},
+		"mallory": {Issuer: "https://idp.example.com", Subject: "2", Email: "mallory@example.org", EmailVerified: true},
+	}
+	users := newUsers()
+	sso := application.NewSSOService(provider, users, application.SSOPolicy{AllowedDomains: []string{"example.com"}})
+	handler, _, _ = newAccountHandlerWithUsers(t, users, handlers.WithSSO(sso, "Example SSO"))
+
+	w = httptest.NewRecorder()
+	handler.Login(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	assert.Contains(t, w.Body.String(), "sso=Example SSO")
+
+	// begin returns the flow cookie and the state the provider sends back.
+	begin := func(next string) (*http.Cookie, string) {
+		w := httptest.NewRecorder()
+		handler.SSOLogin(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login?"+url.Values{"next": {next}}.Encode(), nil))
+		require.Equal(t, http.StatusFound, w.Code)
+		location, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "idp.example.com", location.Host)
+
+		cookie := findCookie(w, "sso_flow")
+		require.NotNil(t, cookie)
+		assert.True(t, cookie.HttpOnly)
+		assert.Equal(t, "/auth/oidc/", cookie.Path)
+		return cookie, location.Query().Get("state")
+	}
+	callback := func(cookie *http.Cookie, query url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+query.Encode(), nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.SSOCallback(w, req)
+		return w
+	}
+
+	cookie, state := begin("/")
+	w = callback(cookie, url.Values{"state": {state}, "code": {"ada"}})
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/", w.Header().Get("Location"))
+	cleared := findCookie(w, "sso_flow")
+	require.NotNil(t, cleared)
+	assert.Negative(t, cleared.MaxAge, "each flow is good for one attempt")
+	session := sessionCookie(t, w)
+	require.NotNil(t, session)
+	user, err := users.Authenticate(context.Background(), session.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", user.Email)
+
+	tests := []struct {
+		name           string
+		withCookie     bool
+		query          func(state string) url.Values
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "no flow cookie",
+			query:          func(state string) url.Values { return url.Values{"state": {state}, "code": {"ada"}} },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "expired",
+		},
+		{
+			name:           "state from another flow",
+			withCookie:     true,
+			query:          func(string) url.Values { return url.Values{"state": {"forged"}, "code": {"ada"}} },
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "expired",
+		},
+		{
+			name:           "provider reported an error",
+			withCookie:     true,
+			query:          func(state string) url.Values { return url.Values{"state": {state}, "error": {"access_denied"}} },
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "cancelled",
+		},
+		{
+			name:           "outside the allowed domains",
+			withCookie:     true,
+			query:          func(state string) url.Values { return url.Values{"state": {state}, "code": {"mallory"}} },
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "not allowed",
+		},
+		{
+			name:           "code rejected by the provider",
+			withCookie:     true,
+			query:          func(state string) url.Values { return url.Values{"state": {state}, "code": {"bogus"}} },
+			expectedStatus: http.StatusBadGateway,
+			expectedError:  "failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookie, state := begin("/dashboard")
+			if !tt.withCookie {
+				cookie = nil
+			}
+			w := callback(cookie, tt.query(state))
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Contains(t, w.Body.String(), tt.expectedError)
+			assert.Nil(t, sessionCookie(t, w))
+		})
+	}
+}
@@ -0,0 +1,516 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+)
+
+const (
+	linksPath       = "/api/v1/links"
+	adminLinksPath  = "/api/v1/admin/links"
+	maxRequestBytes = 1 << 20
+
+	// manageTokenHeader carries the token returned when the link was created.
+	manageTokenHeader = "X-Manage-Token"
+)
+
+const defaultStatsWindow = 7 * 24 * time.Hour
+
+// statsIntervals are the bucket widths accepted by the stats endpoint.
+var statsIntervals = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+type LinksHandler struct {
+	service   *application.ShortenerService
+	analytics *application.AnalyticsService
+}
+
+type LinksHandlerOption func(*LinksHandler)
+
+// WithAnalytics enables the /api/v1/links/{shortCode}/stats endpoint.
+func WithAnalytics(analytics *application.AnalyticsService) LinksHandlerOption {
+	return func(h *LinksHandler) {
+		h.analytics = analytics
+	}
+}
+
+func NewLinksHandler(service *application.ShortenerService, opts ...LinksHandlerOption) *LinksHandler {
+	h := &LinksHandler{
+		service: service,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+type createLinkRequest struct {
+	URL            string     `json:"url"`
+	Alias          string     `json:"alias,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	ExpiresIn      string     `json:"expires_in,omitempty"` // duration such as "24h", or "never"
+	RedirectStatus int        `json:"redirect_status,omitempty"`
+	Password       string     `json:"password,omitempty"`
+}
+
+type updateLinkRequest struct {
+	URL            string `json:"url,omitempty"`
+	RedirectStatus int    `json:"redirect_status,omitempty"`
+}
+
+type adminUpdateLinkRequest struct {
+	Disabled *bool   `json:"disabled,omitempty"`
+	OwnerID  *string `json:"owner_id,omitempty"`
+}
+
+type linkResponse struct {
+	ShortCode      string     `json:"short_code"`
+	ShortURL       string     `json:"short_url"`
+	LongURL        string     `json:"long_url,omitempty"` // hidden on protected links from those who may not manage them
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	RedirectStatus int        `json:"redirect_status"`
+	Disabled       bool       `json:"disabled,omitempty"`
+	Protected      bool       `json:"protected,omitempty"`
+	OwnerID        string     `json:"owner_id,omitempty"`     // only returned to admins
+	ManageToken    string     `json:"manage_token,omitempty"` // only returned on creation
+}
+
+type listLinksResponse struct {
+	Links  []linkResponse `json:"links"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+type clickBucketResponse struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+type linkStatsResponse struct {
+	ShortCode string                `json:"short_code"`
+	Total     int64                 `json:"total"`
+	Interval  string                `json:"interval"`
+	From      time.Time             `json:"from"`
+	To        time.Time             `json:"to"`
+	Buckets   []clickBucketResponse `json:"buckets"`
+}
+
+type errorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Links serves the /api/v1/links collection.
+func (h *LinksHandler) Links(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.listLinks(w, r)
+	case http.MethodPost:
+		h.createLink(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	}
+}
+
+// Link serves a single /api/v1/links/{shortCode} resource and its
+// /stats sub-resource.
+func (h *LinksHandler) Link(w http.ResponseWriter, r *http.Request) {
+	shortCode := strings.TrimPrefix(r.URL.Path, linksPath+"/")
+	if code, ok := strings.CutSuffix(shortCode, "/stats"); ok && h.analytics != nil {
+		h.linkStats(w, r, code)
+		return
+	}
+	if shortCode == "" || strings.Contains(shortCode, "/") {
+		writeError(w, http.StatusNotFound, "not_found", "Resource not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.getLink(w, r, shortCode)
+	case http.MethodPatch:
+		h.updateLink(w, r, shortCode)
+	case http.MethodDelete:
+		h.deleteLink(w, r, shortCode)
+	default:
+		w.Header().Set("Allow", "GET, PATCH, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	}
+}
+
+// AdminLinks serves the /api/v1/admin/links collection of every link.
+func (h *LinksHandler) AdminLinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	urls, err := h.service.ListAllURLs(r.Context(), limit, offset, r.URL.Query().Get("owner"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp := listLinksResponse{
+		Links:  make([]linkResponse, 0, len(urls)),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, u := range urls {
+		resp.Links = append(resp.Links, h.toAdminLinkResponse(r, u))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AdminLink serves /api/v1/admin/links/{shortCode}, through which admins
+// disable, restore and reassign links.
+func (h *LinksHandler) AdminLink(w http.ResponseWriter, r *http.Request) {
+	shortCode := strings.TrimPrefix(r.URL.Path, adminLinksPath+"/")
+	if shortCode == "" || strings.Contains(shortCode, "/") {
+		writeError(w, http.StatusNotFound, "not_found", "Resource not found")
+		return
+	}
+	if r.Method != http.MethodPatch {
+		w.Header().Set("Allow", "PATCH")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	var req adminUpdateLinkRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Request body must be a valid JSON object")
+		return
+	}
+	if req.Disabled == nil && req.OwnerID == nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Nothing to update; set disabled or owner_id")
+		return
+	}
+
+	shortURL, err := h.service.AdminUpdateURL(r.Context(), shortCode, application.AdminUpdateOptions{
+		Disabled: req.Disabled,
+		OwnerID:  req.OwnerID,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.toAdminLinkResponse(r, shortURL))
+}
+
+func (h *LinksHandler) createLink(w http.ResponseWriter, r *http.Request) {
+	var req createLinkRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Request body must be a valid JSON object")
+		return
+	}
+
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "invalid_url", "URL is required")
+		return
+	}
+
+	opts := application.CreateOptions{
+		Alias:          strings.TrimSpace(req.Alias),
+		ExpiresAt:      req.ExpiresAt,
+		RedirectStatus: req.RedirectStatus,
+		Password:       req.Password,
+	}
+	if err := parseExpiresIn(req.ExpiresIn, &opts); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_expiry", err.Error())
+		return
+	}
+
+	shortURL, err := h.service.CreateShortURL(r.Context(), req.URL, opts)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp := h.toLinkResponse(r, shortURL)
+	w.Header().Set("Location", linksPath+"/"+shortURL.ShortCode)
+
+	// A reused link belongs to whoever created it, so its manage token is
+	// not handed out again.
+	if shortURL.Reused {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	resp.ManageToken = h.service.ManageToken(shortURL)
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (h *LinksHandler) getLink(w http.ResponseWriter, r *http.Request, shortCode string) {
+	shortURL, err := h.service.GetURL(r.Context(), shortCode)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.toVisibleLinkResponse(r, shortURL))
+}
+
+func (h *LinksHandler) updateLink(w http.ResponseWriter, r *http.Request, shortCode string) {
+	var req updateLinkRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Request body must be a valid JSON object")
+		return
+	}
+
+	if req.URL == "" && req.RedirectStatus == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "Nothing to update; set url or redirect_status")
+		return
+	}
+
+	shortURL, err := h.service.UpdateURL(r.Context(), shortCode, r.Header.Get(manageTokenHeader), application.UpdateOptions{
+		LongURL:        req.URL,
+		RedirectStatus: req.RedirectStatus,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.toLinkResponse(r, shortURL))
+}
+
+func (h *LinksHandler) deleteLink(w http.ResponseWriter, r *http.Request, shortCode string) {
+	if err := h.service.DeleteURL(r.Context(), shortCode, r.Header.Get(manageTokenHeader)); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *LinksHandler) linkStats(w http.ResponseWriter, r *http.Request, shortCode string) {
+	if shortCode == "" || strings.Contains(shortCode, "/") {
+		writeError(w, http.StatusNotFound, "not_found", "Resource not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	width, ok := statsIntervals[interval]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_stats_query", "interval must be hour or day")
+		return
+	}
+
+	to, err := queryTime(r, "to", time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_stats_query", "to must be an RFC 3339 timestamp")
+		return
+	}
+	from, err := queryTime(r, "from", to.Add(-defaultStatsWindow))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_stats_query", "from must be an RFC 3339 timestamp")
+		return
+	}
+
+	// Stats are only for those who may change the link.
+	shortURL, err := h.service.Authorize(r.Context(), shortCode, r.Header.Get(manageTokenHeader))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	stats, err := h.analytics.GetStats(r.Context(), shortURL, application.StatsQuery{
+		From:     from,
+		To:       to,
+		Interval: width,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp := linkStatsResponse{
+		ShortCode: shortURL.ShortCode,
+		Total:     stats.Total,
+		Interval:  interval,
+		From:      from.Truncate(width).UTC(),
+		To:        to.UTC(),
+		Buckets:   make([]clickBucketResponse, 0, len(stats.Buckets)),
+	}
+	for _, b := range stats.Buckets {
+		resp.Buckets = append(resp.Buckets, clickBucketResponse{Start: b.Start.UTC(), Count: b.Count})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *LinksHandler) listLinks(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	urls, err := h.service.ListURLs(r.Context(), limit, offset)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp := listLinksResponse{
+		Links:  make([]linkResponse, 0, len(urls)),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, u := range urls {
+		resp.Links = append(resp.Links, h.toVisibleLinkResponse(r, u))
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *LinksHandler) toLinkResponse(r *http.Request, u *domain.URL) linkResponse {
+	return linkResponse{
+		ShortCode:      u.ShortCode,
+		ShortURL:       buildShortURL(r, u.ShortCode),
+		LongURL:        u.LongURL,
+		CreatedAt:      u.CreatedAt,
+		ExpiresAt:      u.ExpiresAt,
+		RedirectStatus: h.service.RedirectStatus(u),
+		Disabled:       u.IsDisabled(),
+		Protected:      u.IsProtected(),
+	}
+}
+
+// toVisibleLinkResponse hides the destination of a protected link from
+// callers who may not manage it; they have to enter its password instead.
+func (h *LinksHandler) toVisibleLinkResponse(r *http.Request, u *domain.URL) linkResponse {
+	resp := h.toLinkResponse(r, u)
+	if u.IsProtected() && !h.service.CanManage(r.Context(), u, r.Header.Get(manageTokenHeader)) {
+		resp.LongURL = ""
+	}
+	return resp
+}
+
+func (h *LinksHandler) toAdminLinkResponse(r *http.Request, u *domain.URL) linkResponse {
+	resp := h.toLinkResponse(r, u)
+	resp.OwnerID = u.OwnerID
+	return resp
+}
+
+// pagination reads the limit and offset query parameters, writing an error
+// response if they are malformed.
+func pagination(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit, err := queryInt(r, "limit", application.DefaultListLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "limit must be an integer")
+		return 0, 0, false
+	}
+	offset, err = queryInt(r, "offset", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "offset must be an integer")
+		return 0, 0, false
+	}
+	return limit, offset, true
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func queryInt(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func queryTime(r *http.Request, key string, defaultValue time.Time) (time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing JSON response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, errorResponse{Error: code, Message: message})
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	var permErr *domain.PermissionError
+	switch {
+	case errors.Is(err, domain.ErrURLNotFound):
+		writeError(w, http.StatusNotFound, "not_found", "URL not found")
+	case errors.Is(err, domain.ErrUnauthorized):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "unauthorized", "A valid API key is required")
+	case errors.As(err, &permErr) && permErr.ShortCode == "":
+		writeError(w, http.StatusForbidden, "forbidden", roleMessage(permErr))
+	case errors.Is(err, domain.ErrForbidden):
+		writeError(w, http.StatusForbidden, "forbidden", "A valid manage token or the owner's API key is required")
+	case errors.Is(err, domain.ErrInvalidURL):
+		writeError(w, http.StatusBadRequest, "invalid_url", "Invalid URL format")
+	case errors.Is(err, domain.ErrInvalidAlias):
+		writeError(w, http.StatusBadRequest, "invalid_alias", err.Error())
+	case errors.Is(err, domain.ErrInvalidExpiry):
+		writeError(w, http.StatusBadRequest, "invalid_expiry", err.Error())
+	case errors.Is(err, domain.ErrInvalidRedirect):
+		writeError(w, http.StatusBadRequest, "invalid_redirect", "redirect_status must be 301, 302, 307 or 308")
+	case errors.Is(err, domain.ErrInvalidLinkPassword):
+		writeError(w, http.StatusBadRequest, "invalid_password", err.Error())
+	case errors.Is(err, domain.ErrDisallowedURL):
+		writeError(w, http.StatusBadRequest, "disallowed_url", err.Error())
+	case errors.Is(err, domain.ErrUnsafeURL):
+		writeError(w, http.StatusBadRequest, "unsafe_url", err.Error())
+	case errors.Is(err, domain.ErrInvalidStatsQuery):
+		writeError(w, http.StatusBadRequest, "invalid_stats_query", err.Error())
+	case errors.Is(err, domain.ErrShortCodeExists):
+		writeError(w, http.StatusConflict, "short_code_exists", "Short code already exists")
+	case errors.Is(err, domain.ErrShortCodeUnavailable):
+		log.Printf("Error handling API request: %v", err)
+		writeError(w, http.StatusServiceUnavailable, "short_code_unavailable", "No short code available, try again later")
+	default:
+		log.Printf("Error handling API request: %v", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Internal server error")
+	}
+}
+
+// roleMessage explains a permission error about the caller's role rather
+// than about a particular link.
+func roleMessage(err *domain.PermissionError) string {
+	return fmt.Sprintf("The %s role may not %s", err.Role, err.Action)
+}
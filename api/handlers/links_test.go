@@ -0,0 +1,886 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"url-shortener/api/handlers"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinksHandler_Create(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		setupMocks     func(*MockURLRepository, *MockShortCodeGenerator)
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name: "success",
+			body: `{"url":"https://example.com"}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("abc123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "alias conflict",
+			body: `{"url":"https://example.com","alias":"spring-sale"}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(domain.ErrShortCodeExists)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedError:  "short_code_exists",
+		},
+		{
+			name: "no short code available",
+			body: `{"url":"https://example.com"}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("abc123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(domain.ErrShortCodeExists)
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedError:  "short_code_unavailable",
+		},
+		{
+			name:           "invalid alias",
+			body:           `{"url":"https://example.com","alias":"api"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_alias",
+		},
+		{
+			name: "relative expiry",
+			body: `{"url":"https://example.com","expires_in":"2h"}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("abc123")
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
+					return u.ExpiresAt != nil && time.Until(*u.ExpiresAt) > 119*time.Minute
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "explicit redirect status",
+			body: `{"url":"https://example.com","redirect_status":308}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("abc123")
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
+					return u.RedirectStatus == http.StatusPermanentRedirect
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "unsupported redirect status",
+			body: `{"url":"https://example.com","redirect_status":303}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("abc123")
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_redirect",
+		},
+		{
+			name:           "invalid expiry duration",
+			body:           `{"url":"https://example.com","expires_in":"soon"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_expiry",
+		},
+		{
+			name:           "expiry in the past",
+			body:           `{"url":"https://example.com","expires_at":"2001-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_expiry",
+		},
+		{
+			name:           "malformed JSON",
+			body:           `{"url":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "missing URL",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_url",
+		},
+		{
+			name: "invalid URL",
+			body: `{"url":"not-a-url"}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("abc123").Maybe()
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_url",
+		},
+		{
+			name:           "javascript URL",
+			body:           `{"url":"javascript:alert(1)"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "disallowed_url",
+		},
+		{
+			name: "repository error",
+			body: `{"url":"https://example.com"}`,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("abc123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "internal_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			if tt.setupMocks != nil {
+				tt.setupMocks(repo, gen)
+			}
+			handler := handlers.NewLinksHandler(application.NewShortenerService(repo, gen))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/links", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.Links(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body["error"])
+			} else {
+				assert.Equal(t, "abc123", body["short_code"])
+				assert.Equal(t, "http://example.com/abc123", body["short_url"])
+				assert.NotEmpty(t, body["manage_token"])
+				assert.Equal(t, "/api/v1/links/abc123", w.Header().Get("Location"))
+			}
+			repo.AssertExpectations(t)
+			gen.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLinksHandler_CreateReused(t *testing.T) {
+	repo := new(MockURLRepository)
+	existing := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/", CreatedAt: time.Now()}
+	repo.On("FindByLongURL", mock.Anything, "https://EXAMPLE.com").Return(existing, nil)
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithDeduplication())
+	handler := handlers.NewLinksHandler(service)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", bytes.NewBufferString(`{"url":"https://EXAMPLE.com"}`))
+	w := httptest.NewRecorder()
+
+	handler.Links(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "abc123", body["short_code"])
+	assert.NotContains(t, body, "manage_token", "a reused link must not hand out its manage token")
+	assert.Equal(t, "/api/v1/links/abc123", w.Header().Get("Location"))
+	repo.AssertExpectations(t)
+}
+
+func TestLinksHandler_CreateUnsafe(t *testing.T) {
+	scanner := scannerFunc(func(rawURL string) (*domain.ScanResult, error) {
+		return &domain.ScanResult{Threat: domain.ThreatMalware}, nil
+	})
+	service := application.NewShortenerService(new(MockURLRepository), new(MockShortCodeGenerator),
+		application.WithURLScanner(scanner, application.ScanPolicy{}))
+	handler := handlers.NewLinksHandler(service)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links", bytes.NewBufferString(`{"url":"https://files.example/setup.exe"}`))
+	w := httptest.NewRecorder()
+
+	handler.Links(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "unsafe_url", body["error"])
+	assert.Contains(t, body["message"], "malware")
+}
+
+func TestLinksHandler_Get(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		setupMocks     func(*MockURLRepository)
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			path:   "/api/v1/links/abc123",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(&domain.URL{
+					ShortCode: "abc123",
+					LongURL:   "https://example.com",
+					CreatedAt: time.Now(),
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			path:   "/api/v1/links/missing",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("FindByShortCode", mock.Anything, "missing").Return(nil, domain.ErrURLNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+		},
+		{
+			name:           "unknown sub-resource",
+			method:         http.MethodGet,
+			path:           "/api/v1/links/abc123/unknown",
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPut,
+			path:           "/api/v1/links/abc123",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedError:  "method_not_allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			if tt.setupMocks != nil {
+				tt.setupMocks(repo)
+			}
+			handler := handlers.NewLinksHandler(application.NewShortenerService(repo, gen))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			handler.Link(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var body map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body["error"])
+			} else {
+				assert.Equal(t, "https://example.com", body["long_url"])
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLinksHandler_List(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		principal      *domain.Principal
+		setupMocks     func(*MockURLRepository)
+		expectedStatus int
+		expectedCount  int
+	}{
+		{
+			name:      "default pagination",
+			query:     "",
+			principal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("List", mock.Anything, domain.ListOptions{Limit: application.DefaultListLimit, OwnerID: "owner1"}).Return([]*domain.URL{
+					{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()},
+					{ShortCode: "def456", LongURL: "https://example.org", CreatedAt: time.Now()},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCount:  2,
+		},
+		{
+			name:      "custom pagination",
+			query:     "?limit=5&offset=10",
+			principal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("List", mock.Anything, domain.ListOptions{Limit: 5, Offset: 10, OwnerID: "owner1"}).Return([]*domain.URL{}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCount:  0,
+		},
+		{
+			name:      "scoped to the caller",
+			principal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("List", mock.Anything, domain.ListOptions{Limit: application.DefaultListLimit, OwnerID: "owner1"}).Return([]*domain.URL{
+					{ShortCode: "abc123", LongURL: "https://example.com", OwnerID: "owner1", CreatedAt: time.Now()},
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+		},
+		{
+			name:           "anonymous",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=abc",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "repository error",
+			query:     "",
+			principal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("List", mock.Anything, mock.Anything).Return(nil, assert.AnError)
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			if tt.setupMocks != nil {
+				tt.setupMocks(repo)
+			}
+			handler := handlers.NewLinksHandler(application.NewShortenerService(repo, gen))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/links"+tt.query, nil)
+			if tt.principal != nil {
+				req = req.WithContext(domain.ContextWithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			handler.Links(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				var body struct {
+					Links []map[string]interface{} `json:"links"`
+				}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Len(t, body.Links, tt.expectedCount)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLinksHandler_Stats(t *testing.T) {
+	owner := &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"}
+
+	tests := []struct {
+		name           string
+		method         string
+		query          string
+		linkExists     bool
+		createdAt      time.Time
+		principal      *domain.Principal
+		expectedStatus int
+		expectedError  string
+		expectedTotal  int64
+		expectedCount  int
+	}{
+		{
+			name:           "hourly buckets",
+			method:         http.MethodGet,
+			query:          "?interval=hour&from=2025-01-01T00:00:00Z&to=2025-01-01T03:00:00Z",
+			linkExists:     true,
+			principal:      owner,
+			expectedStatus: http.StatusOK,
+			expectedTotal:  2,
+			expectedCount:  3,
+		},
+		{
+			name:           "clicks of an earlier link with the same code",
+			method:         http.MethodGet,
+			query:          "?interval=hour&from=2025-01-01T00:00:00Z&to=2025-01-01T03:00:00Z",
+			linkExists:     true,
+			createdAt:      time.Date(2025, 1, 1, 1, 40, 0, 0, time.UTC),
+			principal:      owner,
+			expectedStatus: http.StatusOK,
+			expectedTotal:  1,
+			expectedCount:  3,
+		},
+		{
+			name:           "someone else's link",
+			method:         http.MethodGet,
+			linkExists:     true,
+			principal:      &domain.Principal{OwnerID: "owner2", APIKeyID: "key2"},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+		},
+		{
+			name:           "anonymous",
+			method:         http.MethodGet,
+			linkExists:     true,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+		},
+		{
+			name:           "default window is a week of days",
+			method:         http.MethodGet,
+			linkExists:     true,
+			principal:      owner,
+			expectedStatus: http.StatusOK,
+			expectedTotal:  2,
+			expectedCount:  8,
+		},
+		{
+			name:           "unknown link",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusNotFound,
+			expectedError:  "not_found",
+		},
+		{
+			name:           "invalid interval",
+			method:         http.MethodGet,
+			query:          "?interval=week",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_stats_query",
+		},
+		{
+			name:           "invalid timestamp",
+			method:         http.MethodGet,
+			query:          "?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_stats_query",
+		},
+		{
+			name:           "range too large",
+			method:         http.MethodGet,
+			query:          "?interval=hour&from=2024-01-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+			linkExists:     true,
+			principal:      owner,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_stats_query",
+		},
+		{
+			name:           "method not allowed",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedError:  "method_not_allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			if tt.linkExists {
+				createdAt := tt.createdAt
+				if createdAt.IsZero() {
+					createdAt = time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+				}
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(&domain.URL{
+					ShortCode: "abc123",
+					LongURL:   "https://example.com",
+					OwnerID:   "owner1",
+					CreatedAt: createdAt,
+				}, nil)
+			} else {
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(nil, domain.ErrURLNotFound).Maybe()
+			}
+
+			clicks := repository.NewMemoryClickRepository()
+			assert.NoError(t, clicks.SaveClicks(context.Background(), []*domain.Click{
+				{ShortCode: "abc123", Timestamp: time.Date(2025, 1, 1, 1, 30, 0, 0, time.UTC)},
+				{ShortCode: "abc123", Timestamp: time.Date(2025, 1, 1, 1, 45, 0, 0, time.UTC)},
+			}))
+			analytics := application.NewAnalyticsService(clicks, application.AnalyticsConfig{})
+			defer analytics.Close()
+
+			handler := handlers.NewLinksHandler(application.NewShortenerService(repo, gen), handlers.WithAnalytics(analytics))
+
+			req := httptest.NewRequest(tt.method, "/api/v1/links/abc123/stats"+tt.query, nil)
+			if tt.principal != nil {
+				req = req.WithContext(domain.ContextWithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			handler.Link(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+
+			var body struct {
+				Error   string `json:"error"`
+				Total   int64  `json:"total"`
+				Buckets []struct {
+					Start time.Time `json:"start"`
+					Count int64     `json:"count"`
+				} `json:"buckets"`
+			}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.expectedError != "" {
+				assert.Equal(t, tt.expectedError, body.Error)
+				return
+			}
+			assert.Equal(t, tt.expectedTotal, body.Total)
+			assert.Len(t, body.Buckets, tt.expectedCount)
+			if tt.query != "" {
+				assert.Equal(t, tt.expectedTotal, body.Buckets[1].Count)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLinksHandler_StatsDisabled(t *testing.T) {
+	handler := handlers.NewLinksHandler(application.NewShortenerService(new(MockURLRepository), new(MockShortCodeGenerator)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/links/abc123/stats", nil)
+	w := httptest.NewRecorder()
+
+	handler.Link(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestLinksHandler_UpdateDelete(t *testing.T) {
+	existing := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/typo", OwnerID: "owner1", CreatedAt: time.Now()}
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		validToken     bool
+		principal      *domain.Principal
+		setupMocks     func(*MockURLRepository)
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:       "update",
+			method:     http.MethodPatch,
+			body:       `{"url":"https://example.com/fixed"}`,
+			validToken: true,
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:       "update redirect status only",
+			method:     http.MethodPatch,
+			body:       `{"redirect_status":301}`,
+			validToken: true,
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Update", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
+					return u.RedirectStatus == http.StatusMovedPermanently && u.LongURL == "https://example.com/typo"
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "update with nothing to change",
+			method:         http.MethodPatch,
+			body:           `{}`,
+			validToken:     true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "update without token",
+			method:         http.MethodPatch,
+			body:           `{"url":"https://example.com/fixed"}`,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+		},
+		{
+			name:           "update with invalid URL",
+			method:         http.MethodPatch,
+			body:           `{"url":"not-a-url"}`,
+			validToken:     true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_url",
+		},
+		{
+			name:           "update with unknown field",
+			method:         http.MethodPatch,
+			body:           `{"destination":"https://example.com"}`,
+			validToken:     true,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			validToken: true,
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Delete", mock.Anything, "abc123", mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete without token",
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+		},
+		{
+			name:      "update as owner without token",
+			method:    http.MethodPatch,
+			body:      `{"url":"https://example.com/fixed"}`,
+			principal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "delete as owner without token",
+			method:    http.MethodDelete,
+			principal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Delete", mock.Anything, "abc123", mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete as another owner",
+			method:         http.MethodDelete,
+			principal:      &domain.Principal{OwnerID: "owner2", APIKeyID: "key2"},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+		},
+		{
+			name:           "update as viewer of own link",
+			method:         http.MethodPatch,
+			body:           `{"url":"https://example.com/fixed"}`,
+			principal:      &domain.Principal{OwnerID: "owner1", APIKeyID: "key1", Role: domain.RoleViewer},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "forbidden",
+		},
+		{
+			name:      "delete as admin",
+			method:    http.MethodDelete,
+			principal: &domain.Principal{OwnerID: "owner2", APIKeyID: "key2", Role: domain.RoleAdmin},
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Delete", mock.Anything, "abc123", mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			repo.On("FindByShortCode", mock.Anything, "abc123").Return(existing, nil).Maybe()
+			if tt.setupMocks != nil {
+				tt.setupMocks(repo)
+			}
+			service := application.NewShortenerService(repo, new(MockShortCodeGenerator))
+			handler := handlers.NewLinksHandler(service)
+
+			req := httptest.NewRequest(tt.method, "/api/v1/links/abc123", bytes.NewBufferString(tt.body))
+			if tt.validToken {
+				req.Header.Set("X-Manage-Token", service.ManageToken(existing))
+			}
+			if tt.principal != nil {
+				req = req.WithContext(domain.ContextWithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			handler.Link(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusNoContent {
+				assert.Empty(t, w.Body.String())
+			} else {
+				var body map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				if tt.expectedError != "" {
+					assert.Equal(t, tt.expectedError, body["error"])
+				} else {
+					assert.NotEmpty(t, body["long_url"])
+					assert.NotZero(t, body["redirect_status"])
+					assert.Nil(t, body["manage_token"])
+				}
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLinksHandler_Admin(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	for i, owner := range []string{"alice", "bob", ""} {
+		require.NoError(t, repo.Create(ctx, &domain.URL{
+			ShortCode: "link-" + owner,
+			LongURL:   "https://example.com/" + owner,
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+			OwnerID:   owner,
+		}))
+	}
+	handler := handlers.NewLinksHandler(application.NewShortenerService(repo, new(MockShortCodeGenerator)))
+
+	admin := &domain.Principal{OwnerID: "root", APIKeyID: "key1", Role: domain.RoleAdmin}
+	creator := &domain.Principal{OwnerID: "alice", APIKeyID: "key2"}
+	serve := func(principal *domain.Principal, method, target, body string, serve http.HandlerFunc) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		if principal != nil {
+			req = req.WithContext(domain.ContextWithPrincipal(req.Context(), principal))
+		}
+		w := httptest.NewRecorder()
+		serve(w, req)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp
+	}
+
+	t.Run("list every link", func(t *testing.T) {
+		w, resp := serve(admin, http.MethodGet, "/api/v1/admin/links", "", handler.AdminLinks)
+		require.Equal(t, http.StatusOK, w.Code)
+		links := resp["links"].([]interface{})
+		require.Len(t, links, 3)
+		assert.Equal(t, "link-", links[0].(map[string]interface{})["short_code"])
+		assert.Equal(t, "bob", links[1].(map[string]interface{})["owner_id"])
+	})
+
+	t.Run("list by owner", func(t *testing.T) {
+		w, resp := serve(admin, http.MethodGet, "/api/v1/admin/links?owner=alice&limit=5", "", handler.AdminLinks)
+		require.Equal(t, http.StatusOK, w.Code)
+		links := resp["links"].([]interface{})
+		require.Len(t, links, 1)
+		assert.Equal(t, "link-alice", links[0].(map[string]interface{})["short_code"])
+		assert.EqualValues(t, 5, resp["limit"])
+	})
+
+	t.Run("list as creator", func(t *testing.T) {
+		w, resp := serve(creator, http.MethodGet, "/api/v1/admin/links", "", handler.AdminLinks)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "forbidden", resp["error"])
+		assert.Equal(t, "The creator role may not administer links", resp["message"])
+	})
+
+	t.Run("list anonymously", func(t *testing.T) {
+		w, _ := serve(nil, http.MethodGet, "/api/v1/admin/links", "", handler.AdminLinks)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("disable and reassign", func(t *testing.T) {
+		w, resp := serve(admin, http.MethodPatch, "/api/v1/admin/links/link-bob", `{"disabled":true,"owner_id":"alice"}`, handler.AdminLink)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, true, resp["disabled"])
+		assert.Equal(t, "alice", resp["owner_id"])
+
+		found, err := repo.FindByShortCode(ctx, "link-bob")
+		require.NoError(t, err)
+		assert.True(t, found.IsDisabled())
+		assert.Equal(t, "alice", found.OwnerID)
+
+		// The new owner sees the link, disabled, in their own listing.
+		w, resp = serve(creator, http.MethodGet, "/api/v1/links", "", handler.Links)
+		require.Equal(t, http.StatusOK, w.Code)
+		links := resp["links"].([]interface{})
+		require.Len(t, links, 2)
+		assert.Equal(t, true, links[0].(map[string]interface{})["disabled"])
+		assert.Nil(t, links[0].(map[string]interface{})["owner_id"], "owners are only shown to admins")
+
+		w, resp = serve(admin, http.MethodPatch, "/api/v1/admin/links/link-bob", `{"disabled":false}`, handler.AdminLink)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, resp["disabled"])
+	})
+
+	t.Run("update as creator", func(t *testing.T) {
+		w, resp := serve(creator, http.MethodPatch, "/api/v1/admin/links/link-alice", `{"disabled":true}`, handler.AdminLink)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, "forbidden", resp["error"])
+	})
+
+	t.Run("update with nothing to change", func(t *testing.T) {
+		w, resp := serve(admin, http.MethodPatch, "/api/v1/admin/links/link-alice", `{}`, handler.AdminLink)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "invalid_request", resp["error"])
+	})
+
+	t.Run("update missing link", func(t *testing.T) {
+		w, _ := serve(admin, http.MethodPatch, "/api/v1/admin/links/missing", `{"disabled":true}`, handler.AdminLink)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		w, _ := serve(admin, http.MethodDelete, "/api/v1/admin/links/link-alice", "", handler.AdminLink)
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "PATCH", w.Header().Get("Allow"))
+	})
+}
+
+func TestLinksHandler_Protected(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	handler := handlers.NewLinksHandler(application.NewShortenerService(repo, new(MockShortCodeGenerator)))
+	alice := &domain.Principal{OwnerID: "alice", APIKeyID: "key1"}
+
+	serve := func(principal *domain.Principal, method, target, body, manageToken string, serve http.HandlerFunc) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+		if principal != nil {
+			req = req.WithContext(domain.ContextWithPrincipal(req.Context(), principal))
+		}
+		if manageToken != "" {
+			req.Header.Set("X-Manage-Token", manageToken)
+		}
+		w := httptest.NewRecorder()
+		serve(w, req)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp
+	}
+
+	w, resp := serve(nil, http.MethodPost, "/api/v1/links", `{"url":"https://example.com/doc","alias":"too-long","password":"`+strings.Repeat("x", 73)+`"}`, "", handler.Links)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_password", resp["error"])
+
+	w, resp = serve(nil, http.MethodPost, "/api/v1/links", `{"url":"https://example.com/doc","alias":"handbook","password":"s3cret"}`, "", handler.Links)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, true, resp["protected"])
+	assert.Equal(t, "https://example.com/doc", resp["long_url"])
+	assert.NotContains(t, w.Body.String(), "s3cret")
+	manageToken := resp["manage_token"].(string)
+
+	w, _ = serve(alice, http.MethodPost, "/api/v1/links", `{"url":"https://example.com/alice","alias":"alices-doc","password":"hunter2"}`, "", handler.Links)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// Visitors have to enter the password to learn where the link goes.
+	w, resp = serve(nil, http.MethodGet, "/api/v1/links/handbook", "", "", handler.Link)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, resp["protected"])
+	assert.Nil(t, resp["long_url"])
+
+	w, resp = serve(nil, http.MethodGet, "/api/v1/links/handbook", "", manageToken, handler.Link)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://example.com/doc", resp["long_url"])
+
+	w, resp = serve(nil, http.MethodGet, "/api/v1/links", "", "", handler.Links)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "unauthorized", resp["error"])
+
+	w, resp = serve(alice, http.MethodGet, "/api/v1/links", "", "", handler.Links)
+	require.Equal(t, http.StatusOK, w.Code)
+	links := resp["links"].([]interface{})
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://example.com/alice", links[0].(map[string]interface{})["long_url"], "owners see their own destinations")
+}
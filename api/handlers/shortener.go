@@ -1,28 +1,67 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
 	"url-shortener/internal/infrastructure/qrcode"
+	"url-shortener/pkg/middleware"
+)
+
+const (
+	// maxClickFieldLength bounds the client-supplied headers stored per click.
+	maxClickFieldLength = 512
+
+	// DefaultRedirectCacheMaxAge is how long browsers may cache a permanent
+	// redirect. It is kept finite so an edited link eventually takes effect.
+	DefaultRedirectCacheMaxAge = 24 * time.Hour
 )
 
 type ShortenerHandler struct {
 	service     *application.ShortenerService
 	tmpl        *template.Template
 	qrGenerator *qrcode.QRCodeGenerator
+	clicks      domain.ClickRecorder
+
+	redirectCacheMaxAge time.Duration
 }
 
-func NewShortenerHandler(service *application.ShortenerService, tmpl *template.Template) *ShortenerHandler {
-	return &ShortenerHandler{
+type ShortenerHandlerOption func(*ShortenerHandler)
+
+// WithClickRecorder records a click for every successful redirect.
+func WithClickRecorder(recorder domain.ClickRecorder) ShortenerHandlerOption {
+	return func(h *ShortenerHandler) {
+		h.clicks = recorder
+	}
+}
+
+// WithRedirectCacheMaxAge sets the max-age sent with permanent redirects.
+func WithRedirectCacheMaxAge(maxAge time.Duration) ShortenerHandlerOption {
+	return func(h *ShortenerHandler) {
+		h.redirectCacheMaxAge = maxAge
+	}
+}
+
+func NewShortenerHandler(service *application.ShortenerService, tmpl *template.Template, opts ...ShortenerHandlerOption) *ShortenerHandler {
+	h := &ShortenerHandler{
 		service:     service,
 		tmpl:        tmpl,
 		qrGenerator: qrcode.NewQRCodeGenerator(),
+
+		redirectCacheMaxAge: DefaultRedirectCacheMaxAge,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 func (h *ShortenerHandler) ShowForm(w http.ResponseWriter, r *http.Request) {
@@ -31,7 +70,14 @@ func (h *ShortenerHandler) ShowForm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.tmpl.ExecuteTemplate(w, "form.html", nil); err != nil {
+	// Email is shown to logged in users, whose links are listed on their
+	// dashboard.
+	var data struct{ Email string }
+	if p := domain.PrincipalFromContext(r.Context()); p != nil && p.UserID != "" {
+		data.Email = p.Email
+	}
+
+	if err := h.tmpl.ExecuteTemplate(w, "form.html", data); err != nil {
 		log.Printf("Error rendering form template: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -60,11 +106,49 @@ func (h *ShortenerHandler) CreateShortURL(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	opts := application.CreateOptions{
+		Alias: strings.TrimSpace(r.FormValue("alias")),
+	}
+	if err := parseExpiresIn(r.FormValue("expires_in"), &opts); err != nil {
+		http.Error(w, "Invalid expiration", http.StatusBadRequest)
+		return
+	}
+	if err := parseRedirectStatus(r.FormValue("redirect_status"), &opts.RedirectStatus); err != nil {
+		http.Error(w, "Invalid redirect type", http.StatusBadRequest)
+		return
+	}
+	opts.Password = r.FormValue("password")
+
 	ctx := r.Context()
-	shortURL, err := h.service.CreateShortURL(ctx, longURL)
+	shortURL, err := h.service.CreateShortURL(ctx, longURL, opts)
 	if err != nil {
-		log.Printf("Error creating short URL: %v", err)
-		http.Error(w, "Failed to create short URL", http.StatusInternalServerError)
+		var permErr *domain.PermissionError
+		switch {
+		case errors.Is(err, domain.ErrInvalidAlias):
+			http.Error(w, "Invalid alias: use 3-32 letters, digits, '-' or '_' and avoid reserved words", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrShortCodeExists):
+			http.Error(w, "Alias is already in use", http.StatusConflict)
+		case errors.Is(err, domain.ErrShortCodeUnavailable):
+			log.Printf("Error creating short URL: %v", err)
+			http.Error(w, "No short code available, try again later", http.StatusServiceUnavailable)
+		case errors.Is(err, domain.ErrInvalidURL):
+			http.Error(w, "Invalid URL format", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrDisallowedURL):
+			http.Error(w, "This destination is not allowed", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrUnsafeURL):
+			http.Error(w, "This destination has been flagged as unsafe", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrInvalidExpiry):
+			http.Error(w, "Invalid expiration", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrInvalidRedirect):
+			http.Error(w, "Invalid redirect type", http.StatusBadRequest)
+		case errors.Is(err, domain.ErrInvalidLinkPassword):
+			http.Error(w, "Invalid password: use at most 72 bytes", http.StatusBadRequest)
+		case errors.As(err, &permErr):
+			http.Error(w, roleMessage(permErr), http.StatusForbidden)
+		default:
+			log.Printf("Error creating short URL: %v", err)
+			http.Error(w, "Failed to create short URL", http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -72,10 +156,16 @@ func (h *ShortenerHandler) CreateShortURL(w http.ResponseWriter, r *http.Request
 		ShortCode string
 		LongURL   string
 		ShortURL  string
+		ExpiresAt *time.Time
+		ManageURL string // empty for a reused link, which belongs to its creator
 	}{
 		ShortCode: shortURL.ShortCode,
 		LongURL:   shortURL.LongURL,
-		ShortURL:  h.buildShortURL(r, shortURL.ShortCode),
+		ShortURL:  buildShortURL(r, shortURL.ShortCode),
+		ExpiresAt: shortURL.ExpiresAt,
+	}
+	if !shortURL.Reused {
+		data.ManageURL = buildManageURL(r, shortURL.ShortCode, h.service.ManageToken(shortURL))
 	}
 
 	if err := h.tmpl.ExecuteTemplate(w, "result.html", data); err != nil {
@@ -85,8 +175,10 @@ func (h *ShortenerHandler) CreateShortURL(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// Redirect sends visitors on to a link's destination. Protected links first
+// show a password prompt, which is posted back here.
 func (h *ShortenerHandler) Redirect(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -98,14 +190,283 @@ func (h *ShortenerHandler) Redirect(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	longURL, err := h.service.GetLongURL(ctx, shortCode)
+	shortURL, err := h.service.GetURL(ctx, shortCode)
 	if err != nil {
 		log.Printf("Error getting long URL: %v", err)
 		http.Error(w, "URL not found", http.StatusNotFound)
 		return
 	}
+	if shortURL.IsDisabled() {
+		http.Error(w, "This link has been disabled", http.StatusGone)
+		return
+	}
+	if shortURL.IsProtected() {
+		if !h.unlock(w, r, shortURL) {
+			return
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scan, err := h.service.ScanRedirect(ctx, shortURL)
+	if err != nil {
+		log.Printf("Error scanning destination of %s: %v", shortURL.ShortCode, err)
+	}
+	if scan.Flagged() {
+		h.renderWarning(w, shortURL, scan)
+		return
+	}
 
-	http.Redirect(w, r, longURL, http.StatusMovedPermanently)
+	if h.clicks != nil {
+		h.clicks.Record(&domain.Click{
+			ShortCode: shortURL.ShortCode,
+			Timestamp: time.Now(),
+			Referrer:  truncate(r.Referer(), maxClickFieldLength),
+			UserAgent: truncate(r.UserAgent(), maxClickFieldLength),
+		})
+	}
+
+	// The answer to a password must never be cached, and See Other makes
+	// the browser follow it with a GET whatever the link's own status is.
+	if shortURL.IsProtected() {
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, shortURL.LongURL, http.StatusSeeOther)
+		return
+	}
+
+	status := h.service.RedirectStatus(shortURL)
+	w.Header().Set("Cache-Control", redirectCacheControl(status, shortURL.ExpiresAt, h.redirectCacheMaxAge))
+	http.Redirect(w, r, shortURL.LongURL, status)
+}
+
+// unlock shows the password prompt of a protected link, reporting true once
+// the visitor posted the right password.
+func (h *ShortenerHandler) unlock(w http.ResponseWriter, r *http.Request, shortURL *domain.URL) bool {
+	if r.Method != http.MethodPost {
+		h.renderPasswordPrompt(w, shortURL, http.StatusOK, "")
+		return false
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return false
+	}
+
+	err := h.service.UnlockURL(r.Context(), shortURL, r.PostFormValue("password"), middleware.ClientIP(r))
+	var attemptsErr *domain.AttemptsError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &attemptsErr):
+		// Rounded up so visitors never come back too early.
+		retryAfter := int64((attemptsErr.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.FormatInt(max(1, retryAfter), 10))
+		h.renderPasswordPrompt(w, shortURL, http.StatusTooManyRequests, "Too many attempts, try again later")
+	case errors.Is(err, domain.ErrWrongPassword):
+		h.renderPasswordPrompt(w, shortURL, http.StatusForbidden, "Wrong password")
+	default:
+		log.Printf("Error unlocking %s: %v", shortURL.ShortCode, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return false
+}
+
+func (h *ShortenerHandler) renderPasswordPrompt(w http.ResponseWriter, shortURL *domain.URL, status int, message string) {
+	data := struct {
+		ShortCode string
+		Error     string
+	}{
+		ShortCode: shortURL.ShortCode,
+		Error:     message,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(status)
+	if err := h.tmpl.ExecuteTemplate(w, "password.html", data); err != nil {
+		log.Printf("Error rendering password template: %v", err)
+	}
+}
+
+// renderWarning shows an interstitial instead of redirecting to a
+// destination the scanner flagged. Visitors can still continue at their own
+// risk; those clicks aren't counted.
+func (h *ShortenerHandler) renderWarning(w http.ResponseWriter, shortURL *domain.URL, scan *domain.ScanResult) {
+	data := struct {
+		ShortCode string
+		LongURL   string
+		Threat    domain.Threat
+		Detail    string
+	}{
+		ShortCode: shortURL.ShortCode,
+		LongURL:   shortURL.LongURL,
+		Threat:    scan.Threat,
+		Detail:    scan.Detail,
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.WriteHeader(http.StatusOK)
+	if err := h.tmpl.ExecuteTemplate(w, "warning.html", data); err != nil {
+		log.Printf("Error rendering warning template: %v", err)
+	}
+}
+
+// redirectCacheControl lets browsers and proxies cache permanent redirects for
+// at most maxAge, and never past the link's expiry. Temporary redirects must
+// be revalidated so edits, expiry and click counting apply on every visit.
+func redirectCacheControl(status int, expiresAt *time.Time, maxAge time.Duration) string {
+	if !domain.PermanentRedirect(status) {
+		return "private, no-cache"
+	}
+
+	if expiresAt != nil {
+		if remaining := time.Until(*expiresAt); remaining < maxAge {
+			maxAge = remaining
+		}
+	}
+	if maxAge <= 0 {
+		return "private, no-cache"
+	}
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+type managePageData struct {
+	ShortCode      string
+	ShortURL       string
+	LongURL        string
+	RedirectStatus int
+	Token          string
+	Message        string
+	Error          string
+	Deleted        bool
+}
+
+// Manage serves /manage/{shortCode}, where whoever holds the manage token
+// handed out at creation can change the destination or delete the link.
+func (h *ShortenerHandler) Manage(w http.ResponseWriter, r *http.Request) {
+	shortCode := strings.TrimPrefix(r.URL.Path, "/manage/")
+	if shortCode == "" || strings.Contains(shortCode, "/") {
+		http.Error(w, "URL not found", http.StatusNotFound)
+		return
+	}
+
+	// the page URL carries the token, so keep it out of Referer headers
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	switch r.Method {
+	case http.MethodGet:
+		h.showManagePage(w, r, shortCode)
+	case http.MethodPost:
+		h.submitManagePage(w, r, shortCode)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *ShortenerHandler) showManagePage(w http.ResponseWriter, r *http.Request, shortCode string) {
+	token := r.URL.Query().Get("token")
+
+	shortURL, err := h.service.Authorize(r.Context(), shortCode, token)
+	if err != nil {
+		writeManageError(w, err)
+		return
+	}
+
+	h.renderManagePage(w, http.StatusOK, managePageData{
+		ShortCode:      shortURL.ShortCode,
+		ShortURL:       buildShortURL(r, shortURL.ShortCode),
+		LongURL:        shortURL.LongURL,
+		RedirectStatus: h.service.RedirectStatus(shortURL),
+		Token:          token,
+	})
+}
+
+func (h *ShortenerHandler) submitManagePage(w http.ResponseWriter, r *http.Request, shortCode string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+	token := r.PostFormValue("token")
+
+	data := managePageData{
+		ShortCode: shortCode,
+		ShortURL:  buildShortURL(r, shortCode),
+		Token:     token,
+	}
+
+	switch r.PostFormValue("action") {
+	case "update":
+		opts := application.UpdateOptions{LongURL: strings.TrimSpace(r.PostFormValue("url"))}
+		if err := parseRedirectStatus(r.PostFormValue("redirect_status"), &opts.RedirectStatus); err != nil {
+			http.Error(w, "Invalid redirect type", http.StatusBadRequest)
+			return
+		}
+		if opts.LongURL == "" {
+			http.Error(w, "URL is required", http.StatusBadRequest)
+			return
+		}
+
+		shortURL, err := h.service.UpdateURL(r.Context(), shortCode, token, opts)
+		if errors.Is(err, domain.ErrInvalidURL) || errors.Is(err, domain.ErrInvalidRedirect) ||
+			errors.Is(err, domain.ErrDisallowedURL) || errors.Is(err, domain.ErrUnsafeURL) {
+			data.LongURL = opts.LongURL
+			data.RedirectStatus = opts.RedirectStatus
+			switch {
+			case errors.Is(err, domain.ErrDisallowedURL):
+				data.Error = "This destination is not allowed"
+			case errors.Is(err, domain.ErrUnsafeURL):
+				data.Error = "This destination has been flagged as unsafe"
+			default:
+				data.Error = "Invalid URL or redirect type"
+			}
+			h.renderManagePage(w, http.StatusBadRequest, data)
+			return
+		}
+		if err != nil {
+			writeManageError(w, err)
+			return
+		}
+		data.LongURL = shortURL.LongURL
+		data.RedirectStatus = h.service.RedirectStatus(shortURL)
+		data.Message = "Link updated"
+	case "delete":
+		if err := h.service.DeleteURL(r.Context(), shortCode, token); err != nil {
+			writeManageError(w, err)
+			return
+		}
+		data.Deleted = true
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+
+	h.renderManagePage(w, http.StatusOK, data)
+}
+
+func (h *ShortenerHandler) renderManagePage(w http.ResponseWriter, status int, data managePageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.tmpl.ExecuteTemplate(w, "manage.html", data); err != nil {
+		log.Printf("Error rendering manage template: %v", err)
+	}
+}
+
+func writeManageError(w http.ResponseWriter, err error) {
+	var permErr *domain.PermissionError
+	switch {
+	case errors.Is(err, domain.ErrURLNotFound):
+		http.Error(w, "URL not found", http.StatusNotFound)
+	case errors.As(err, &permErr) && permErr.ShortCode == "":
+		http.Error(w, roleMessage(permErr), http.StatusForbidden)
+	case errors.Is(err, domain.ErrForbidden):
+		http.Error(w, "Invalid or missing manage token", http.StatusForbidden)
+	default:
+		log.Printf("Error managing short URL: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
 }
 
 func (h *ShortenerHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
@@ -121,14 +482,18 @@ func (h *ShortenerHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
-	_, err := h.service.GetLongURL(ctx, path)
+	link, err := h.service.GetURL(ctx, path)
 	if err != nil {
 		log.Printf("Error getting long URL for QR code: %v", err)
 		http.Error(w, "URL not found", http.StatusNotFound)
 		return
 	}
+	if link.IsDisabled() {
+		http.Error(w, "This link has been disabled", http.StatusGone)
+		return
+	}
 
-	shortURL := h.buildShortURL(r, path)
+	shortURL := buildShortURL(r, path)
 
 	pngData, err := h.qrGenerator.GeneratePNG(shortURL)
 	if err != nil {
@@ -146,10 +511,56 @@ func (h *ShortenerHandler) GetQRCode(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *ShortenerHandler) buildShortURL(r *http.Request, shortCode string) string {
+func buildShortURL(r *http.Request, shortCode string) string {
 	scheme := "http"
 	if r.TLS != nil {
 		scheme = "https"
 	}
 	return fmt.Sprintf("%s://%s/%s", scheme, r.Host, shortCode)
 }
+
+// parseExpiresIn accepts a Go duration such as "24h", or "never".
+func parseExpiresIn(value string, opts *application.CreateOptions) error {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return nil
+	case "never":
+		opts.NoExpiry = true
+		return nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidExpiry, err)
+	}
+	opts.ExpiresIn = d
+	return nil
+}
+
+// parseRedirectStatus accepts an empty value, meaning the default, or one of
+// the numeric redirect statuses.
+func parseRedirectStatus(value string, status *int) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	code, err := strconv.Atoi(value)
+	if err != nil || !domain.ValidRedirectStatus(code) {
+		return domain.ErrInvalidRedirect
+	}
+	*status = code
+	return nil
+}
+
+func buildManageURL(r *http.Request, shortCode, token string) string {
+	return buildShortURL(r, "manage/"+shortCode) + "?token=" + url.QueryEscape(token)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -8,20 +8,29 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 	"url-shortener/api/handlers"
 	"url-shortener/internal/application"
 	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/ratelimiter"
+	"url-shortener/internal/infrastructure/repository"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockURLRepository struct {
 	mock.Mock
 }
 
+func (m *MockURLRepository) Create(ctx context.Context, url *domain.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
 func (m *MockURLRepository) Save(ctx context.Context, url *domain.URL) error {
 	args := m.Called(ctx, url)
 	return args.Error(0)
@@ -35,11 +44,42 @@ func (m *MockURLRepository) FindByShortCode(ctx context.Context, shortCode strin
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
 
+func (m *MockURLRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URL, error) {
+	args := m.Called(ctx, longURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
 func (m *MockURLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
 	args := m.Called(ctx, shortCode)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockURLRepository) List(ctx context.Context, opts domain.ListOptions) ([]*domain.URL, error) {
+	args := m.Called(ctx, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) Moderate(ctx context.Context, url *domain.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) Delete(ctx context.Context, shortCode string, tombstoneUntil time.Time) error {
+	args := m.Called(ctx, shortCode, tombstoneUntil)
+	return args.Error(0)
+}
+
 type MockShortCodeGenerator struct {
 	mock.Mock
 }
@@ -122,13 +162,47 @@ func TestShortenerHandler_CreateShortURL(t *testing.T) {
 			},
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
 				gen.On("Generate").Return("abc123")
-				repo.On("Exists", mock.Anything, "abc123").Return(false, nil)
-				repo.On("Save", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
 					return u.ShortCode == "abc123" && u.LongURL == "https://example.com"
 				})).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:   "POST request - custom alias",
+			method: http.MethodPost,
+			formData: url.Values{
+				"url":   []string{"https://example.com"},
+				"alias": []string{"spring-sale"},
+			},
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
+					return u.ShortCode == "spring-sale"
+				})).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "POST request - alias taken",
+			method: http.MethodPost,
+			formData: url.Values{
+				"url":   []string{"https://example.com"},
+				"alias": []string{"spring-sale"},
+			},
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(domain.ErrShortCodeExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "POST request - reserved alias",
+			method: http.MethodPost,
+			formData: url.Values{
+				"url":   []string{"https://example.com"},
+				"alias": []string{"shorten"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "GET request - method not allowed",
 			method:         http.MethodGet,
@@ -151,6 +225,14 @@ func TestShortenerHandler_CreateShortURL(t *testing.T) {
 			},
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name:   "POST request - disallowed scheme",
+			method: http.MethodPost,
+			formData: url.Values{
+				"url": []string{"data:text/html,<script>alert(1)</script>"},
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:   "POST request - service error on save",
 			method: http.MethodPost,
@@ -159,8 +241,7 @@ func TestShortenerHandler_CreateShortURL(t *testing.T) {
 			},
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
 				gen.On("Generate").Return("abc123")
-				repo.On("Exists", mock.Anything, "abc123").Return(false, nil)
-				repo.On("Save", mock.Anything, mock.Anything).Return(assert.AnError)
+				repo.On("Create", mock.Anything, mock.Anything).Return(assert.AnError)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -172,8 +253,7 @@ func TestShortenerHandler_CreateShortURL(t *testing.T) {
 			},
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
 				gen.On("Generate").Return("abc123")
-				repo.On("Exists", mock.Anything, "abc123").Return(false, nil)
-				repo.On("Save", mock.Anything, mock.Anything).Return(nil)
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
 			},
 			expectedStatus: http.StatusInternalServerError,
 		},
@@ -223,6 +303,23 @@ func TestShortenerHandler_CreateShortURL(t *testing.T) {
 	}
 }
 
+func TestShortenerHandler_CreateShortURL_Viewer(t *testing.T) {
+	repo := new(MockURLRepository)
+	tmpl := template.Must(template.New("result.html").Parse(`ShortCode: {{.ShortCode}}`))
+	handler := handlers.NewShortenerHandler(application.NewShortenerService(repo, new(MockShortCodeGenerator)), tmpl)
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(url.Values{"url": {"https://example.com"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(domain.ContextWithPrincipal(req.Context(), &domain.Principal{OwnerID: "u1", UserID: "u1", Role: domain.RoleViewer}))
+	w := httptest.NewRecorder()
+
+	handler.CreateShortURL(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "The viewer role may not create links")
+	repo.AssertExpectations(t)
+}
+
 func TestShortenerHandler_Redirect(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -231,6 +328,7 @@ func TestShortenerHandler_Redirect(t *testing.T) {
 		setupMocks     func(*MockURLRepository, *MockShortCodeGenerator)
 		expectedStatus int
 		expectedURL    string
+		expectedCache  string
 	}{
 		{
 			name:   "GET request - success",
@@ -244,12 +342,77 @@ func TestShortenerHandler_Redirect(t *testing.T) {
 				}
 				repo.On("FindByShortCode", mock.Anything, "abc123").Return(url, nil)
 			},
+			expectedStatus: http.StatusFound,
+			expectedURL:    "https://example.com",
+			expectedCache:  "private, no-cache",
+		},
+		{
+			name:   "GET request - permanent redirect is cached",
+			method: http.MethodGet,
+			path:   "/perm301",
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("FindByShortCode", mock.Anything, "perm301").Return(&domain.URL{
+					ShortCode:      "perm301",
+					LongURL:        "https://example.com",
+					CreatedAt:      time.Now(),
+					RedirectStatus: http.StatusMovedPermanently,
+				}, nil)
+			},
 			expectedStatus: http.StatusMovedPermanently,
 			expectedURL:    "https://example.com",
+			expectedCache:  "public, max-age=86400",
 		},
 		{
-			name:           "POST request - method not allowed",
-			method:         http.MethodPost,
+			name:   "GET request - permanent redirect cached until expiry",
+			method: http.MethodGet,
+			path:   "/perm308",
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				expiresAt := time.Now().Add(10*time.Minute + time.Second)
+				repo.On("FindByShortCode", mock.Anything, "perm308").Return(&domain.URL{
+					ShortCode:      "perm308",
+					LongURL:        "https://example.com",
+					CreatedAt:      time.Now(),
+					ExpiresAt:      &expiresAt,
+					RedirectStatus: http.StatusPermanentRedirect,
+				}, nil)
+			},
+			expectedStatus: http.StatusPermanentRedirect,
+			expectedURL:    "https://example.com",
+			expectedCache:  "public, max-age=600",
+		},
+		{
+			name:   "GET request - temporary redirect keeping method",
+			method: http.MethodGet,
+			path:   "/temp307",
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("FindByShortCode", mock.Anything, "temp307").Return(&domain.URL{
+					ShortCode:      "temp307",
+					LongURL:        "https://example.com",
+					CreatedAt:      time.Now(),
+					RedirectStatus: http.StatusTemporaryRedirect,
+				}, nil)
+			},
+			expectedStatus: http.StatusTemporaryRedirect,
+			expectedURL:    "https://example.com",
+			expectedCache:  "private, no-cache",
+		},
+		{
+			name:   "POST request - method not allowed",
+			method: http.MethodPost,
+			path:   "/abc123",
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				url := &domain.URL{
+					ShortCode: "abc123",
+					LongURL:   "https://example.com",
+					CreatedAt: time.Now(),
+				}
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(url, nil)
+			},
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "PUT request - method not allowed",
+			method:         http.MethodPut,
 			path:           "/abc123",
 			expectedStatus: http.StatusMethodNotAllowed,
 		},
@@ -284,6 +447,21 @@ func TestShortenerHandler_Redirect(t *testing.T) {
 			},
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:   "GET request - disabled URL",
+			method: http.MethodGet,
+			path:   "/disabled1",
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				disabledAt := time.Now().Add(-time.Minute)
+				repo.On("FindByShortCode", mock.Anything, "disabled1").Return(&domain.URL{
+					ShortCode:  "disabled1",
+					LongURL:    "https://example.com",
+					CreatedAt:  time.Now().Add(-time.Hour),
+					DisabledAt: &disabledAt,
+				}, nil)
+			},
+			expectedStatus: http.StatusGone,
+		},
 	}
 
 	for _, tt := range tests {
@@ -307,11 +485,217 @@ func TestShortenerHandler_Redirect(t *testing.T) {
 			if tt.expectedURL != "" {
 				assert.Equal(t, tt.expectedURL, w.Header().Get("Location"))
 			}
+			if tt.expectedCache != "" {
+				assert.Equal(t, tt.expectedCache, w.Header().Get("Cache-Control"))
+			}
 			repo.AssertExpectations(t)
 		})
 	}
 }
 
+type clickRecorderFunc func(*domain.Click)
+
+func (f clickRecorderFunc) Record(click *domain.Click) { f(click) }
+
+func TestShortenerHandler_Redirect_RecordsClick(t *testing.T) {
+	repo := new(MockURLRepository)
+	gen := new(MockShortCodeGenerator)
+	repo.On("FindByShortCode", mock.Anything, "abc123").Return(&domain.URL{
+		ShortCode: "abc123",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Now(),
+	}, nil)
+	repo.On("FindByShortCode", mock.Anything, "missing").Return(nil, domain.ErrURLNotFound)
+
+	var clicks []*domain.Click
+	recorder := clickRecorderFunc(func(c *domain.Click) { clicks = append(clicks, c) })
+
+	service := application.NewShortenerService(repo, gen)
+	tmpl := template.Must(template.New("test").Parse("test"))
+	handler := handlers.NewShortenerHandler(service, tmpl, handlers.WithClickRecorder(recorder))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req.Header.Set("Referer", "https://news.example/post")
+	req.Header.Set("User-Agent", strings.Repeat("x", 1000))
+	handler.Redirect(httptest.NewRecorder(), req)
+
+	handler.Redirect(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	assert.Len(t, clicks, 1)
+	assert.Equal(t, "abc123", clicks[0].ShortCode)
+	assert.Equal(t, "https://news.example/post", clicks[0].Referrer)
+	assert.Len(t, clicks[0].UserAgent, 512)
+	assert.WithinDuration(t, time.Now(), clicks[0].Timestamp, time.Second)
+}
+
+func TestShortenerHandler_Redirect_CaseInsensitive(t *testing.T) {
+	repo := new(MockURLRepository)
+	repo.On("FindByShortCode", mock.Anything, "Brave-Otter-42").Return(nil, domain.ErrURLNotFound)
+	repo.On("FindByShortCode", mock.Anything, "brave-otter-42").Return(&domain.URL{
+		ShortCode: "brave-otter-42",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Now(),
+	}, nil)
+
+	var clicks []*domain.Click
+	recorder := clickRecorderFunc(func(c *domain.Click) { clicks = append(clicks, c) })
+
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithCaseInsensitiveCodes())
+	tmpl := template.Must(template.New("test").Parse("test"))
+	handler := handlers.NewShortenerHandler(service, tmpl, handlers.WithClickRecorder(recorder))
+
+	w := httptest.NewRecorder()
+	handler.Redirect(w, httptest.NewRequest(http.MethodGet, "/Brave-Otter-42", nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	require.Len(t, clicks, 1)
+	assert.Equal(t, "brave-otter-42", clicks[0].ShortCode, "clicks should be counted under the stored code")
+}
+
+type scannerFunc func(rawURL string) (*domain.ScanResult, error)
+
+func (f scannerFunc) Scan(ctx context.Context, rawURL string) (*domain.ScanResult, error) {
+	return f(rawURL)
+}
+
+func TestShortenerHandler_Redirect_UnsafeDestination(t *testing.T) {
+	repo := new(MockURLRepository)
+	repo.On("FindByShortCode", mock.Anything, "phish").Return(&domain.URL{
+		ShortCode: "phish",
+		LongURL:   "https://phish.example/login",
+		CreatedAt: time.Now(),
+	}, nil)
+	repo.On("FindByShortCode", mock.Anything, "clean").Return(&domain.URL{
+		ShortCode: "clean",
+		LongURL:   "https://example.com",
+		CreatedAt: time.Now(),
+	}, nil)
+
+	scanner := scannerFunc(func(rawURL string) (*domain.ScanResult, error) {
+		if rawURL == "https://phish.example/login" {
+			return &domain.ScanResult{Threat: domain.ThreatPhishing, Detail: "reported by users"}, nil
+		}
+		return &domain.ScanResult{}, nil
+	})
+
+	var clicks []*domain.Click
+	recorder := clickRecorderFunc(func(c *domain.Click) { clicks = append(clicks, c) })
+
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator),
+		application.WithURLScanner(scanner, application.ScanPolicy{OnRedirect: true}))
+	tmpl := template.Must(template.ParseGlob("../templates/*.html"))
+	handler := handlers.NewShortenerHandler(service, tmpl, handlers.WithClickRecorder(recorder))
+
+	w := httptest.NewRecorder()
+	handler.Redirect(w, httptest.NewRequest(http.MethodGet, "/phish", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), "phishing site")
+	assert.Contains(t, w.Body.String(), "reported by users")
+	assert.Contains(t, w.Body.String(), `href="https://phish.example/login"`)
+	assert.Empty(t, clicks, "warnings should not count as clicks")
+
+	w = httptest.NewRecorder()
+	handler.Redirect(w, httptest.NewRequest(http.MethodGet, "/clean", nil))
+
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Location"))
+	assert.Len(t, clicks, 1)
+}
+
+func TestShortenerHandler_Redirect_Protected(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	limiter := ratelimiter.NewMemoryRateLimiter(3, time.Minute)
+	defer limiter.(*ratelimiter.MemoryRateLimiter).Close()
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithPasswordAttemptLimiter(limiter))
+	_, err := service.CreateShortURL(context.Background(), "https://example.com/doc", application.CreateOptions{
+		Alias:          "handbook",
+		RedirectStatus: http.StatusMovedPermanently,
+		Password:       "s3cret",
+	})
+	require.NoError(t, err)
+
+	var clicks []*domain.Click
+	recorder := clickRecorderFunc(func(c *domain.Click) { clicks = append(clicks, c) })
+	tmpl := template.Must(template.ParseGlob("../templates/*.html"))
+	handler := handlers.NewShortenerHandler(service, tmpl, handlers.WithClickRecorder(recorder))
+
+	unlock := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/handbook", strings.NewReader(url.Values{"password": {password}}.Encode()))
+		req.RemoteAddr = "198.51.100.7:1234"
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler.Redirect(w, req)
+		return w
+	}
+
+	w := httptest.NewRecorder()
+	handler.Redirect(w, httptest.NewRequest(http.MethodGet, "/handbook", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), `action="/handbook"`)
+	assert.NotContains(t, w.Body.String(), "example.com/doc")
+
+	w = unlock("guess")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Wrong password")
+	assert.Empty(t, clicks)
+
+	w = unlock("s3cret")
+	assert.Equal(t, http.StatusSeeOther, w.Code, "the form is answered with a GET redirect, whatever the link's status")
+	assert.Equal(t, "https://example.com/doc", w.Header().Get("Location"))
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	assert.Len(t, clicks, 1)
+
+	unlock("guess")
+	unlock("guess")
+	w = unlock("s3cret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "three wrong guesses lock the client out")
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "Too many attempts")
+	assert.Len(t, clicks, 1)
+
+	w = httptest.NewRecorder()
+	handler.Redirect(w, httptest.NewRequest(http.MethodPut, "/handbook", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestShortenerHandler_GetQRCode(t *testing.T) {
+	disabledAt := time.Now()
+	repo := new(MockURLRepository)
+	repo.On("FindByShortCode", mock.Anything, "abc123").Return(&domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}, nil)
+	repo.On("FindByShortCode", mock.Anything, "banned").Return(&domain.URL{ShortCode: "banned", LongURL: "https://example.com", CreatedAt: time.Now(), DisabledAt: &disabledAt}, nil)
+	repo.On("FindByShortCode", mock.Anything, "missing").Return(nil, domain.ErrURLNotFound)
+
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator))
+	handler := handlers.NewShortenerHandler(service, template.Must(template.New("test").Parse("test")))
+
+	tests := []struct {
+		code           string
+		expectedStatus int
+	}{
+		{code: "abc123", expectedStatus: http.StatusOK},
+		{code: "banned", expectedStatus: http.StatusGone},
+		{code: "missing", expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.GetQRCode(w, httptest.NewRequest(http.MethodGet, "/qrcode/"+tt.code, nil))
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
 func TestShortenerHandler_buildShortURL(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -345,8 +729,7 @@ func TestShortenerHandler_buildShortURL(t *testing.T) {
 			handler := handlers.NewShortenerHandler(service, tmpl)
 
 			gen.On("Generate").Return("abc123")
-			repo.On("Exists", mock.Anything, "abc123").Return(false, nil)
-			repo.On("Save", mock.Anything, mock.Anything).Return(nil)
+			repo.On("Create", mock.Anything, mock.Anything).Return(nil)
 
 			formData := url.Values{"url": []string{"https://example.com"}}
 			req := httptest.NewRequest(http.MethodPost, "/shorten", bytes.NewBufferString(formData.Encode()))
@@ -365,3 +748,124 @@ func TestShortenerHandler_buildShortURL(t *testing.T) {
 		})
 	}
 }
+
+func TestShortenerHandler_Manage(t *testing.T) {
+	existing := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/typo", CreatedAt: time.Now()}
+
+	tests := []struct {
+		name           string
+		method         string
+		form           url.Values
+		query          string
+		validToken     bool
+		principal      *domain.Principal
+		setupMocks     func(*MockURLRepository)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "show page",
+			method:         http.MethodGet,
+			validToken:     true,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "https://example.com/typo",
+		},
+		{
+			name:           "show page with wrong token",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "show page as viewer",
+			method:         http.MethodGet,
+			validToken:     true,
+			principal:      &domain.Principal{OwnerID: "alice", Role: domain.RoleViewer},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   "The viewer role may not manage links",
+		},
+		{
+			name:       "update destination",
+			method:     http.MethodPost,
+			form:       url.Values{"action": {"update"}, "url": {"https://example.com/fixed"}},
+			validToken: true,
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "Link updated",
+		},
+		{
+			name:           "update with invalid URL",
+			method:         http.MethodPost,
+			form:           url.Values{"action": {"update"}, "url": {"not-a-url"}},
+			validToken:     true,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "Invalid URL or redirect type",
+		},
+		{
+			name:       "delete",
+			method:     http.MethodPost,
+			form:       url.Values{"action": {"delete"}},
+			validToken: true,
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Delete", mock.Anything, "abc123", mock.Anything).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "has been deleted",
+		},
+		{
+			name:           "delete with wrong token",
+			method:         http.MethodPost,
+			form:           url.Values{"action": {"delete"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unknown action",
+			method:         http.MethodPost,
+			form:           url.Values{"action": {"rename"}},
+			validToken:     true,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	tmpl := template.Must(template.ParseGlob("../templates/*.html"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			repo.On("FindByShortCode", mock.Anything, "abc123").Return(existing, nil).Maybe()
+			if tt.setupMocks != nil {
+				tt.setupMocks(repo)
+			}
+			service := application.NewShortenerService(repo, new(MockShortCodeGenerator))
+			handler := handlers.NewShortenerHandler(service, tmpl)
+
+			token := "wrong"
+			if tt.validToken {
+				token = service.ManageToken(existing)
+			}
+
+			var req *http.Request
+			if tt.method == http.MethodGet {
+				req = httptest.NewRequest(http.MethodGet, "/manage/abc123?token="+url.QueryEscape(token), nil)
+			} else {
+				tt.form.Set("token", token)
+				req = httptest.NewRequest(http.MethodPost, "/manage/abc123", strings.NewReader(tt.form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.principal != nil {
+				req = req.WithContext(domain.ContextWithPrincipal(req.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+
+			handler.Manage(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+			if tt.expectedBody != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedBody)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
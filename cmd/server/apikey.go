@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newAPIKeyRepository keeps API keys in the same store as the URLs, so that
+// keys issued with the apikey command are seen by the server.
+func newAPIKeyRepository(urlRepo domain.URLRepository, redisClient *redis.Client) (domain.APIKeyRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *repository.SQLiteURLRepository:
+		return repository.NewSQLAPIKeyRepository(repo.DB(), sqlstore.DialectSQLite)
+	case *repository.PostgresURLRepository:
+		return repository.NewSQLAPIKeyRepository(repo.DB(), sqlstore.DialectPostgres)
+	case *repository.RedisURLRepository:
+		return repository.NewRedisAPIKeyRepository(redisClient), nil
+	default:
+		return repository.NewMemoryAPIKeyRepository(), nil
+	}
+}
+
+// newUserRepositories keeps accounts and sessions next to the URLs, like
+// newAPIKeyRepository.
+func newUserRepositories(urlRepo domain.URLRepository, redisClient *redis.Client) (domain.UserRepository, domain.SessionRepository, error) {
+	var dialect sqlstore.Dialect
+	var db *sql.DB
+	switch repo := urlRepo.(type) {
+	case *repository.SQLiteURLRepository:
+		db, dialect = repo.DB(), sqlstore.DialectSQLite
+	case *repository.PostgresURLRepository:
+		db, dialect = repo.DB(), sqlstore.DialectPostgres
+	case *repository.RedisURLRepository:
+		return repository.NewRedisUserRepository(redisClient), repository.NewRedisSessionRepository(redisClient), nil
+	default:
+		return repository.NewMemoryUserRepository(), repository.NewMemorySessionRepository(), nil
+	}
+
+	users, err := repository.NewSQLUserRepository(db, dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+	sessions, err := repository.NewSQLSessionRepository(db, dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+	return users, sessions, nil
+}
+
+const apiKeyUsage = `usage:
+  server apikey create [-owner id] [-role viewer|creator|admin] name
+  server apikey list
+  server apikey revoke id`
+
+// runAPIKeyCommand manages API keys from the command line, since there is no
+// admin API to issue them.
+func runAPIKeyCommand(ctx context.Context, keys *application.APIKeyService, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+
+	switch args[0] {
+	case "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		owner := fs.String("owner", "", "owner of the links created with the key; the key itself when empty")
+		role := fs.String("role", string(domain.RoleCreator), "what the key may do: viewer, creator or admin")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() != 1 {
+			return errors.New(apiKeyUsage)
+		}
+
+		key, secret, err := keys.Issue(ctx, fs.Arg(0), *owner, domain.Role(*role))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created API key %s (owner %s, role %s)\n", key.ID, key.OwnerID, key.Role)
+		fmt.Fprintf(out, "Secret, shown only once: %s\n", secret)
+		return nil
+	case "list":
+		list, err := keys.List(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tOWNER\tROLE\tCREATED\tREVOKED")
+		for _, key := range list {
+			revoked := "-"
+			if key.RevokedAt != nil {
+				revoked = key.RevokedAt.Format(time.RFC3339)
+			}
+			role := key.Role
+			if role == "" {
+				role = domain.RoleCreator
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.OwnerID, role, key.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		return w.Flush()
+	case "revoke":
+		if len(args) != 2 {
+			return errors.New(apiKeyUsage)
+		}
+		if err := keys.Revoke(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Revoked API key %s\n", args[1])
+		return nil
+	default:
+		return errors.New(apiKeyUsage)
+	}
+}
@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 	"url-shortener/api/handlers"
@@ -14,10 +17,15 @@ import (
 	"url-shortener/internal/application"
 	"url-shortener/internal/domain"
 	"url-shortener/internal/infrastructure/generator"
+	"url-shortener/internal/infrastructure/oidc"
 	"url-shortener/internal/infrastructure/ratelimiter"
 	"url-shortener/internal/infrastructure/repository"
+	"url-shortener/internal/infrastructure/scanner"
+	"url-shortener/internal/infrastructure/sqlstore"
 	"url-shortener/pkg/middleware"
 	"url-shortener/pkg/observability"
+
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -26,16 +34,155 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	urlRepo := repository.NewMemoryURLRepository(cfg.Storage.TTL)
-	codeGenerator := generator.NewRandomShortCodeGenerator()
-	shortenerService := application.NewShortenerService(urlRepo, codeGenerator)
+	var redisClient *redis.Client
+	if cfg.Storage.Driver == configs.StorageDriverRedis ||
+		((cfg.RateLimiter.Enabled || cfg.Redirect.PasswordAttempts > 0) && cfg.RateLimiter.Backend == configs.RateLimiterBackendRedis) {
+		redisClient, err = newRedisClient(cfg.Redis)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		defer redisClient.Close()
+	}
+
+	urlRepo, closeRepo, err := newURLRepository(cfg.Storage, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	defer closeRepo()
+
+	apiKeyRepo, err := newAPIKeyRepository(urlRepo, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize API key storage: %v", err)
+	}
+	apiKeyService := application.NewAPIKeyService(apiKeyRepo)
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if cfg.Storage.Driver == configs.StorageDriverMemory {
+			log.Fatalf("API keys cannot be managed with the %q storage driver", configs.StorageDriverMemory)
+		}
+		if err := runAPIKeyCommand(context.Background(), apiKeyService, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	userRepo, sessionRepo, err := newUserRepositories(urlRepo, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize account storage: %v", err)
+	}
+	userService := application.NewUserService(userRepo, sessionRepo,
+		application.WithSessionTTL(cfg.Auth.SessionTTL),
+	)
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		if cfg.Storage.Driver == configs.StorageDriverMemory {
+			log.Fatalf("Users cannot be managed with the %q storage driver", configs.StorageDriverMemory)
+		}
+		if err := runUserCommand(context.Background(), userService, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	codeGenerator, err := newShortCodeGenerator(cfg.Generator, urlRepo, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize short code generator: %v", err)
+	}
+	serviceOpts := []application.ServiceOption{
+		application.WithTombstoneTTL(cfg.Storage.TombstoneTTL),
+		application.WithDefaultRedirectStatus(cfg.Redirect.DefaultStatus),
+	}
+	if cfg.App.Deduplicate {
+		serviceOpts = append(serviceOpts, application.WithDeduplication())
+	}
+	if cfg.Generator.CaseInsensitive {
+		serviceOpts = append(serviceOpts, application.WithCaseInsensitiveCodes())
+	}
+	if cfg.App.Secret != "" {
+		serviceOpts = append(serviceOpts, application.WithManageSecret([]byte(cfg.App.Secret)))
+	} else {
+		log.Printf("Warning: APP_SECRET is not set; manage links will stop working after a restart")
+	}
+	destinationPolicy, err := newDestinationPolicy(cfg.Destination, cfg.App.BaseURL)
+	if err != nil {
+		log.Fatalf("Failed to load destination policy: %v", err)
+	}
+	serviceOpts = append(serviceOpts, application.WithDestinationPolicy(destinationPolicy))
+	lists := []reloader{destinationPolicy}
+
+	urlScanner, err := newURLScanner(cfg.Scanner)
+	if err != nil {
+		log.Fatalf("Failed to initialize URL scanner: %v", err)
+	}
+	if urlScanner != nil {
+		serviceOpts = append(serviceOpts, application.WithURLScanner(urlScanner, application.ScanPolicy{
+			OnRedirect: cfg.Scanner.OnRedirect,
+			FailClosed: cfg.Scanner.FailClosed,
+		}))
+		if r, ok := urlScanner.(reloader); ok {
+			lists = append(lists, r)
+		}
+		log.Printf("Scanning destinations with the %s scanner", cfg.Scanner.Backend)
+	}
+	if cfg.Redirect.PasswordAttempts > 0 {
+		limiterCfg := cfg.RateLimiter
+		limiterCfg.Limit, limiterCfg.Window = cfg.Redirect.PasswordAttempts, cfg.Redirect.PasswordWindow
+		passwordLimiter, err := newRateLimiter(limiterCfg, redisClient)
+		if err != nil {
+			log.Fatalf("Failed to initialize password attempt limiter: %v", err)
+		}
+		if closer, ok := passwordLimiter.(interface{ Close() }); ok {
+			defer closer.Close()
+		}
+		serviceOpts = append(serviceOpts, application.WithPasswordAttemptLimiter(passwordLimiter))
+	}
+	stopReload := reloadLists(cfg.Destination.ListReloadInterval, lists...)
+	defer stopReload()
+	shortenerService := application.NewShortenerService(urlRepo, codeGenerator, serviceOpts...)
 
 	tmpl, err := template.ParseGlob("api/templates/*.html")
 	if err != nil {
 		log.Fatalf("Failed to load templates: %v", err)
 	}
 
-	shortenerHandler := handlers.NewShortenerHandler(shortenerService, tmpl)
+	shortenerOpts := []handlers.ShortenerHandlerOption{
+		handlers.WithRedirectCacheMaxAge(cfg.Redirect.CacheMaxAge),
+	}
+	var linksOpts []handlers.LinksHandlerOption
+	if cfg.Analytics.Enabled {
+		clickRepo, err := newClickRepository(urlRepo)
+		if err != nil {
+			log.Fatalf("Failed to initialize click storage: %v", err)
+		}
+		analyticsService := application.NewAnalyticsService(clickRepo, application.AnalyticsConfig{
+			BufferSize:    cfg.Analytics.BufferSize,
+			BatchSize:     cfg.Analytics.BatchSize,
+			FlushInterval: cfg.Analytics.FlushInterval,
+		})
+		defer analyticsService.Close()
+
+		shortenerOpts = append(shortenerOpts, handlers.WithClickRecorder(analyticsService))
+		linksOpts = append(linksOpts, handlers.WithAnalytics(analyticsService))
+	}
+
+	shortenerHandler := handlers.NewShortenerHandler(shortenerService, tmpl, shortenerOpts...)
+	linksHandler := handlers.NewLinksHandler(shortenerService, linksOpts...)
+	var accountOpts []handlers.AccountHandlerOption
+	if strings.HasPrefix(cfg.App.BaseURL, "https://") {
+		accountOpts = append(accountOpts, handlers.WithSecureCookies())
+	}
+	if !cfg.Auth.AllowRegistration {
+		accountOpts = append(accountOpts, handlers.WithoutRegistration())
+	}
+	if !cfg.Auth.AllowPasswordLogin {
+		accountOpts = append(accountOpts, handlers.WithoutPasswordLogin())
+	}
+	if cfg.Auth.OIDC.Issuer != "" {
+		sso, err := newSSOService(cfg.Auth.OIDC, userService)
+		if err != nil {
+			log.Fatalf("Failed to initialize single sign-on: %v", err)
+		}
+		accountOpts = append(accountOpts, handlers.WithSSO(sso, cfg.Auth.OIDC.Name))
+		log.Printf("Offering single sign-on through %s", cfg.Auth.OIDC.Issuer)
+	}
+	accountHandler := handlers.NewAccountHandler(userService, shortenerService, tmpl, accountOpts...)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -47,6 +194,17 @@ func main() {
 	})
 	mux.HandleFunc("/shorten", shortenerHandler.CreateShortURL)
 	mux.HandleFunc("/qrcode/", shortenerHandler.GetQRCode)
+	mux.HandleFunc("/manage/", shortenerHandler.Manage)
+	mux.HandleFunc("/login", accountHandler.Login)
+	mux.HandleFunc("/register", accountHandler.Register)
+	mux.HandleFunc("/logout", accountHandler.Logout)
+	mux.HandleFunc("/dashboard", accountHandler.Dashboard)
+	mux.HandleFunc("/auth/oidc/login", accountHandler.SSOLogin)
+	mux.HandleFunc("/auth/oidc/callback", accountHandler.SSOCallback)
+	mux.HandleFunc("/api/v1/links", linksHandler.Links)
+	mux.HandleFunc("/api/v1/links/", linksHandler.Link)
+	mux.HandleFunc("/api/v1/admin/links", linksHandler.AdminLinks)
+	mux.HandleFunc("/api/v1/admin/links/", linksHandler.AdminLink)
 
 	cleanupTracing, err := observability.InitTracing(cfg)
 	if err != nil {
@@ -55,25 +213,37 @@ func main() {
 		defer cleanupTracing()
 	}
 
-	var rateLimiterInstance domain.RateLimiter
+	var handler http.Handler = mux
 	if cfg.RateLimiter.Enabled {
-		rateLimiterInstance = ratelimiter.NewMemoryRateLimiter(
-			cfg.RateLimiter.Limit,
-			cfg.RateLimiter.Window,
-		)
-		if memRL, ok := rateLimiterInstance.(*ratelimiter.MemoryRateLimiter); ok {
-			defer memRL.Close()
+		policies, closeLimiters, err := newRateLimitPolicies(cfg.RateLimiter, redisClient)
+		if err != nil {
+			log.Fatalf("Failed to initialize rate limiter: %v", err)
+		}
+		defer closeLimiters()
+		handler = middleware.PolicyRateLimitingMiddleware(policies,
+			middleware.WithAllowlist(cfg.RateLimiter.Allowlist),
+			middleware.WithAPIKeyIdentity(middleware.APIKeyIdentity),
+		)(handler)
+		for _, p := range cfg.RateLimiter.Policies {
+			if p.Limit == 0 {
+				log.Printf("Rate limit policy %q: unlimited", p.Name)
+				continue
+			}
+			log.Printf("Rate limit policy %q (%s, %s): %d requests per %v",
+				p.Name, cfg.RateLimiter.Backend, cfg.RateLimiter.Algorithm, p.Limit, p.Window)
 		}
-		log.Printf("Rate limiting enabled: %d requests per %v", cfg.RateLimiter.Limit, cfg.RateLimiter.Window)
-	}
-
-	var handler http.Handler = mux
-	if cfg.RateLimiter.Enabled && rateLimiterInstance != nil {
-		handler = middleware.RateLimitingMiddleware(rateLimiterInstance)(handler)
 	}
+	// Authentication runs first, so rate limits can key off the API key. An
+	// API key takes precedence over a login cookie sent along with it.
+	handler = middleware.APIKeyAuthMiddleware(apiKeyService,
+		middleware.WithRequiredRoutes(cfg.Auth.RequiredRoutes),
+	)(handler)
+	handler = middleware.SessionMiddleware(userService, handlers.SessionCookieName)(handler)
 	handler = middleware.RecoveryMiddleware(
-		middleware.TracingMiddleware(
-			middleware.LoggingMiddleware(handler),
+		middleware.ClientIPMiddleware(cfg.Server.TrustedProxies, cfg.Server.ProxyHeader)(
+			middleware.TracingMiddleware(
+				middleware.LoggingMiddleware(handler),
+			),
 		),
 	)
 
@@ -102,8 +272,288 @@ func main() {
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
+}
+
+func newRedisClient(cfg configs.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// newRateLimitPolicies builds a limiter for every configured policy. The
+// returned function closes the in-memory limiters' sweepers.
+func newRateLimitPolicies(cfg configs.RateLimiterConfig, redisClient *redis.Client) ([]middleware.RateLimitPolicy, func(), error) {
+	var closers []func()
+	closeAll := func() {
+		for _, c := range closers {
+			c()
+		}
+	}
+
+	policies := make([]middleware.RateLimitPolicy, 0, len(cfg.Policies))
+	for _, p := range cfg.Policies {
+		policy := middleware.RateLimitPolicy{Name: p.Name, Routes: p.Routes}
+		for _, identity := range p.Identities {
+			policy.Identities = append(policy.Identities, middleware.IdentityClass(identity))
+		}
+
+		if p.Limit > 0 {
+			limiterCfg := cfg
+			limiterCfg.Limit, limiterCfg.Window = p.Limit, p.Window
+			limiter, err := newRateLimiter(limiterCfg, redisClient)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			if closer, ok := limiter.(interface{ Close() }); ok {
+				closers = append(closers, closer.Close)
+			}
+			policy.Limiter = limiter
+		}
+		policies = append(policies, policy)
+	}
+	return policies, closeAll, nil
+}
+
+func newRateLimiter(cfg configs.RateLimiterConfig, redisClient *redis.Client) (domain.RateLimiter, error) {
+	switch cfg.Backend {
+	case configs.RateLimiterBackendRedis:
+		switch cfg.Algorithm {
+		case configs.RateLimiterAlgorithmTokenBucket:
+			return ratelimiter.NewRedisTokenBucketRateLimiter(redisClient, cfg.Limit, cfg.Window), nil
+		case configs.RateLimiterAlgorithmSlidingWindow:
+			return ratelimiter.NewRedisSlidingWindowRateLimiter(redisClient, cfg.Limit, cfg.Window), nil
+		default:
+			return ratelimiter.NewRedisRateLimiter(redisClient, cfg.Limit, cfg.Window), nil
+		}
+	case configs.RateLimiterBackendMemory:
+		switch cfg.Algorithm {
+		case configs.RateLimiterAlgorithmTokenBucket:
+			return ratelimiter.NewTokenBucketRateLimiter(cfg.Limit, cfg.Window), nil
+		case configs.RateLimiterAlgorithmSlidingWindow:
+			return ratelimiter.NewSlidingWindowRateLimiter(cfg.Limit, cfg.Window), nil
+		default:
+			return ratelimiter.NewMemoryRateLimiter(cfg.Limit, cfg.Window), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rate limiter backend %q", cfg.Backend)
+	}
+}
+
+func newURLRepository(cfg configs.StorageConfig, redisClient *redis.Client) (domain.URLRepository, func(), error) {
+	switch cfg.Driver {
+	case configs.StorageDriverMemory:
+		repo := repository.NewMemoryURLRepository(cfg.TTL)
+		return repo, repo.(*repository.MemoryURLRepository).Close, nil
+	case configs.StorageDriverSQLite:
+		repo, err := repository.NewSQLiteURLRepository(cfg.DSN, cfg.TTL)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("Using SQLite storage at %s", cfg.DSN)
+		return repo, func() {
+			if err := repo.(*repository.SQLiteURLRepository).Close(); err != nil {
+				log.Printf("Error closing SQLite storage: %v", err)
+			}
+		}, nil
+	case configs.StorageDriverPostgres:
+		repo, err := repository.NewPostgresURLRepository(cfg.DSN, cfg.TTL, repository.PoolConfig{
+			MaxOpenConns:    cfg.MaxOpenConns,
+			MaxIdleConns:    cfg.MaxIdleConns,
+			ConnMaxLifetime: cfg.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("Using PostgreSQL storage (max %d connections)", cfg.MaxOpenConns)
+		return repo, func() {
+			if err := repo.(*repository.PostgresURLRepository).Close(); err != nil {
+				log.Printf("Error closing PostgreSQL storage: %v", err)
+			}
+		}, nil
+	case configs.StorageDriverRedis:
+		log.Printf("Using Redis storage")
+		return repository.NewRedisURLRepository(redisClient, cfg.TTL), func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported storage driver %q", cfg.Driver)
+	}
+}
+
+func newShortCodeGenerator(cfg configs.GeneratorConfig, urlRepo domain.URLRepository, redisClient *redis.Client) (domain.ShortCodeGenerator, error) {
+	switch cfg.Strategy {
+	case configs.GeneratorStrategyWords:
+		return generator.NewWordShortCodeGenerator(generator.WordConfig{
+			CollisionThreshold: cfg.CollisionThreshold,
+		})
+	case configs.GeneratorStrategyReadable:
+		cfg.Alphabet = generator.ReadableAlphabet
+	case configs.GeneratorStrategySequential:
+		return newSequentialGenerator(cfg, urlRepo, redisClient)
+	}
+
+	return generator.NewRandomShortCodeGenerator(generator.RandomConfig{
+		Length:             cfg.Length,
+		MaxLength:          cfg.MaxLength,
+		Alphabet:           cfg.Alphabet,
+		CollisionThreshold: cfg.CollisionThreshold,
+	})
+}
+
+func newSequentialGenerator(cfg configs.GeneratorConfig, urlRepo domain.URLRepository, redisClient *redis.Client) (domain.ShortCodeGenerator, error) {
+	seq, err := newIDSequence(urlRepo, redisClient)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Key == "" {
+		log.Printf("Warning: GENERATOR_KEY is not set; sequential short codes reveal how many links exist")
+	}
+	return generator.NewSequentialShortCodeGenerator(seq, generator.SequentialConfig{
+		MinLength: cfg.Length,
+		Alphabet:  cfg.Alphabet,
+		Key:       []byte(cfg.Key),
+	})
+}
+
+// newIDSequence keeps the short code counter next to the URLs, so it
+// survives restarts whenever they do.
+func newIDSequence(urlRepo domain.URLRepository, redisClient *redis.Client) (domain.IDSequence, error) {
+	switch repo := urlRepo.(type) {
+	case *repository.SQLiteURLRepository:
+		return repository.NewSQLIDSequence(repo.DB(), sqlstore.DialectSQLite)
+	case *repository.PostgresURLRepository:
+		return repository.NewSQLIDSequence(repo.DB(), sqlstore.DialectPostgres)
+	case *repository.RedisURLRepository:
+		return repository.NewRedisIDSequence(redisClient), nil
+	default:
+		return repository.NewMemoryIDSequence(), nil
+	}
+}
+
+func newDestinationPolicy(cfg configs.DestinationConfig, baseURL string) (*application.DestinationPolicy, error) {
+	policyCfg := application.DestinationConfig{
+		Schemes:       cfg.Schemes,
+		RejectPrivate: cfg.RejectPrivate,
+		BlocklistFile: cfg.BlocklistFile,
+		AllowlistFile: cfg.AllowlistFile,
+	}
+	if cfg.RejectSelf {
+		u, err := url.Parse(baseURL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("APP_BASE_URL %q has no host", baseURL)
+		}
+		policyCfg.SelfHosts = []string{u.Host}
+	}
+	return application.NewDestinationPolicy(policyCfg)
+}
+
+func newSSOService(cfg configs.OIDCConfig, users *application.UserService) (*application.SSOService, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	provider, err := oidc.NewProvider(ctx, oidc.Config{
+		IssuerURL:    cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+		GroupsClaim:  cfg.GroupsClaim,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return application.NewSSOService(provider, users, application.SSOPolicy{
+		AllowedDomains: cfg.AllowedDomains,
+		AllowedGroups:  cfg.AllowedGroups,
+	}), nil
+}
+
+func newURLScanner(cfg configs.ScannerConfig) (domain.URLScanner, error) {
+	switch cfg.Backend {
+	case configs.ScannerBackendHashList:
+		s, err := scanner.NewHashListScanner(cfg.HashListFile)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case configs.ScannerBackendWebhook:
+		return scanner.NewWebhookScanner(scanner.WebhookConfig{
+			URL:     cfg.WebhookURL,
+			Token:   cfg.WebhookToken,
+			Timeout: cfg.Timeout,
+		}), nil
+	default:
+		return nil, nil
+	}
+}
+
+type reloader interface {
+	Reload() error
+}
+
+// reloadLists rereads the domain and hash lists on SIGHUP and, when interval
+// is set, periodically. A list that fails to load is logged and the previous
+// one stays in effect.
+func reloadLists(interval time.Duration, lists ...reloader) func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var ticker *time.Ticker
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-hup:
+				log.Printf("Reloading destination and scanner lists")
+			case <-tick:
+			case <-done:
+				return
+			}
+			for _, list := range lists {
+				if err := list.Reload(); err != nil {
+					log.Printf("Failed to reload list: %v", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(hup)
+		if ticker != nil {
+			ticker.Stop()
+		}
+		close(done)
+	}
+}
 
-	if memRepo, ok := urlRepo.(*repository.MemoryURLRepository); ok {
-		memRepo.Close()
+// newClickRepository keeps clicks in the same database as the URLs when the
+// storage driver is SQL, and in memory otherwise.
+func newClickRepository(urlRepo domain.URLRepository) (domain.ClickRepository, error) {
+	switch repo := urlRepo.(type) {
+	case *repository.SQLiteURLRepository:
+		return repository.NewSQLClickRepository(repo.DB(), sqlstore.DialectSQLite)
+	case *repository.PostgresURLRepository:
+		return repository.NewSQLClickRepository(repo.DB(), sqlstore.DialectPostgres)
+	default:
+		log.Printf("Keeping click analytics in memory")
+		return repository.NewMemoryClickRepository(), nil
 	}
 }
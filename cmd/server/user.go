@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+)
+
+const userUsage = `usage:
+  server user role email viewer|creator|admin`
+
+// runUserCommand manages accounts from the command line, so the first admin
+// can be appointed before anyone may use the admin API.
+func runUserCommand(ctx context.Context, users *application.UserService, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(userUsage)
+	}
+
+	switch args[0] {
+	case "role":
+		if len(args) != 3 {
+			return errors.New(userUsage)
+		}
+		user, err := users.SetRole(ctx, args[1], domain.Role(args[2]))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s is now %s\n", user.Email, user.Role)
+		return nil
+	default:
+		return errors.New(userUsage)
+	}
+}
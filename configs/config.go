@@ -1,16 +1,30 @@
 package configs
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
 	Server      ServerConfig
 	Storage     StorageConfig
+	Redis       RedisConfig
 	App         AppConfig
 	RateLimiter RateLimiterConfig
+	Analytics   AnalyticsConfig
+	Redirect    RedirectConfig
+	Generator   GeneratorConfig
+	Destination DestinationConfig
+	Scanner     ScannerConfig
+	Auth        AuthConfig
 }
 
 type ServerConfig struct {
@@ -18,46 +32,407 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+
+	// TrustedProxies are the networks of reverse proxies whose forwarding
+	// headers name the client. Without any, the peer address is the client.
+	TrustedProxies []netip.Prefix
+	// ProxyHeader is the one forwarding header the trusted proxies set.
+	// Others are ignored, since clients can send them through the proxy.
+	ProxyHeader string
 }
 
+const (
+	ProxyHeaderXForwardedFor = "X-Forwarded-For"
+	ProxyHeaderForwarded     = "Forwarded" // RFC 7239
+	ProxyHeaderXRealIP       = "X-Real-IP"
+)
+
+const (
+	StorageDriverMemory   = "memory"
+	StorageDriverSQLite   = "sqlite"
+	StorageDriverPostgres = "postgres"
+	StorageDriverRedis    = "redis"
+)
+
+const (
+	GeneratorStrategyRandom     = "random"
+	GeneratorStrategySequential = "sequential"
+	GeneratorStrategyReadable   = "readable" // random lowercase codes without look-alike characters
+	GeneratorStrategyWords      = "words"    // codes like brave-otter-42
+)
+
+const (
+	ScannerBackendNone     = "none"
+	ScannerBackendHashList = "hashlist"
+	ScannerBackendWebhook  = "webhook"
+)
+
+const (
+	RateLimiterBackendMemory = "memory"
+	RateLimiterBackendRedis  = "redis"
+)
+
+const (
+	RateLimiterAlgorithmFixedWindow   = "fixed_window" // cheapest, but allows 2x bursts across window boundaries
+	RateLimiterAlgorithmTokenBucket   = "token_bucket"
+	RateLimiterAlgorithmSlidingWindow = "sliding_window"
+)
+
 type StorageConfig struct {
-	TTL time.Duration // time to live for stored URLs
+	Driver string        // backend used for URLs: memory, sqlite, postgres or redis
+	DSN    string        // data source name passed to the driver
+	TTL    time.Duration // time to live for stored URLs
+
+	TombstoneTTL time.Duration // how long the short code of a deleted URL stays reserved
+
+	// connection pool settings, used by the postgres driver
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
 }
 
 type AppConfig struct {
 	BaseURL string
+	Secret  string // signs manage tokens; a random one is used when empty
+
+	Deduplicate bool // return the existing link when the same URL is shortened again
 }
 
+const (
+	RateLimitIdentityAnonymous   = "anonymous"
+	RateLimitIdentityAPIKey      = "api_key"
+	RateLimitIdentityAllowlisted = "allowlisted"
+)
+
 type RateLimiterConfig struct {
-	Enabled bool
-	Limit   int
-	Window  time.Duration
+	Enabled     bool
+	Backend     string // memory or redis
+	Algorithm   string // fixed_window, token_bucket or sliding_window
+	Limit       int    // requests per window for routes without a tighter policy
+	CreateLimit int    // requests per window for creating links
+	Window      time.Duration
+
+	Allowlist []netip.Prefix // clients in these networks are exempt by default
+
+	// Policies are tried in order and the first matching one applies. They
+	// are read from PolicyFile when it is set, and otherwise built from the
+	// limits above.
+	PolicyFile string
+	Policies   []RateLimitPolicyConfig
+}
+
+type RateLimitPolicyConfig struct {
+	Name       string
+	Routes     []string      // "POST /shorten" or "/api/v1/links/"; empty matches all routes
+	Identities []string      // anonymous, api_key or allowlisted; empty matches all clients
+	Limit      int           // zero means unlimited
+	Window     time.Duration // RATE_LIMITER_WINDOW when zero
+}
+
+type AnalyticsConfig struct {
+	Enabled       bool
+	BufferSize    int           // clicks queued before new ones are dropped
+	BatchSize     int           // clicks written per storage call
+	FlushInterval time.Duration // maximum delay before a queued click is written
+}
+
+type RedirectConfig struct {
+	DefaultStatus int           // used for links that do not choose one: 301, 302, 307 or 308
+	CacheMaxAge   time.Duration // max-age sent with permanent redirects
+
+	// PasswordAttempts is how many wrong passwords each client may try per
+	// PasswordWindow on each protected link; zero means unlimited. The
+	// limiter uses the RATE_LIMITER_ backend and algorithm, even when
+	// request rate limiting is disabled.
+	PasswordAttempts int
+	PasswordWindow   time.Duration
+}
+
+type GeneratorConfig struct {
+	Strategy           string // random or sequential
+	Length             int    // initial length of generated short codes
+	MaxLength          int    // generated codes never grow past this length
+	Alphabet           string // characters generated codes are drawn from
+	CollisionThreshold int    // consecutive collisions before codes grow by one character
+
+	// Key permutes sequential codes so they don't reveal how many links
+	// exist. It must never change once codes have been handed out.
+	Key string
+
+	// CaseInsensitive lets short codes be typed in any case. It defaults to
+	// on for the readable and words strategies, whose codes are lowercase.
+	CaseInsensitive bool
+}
+
+type DestinationConfig struct {
+	Schemes       []string // URL schemes that may be shortened
+	RejectSelf    bool     // refuse links to APP_BASE_URL's host
+	RejectPrivate bool     // refuse loopback, private and link-local destinations
+
+	// Domain list files hold one domain per line and are reread on SIGHUP
+	// and every ListReloadInterval when it is set.
+	BlocklistFile      string
+	AllowlistFile      string
+	ListReloadInterval time.Duration
+}
+
+type ScannerConfig struct {
+	Backend      string        // none, hashlist or webhook
+	HashListFile string        // used by the hashlist backend, reloaded with the destination lists
+	WebhookURL   string        // used by the webhook backend
+	WebhookToken string        // bearer token sent to the webhook
+	Timeout      time.Duration // per webhook request
+	OnRedirect   bool          // rescan on every redirect and warn visitors about flagged links
+	FailClosed   bool          // refuse new links while the scanner is failing
+}
+
+type AuthConfig struct {
+	// RequiredRoutes are rejected without a valid API key or a login, for
+	// example "POST /api/v1/links". Elsewhere both are optional and only
+	// claim ownership of the links created with them.
+	RequiredRoutes []string
+
+	SessionTTL time.Duration // how long a web UI login lasts
+
+	// AllowRegistration lets visitors create accounts themselves and
+	// AllowPasswordLogin lets them log in with a password. Both default to
+	// off when OIDC is configured, so the provider's domain and group
+	// restrictions cannot be sidestepped through a local account.
+	AllowRegistration  bool
+	AllowPasswordLogin bool
+
+	OIDC OIDCConfig
+}
+
+// OIDCConfig enables logging in to the web UI through an OpenID Connect
+// provider when Issuer is set.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // defaults to APP_BASE_URL + "/auth/oidc/callback"
+	Scopes       []string
+	Name         string // shown on the login button
+
+	// Users are let in if their verified email is in one of AllowedDomains
+	// or GroupsClaim lists one of AllowedGroups. With neither set, anyone
+	// the provider authenticates can log in.
+	AllowedDomains []string
+	AllowedGroups  []string
+	GroupsClaim    string
 }
 
 func Load() (*Config, error) {
+	localAccounts := getEnv("AUTH_OIDC_ISSUER", "") == ""
+
 	config := &Config{
 		Server: ServerConfig{
 			Port:         getEnv("SERVER_PORT", "8181"),
 			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
 			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
 			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			ProxyHeader:  getEnv("SERVER_PROXY_HEADER", ProxyHeaderXForwardedFor),
 		},
 		Storage: StorageConfig{
-			TTL: getDurationEnv("STORAGE_TTL", 24*time.Hour),
+			Driver: getEnv("STORAGE_DRIVER", StorageDriverMemory),
+			DSN:    getEnv("STORAGE_DSN", ""),
+			TTL:    getDurationEnv("STORAGE_TTL", 24*time.Hour),
+
+			TombstoneTTL: getDurationEnv("STORAGE_TOMBSTONE_TTL", 30*24*time.Hour),
+
+			MaxOpenConns:    getIntEnv("STORAGE_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    getIntEnv("STORAGE_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: getDurationEnv("STORAGE_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: getDurationEnv("STORAGE_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		},
+		Redis: RedisConfig{
+			Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
+			Password: getEnv("REDIS_PASSWORD", ""),
+			DB:       getIntEnv("REDIS_DB", 0),
 		},
 		App: AppConfig{
 			BaseURL: getEnv("APP_BASE_URL", "http://localhost:8181"),
+			Secret:  getEnv("APP_SECRET", ""),
+
+			Deduplicate: getBoolEnv("APP_DEDUPLICATE", false),
 		},
 		RateLimiter: RateLimiterConfig{
-			Enabled: getBoolEnv("RATE_LIMITER_ENABLED", true),
-			Limit:   getIntEnv("RATE_LIMITER_LIMIT", 100),
-			Window:  getDurationEnv("RATE_LIMITER_WINDOW", 1*time.Minute),
+			Enabled:     getBoolEnv("RATE_LIMITER_ENABLED", true),
+			Backend:     getEnv("RATE_LIMITER_BACKEND", RateLimiterBackendMemory),
+			Algorithm:   getEnv("RATE_LIMITER_ALGORITHM", RateLimiterAlgorithmFixedWindow),
+			Limit:       getIntEnv("RATE_LIMITER_LIMIT", 100),
+			CreateLimit: getIntEnv("RATE_LIMITER_CREATE_LIMIT", 10),
+			Window:      getDurationEnv("RATE_LIMITER_WINDOW", 1*time.Minute),
+			PolicyFile:  getEnv("RATE_LIMITER_POLICY_FILE", ""),
+		},
+		Analytics: AnalyticsConfig{
+			Enabled:       getBoolEnv("ANALYTICS_ENABLED", true),
+			BufferSize:    getIntEnv("ANALYTICS_BUFFER_SIZE", 10000),
+			BatchSize:     getIntEnv("ANALYTICS_BATCH_SIZE", 100),
+			FlushInterval: getDurationEnv("ANALYTICS_FLUSH_INTERVAL", 1*time.Second),
+		},
+		Redirect: RedirectConfig{
+			DefaultStatus: getIntEnv("REDIRECT_STATUS", 302),
+			CacheMaxAge:   getDurationEnv("REDIRECT_CACHE_MAX_AGE", 24*time.Hour),
+
+			PasswordAttempts: getIntEnv("REDIRECT_PASSWORD_ATTEMPTS", 10),
+			PasswordWindow:   getDurationEnv("REDIRECT_PASSWORD_WINDOW", 15*time.Minute),
+		},
+		Generator: GeneratorConfig{
+			Strategy:           getEnv("GENERATOR_STRATEGY", GeneratorStrategyRandom),
+			Length:             getIntEnv("GENERATOR_LENGTH", 6),
+			MaxLength:          getIntEnv("GENERATOR_MAX_LENGTH", 12),
+			Alphabet:           getEnv("GENERATOR_ALPHABET", "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"),
+			CollisionThreshold: getIntEnv("GENERATOR_COLLISION_THRESHOLD", 3),
+			Key:                getEnv("GENERATOR_KEY", ""),
+		},
+		Destination: DestinationConfig{
+			Schemes:       getListEnv("DESTINATION_SCHEMES", []string{"http", "https"}),
+			RejectSelf:    getBoolEnv("DESTINATION_REJECT_SELF", true),
+			RejectPrivate: getBoolEnv("DESTINATION_REJECT_PRIVATE", false),
+
+			BlocklistFile:      getEnv("DESTINATION_BLOCKLIST_FILE", ""),
+			AllowlistFile:      getEnv("DESTINATION_ALLOWLIST_FILE", ""),
+			ListReloadInterval: getDurationEnv("DESTINATION_LIST_RELOAD_INTERVAL", 0),
+		},
+		Scanner: ScannerConfig{
+			Backend:      getEnv("SCANNER_BACKEND", ScannerBackendNone),
+			HashListFile: getEnv("SCANNER_HASHLIST_FILE", ""),
+			WebhookURL:   getEnv("SCANNER_WEBHOOK_URL", ""),
+			WebhookToken: getEnv("SCANNER_WEBHOOK_TOKEN", ""),
+			Timeout:      getDurationEnv("SCANNER_TIMEOUT", 2*time.Second),
+			OnRedirect:   getBoolEnv("SCANNER_ON_REDIRECT", false),
+			FailClosed:   getBoolEnv("SCANNER_FAIL_CLOSED", false),
+		},
+		Auth: AuthConfig{
+			RequiredRoutes: getListEnv("AUTH_REQUIRED_ROUTES", nil),
+
+			SessionTTL:         getDurationEnv("AUTH_SESSION_TTL", 7*24*time.Hour),
+			AllowRegistration:  getBoolEnv("AUTH_ALLOW_REGISTRATION", localAccounts),
+			AllowPasswordLogin: getBoolEnv("AUTH_ALLOW_PASSWORD_LOGIN", localAccounts),
+
+			OIDC: OIDCConfig{
+				Issuer:         getEnv("AUTH_OIDC_ISSUER", ""),
+				ClientID:       getEnv("AUTH_OIDC_CLIENT_ID", ""),
+				ClientSecret:   getEnv("AUTH_OIDC_CLIENT_SECRET", ""),
+				RedirectURL:    getEnv("AUTH_OIDC_REDIRECT_URL", ""),
+				Scopes:         getListEnv("AUTH_OIDC_SCOPES", []string{"openid", "email", "profile"}),
+				Name:           getEnv("AUTH_OIDC_NAME", "single sign-on"),
+				AllowedDomains: getListEnv("AUTH_OIDC_ALLOWED_DOMAINS", nil),
+				AllowedGroups:  getListEnv("AUTH_OIDC_ALLOWED_GROUPS", nil),
+				GroupsClaim:    getEnv("AUTH_OIDC_GROUPS_CLAIM", "groups"),
+			},
 		},
 	}
 
+	if config.Storage.Driver == StorageDriverSQLite && config.Storage.DSN == "" {
+		config.Storage.DSN = "url-shortener.db"
+	}
+
+	switch config.Redirect.DefaultStatus {
+	case 301, 302, 307, 308:
+	default:
+		return nil, fmt.Errorf("REDIRECT_STATUS must be 301, 302, 307 or 308, got %d", config.Redirect.DefaultStatus)
+	}
+	if config.Redirect.PasswordAttempts < 0 {
+		return nil, fmt.Errorf("REDIRECT_PASSWORD_ATTEMPTS must not be negative, got %d", config.Redirect.PasswordAttempts)
+	}
+	if config.Redirect.PasswordWindow <= 0 {
+		return nil, fmt.Errorf("REDIRECT_PASSWORD_WINDOW must be positive, got %v", config.Redirect.PasswordWindow)
+	}
+
+	switch config.RateLimiter.Algorithm {
+	case RateLimiterAlgorithmFixedWindow, RateLimiterAlgorithmTokenBucket, RateLimiterAlgorithmSlidingWindow:
+	default:
+		return nil, fmt.Errorf("RATE_LIMITER_ALGORITHM must be one of %q, %q or %q, got %q",
+			RateLimiterAlgorithmFixedWindow, RateLimiterAlgorithmTokenBucket, RateLimiterAlgorithmSlidingWindow, config.RateLimiter.Algorithm)
+	}
+
+	var err error
+	if config.Server.TrustedProxies, err = getPrefixListEnv("SERVER_TRUSTED_PROXIES"); err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc([]string{ProxyHeaderXForwardedFor, ProxyHeaderForwarded, ProxyHeaderXRealIP}, func(h string) bool {
+		return strings.EqualFold(h, config.Server.ProxyHeader)
+	}) {
+		return nil, fmt.Errorf("SERVER_PROXY_HEADER must be one of %q, %q or %q, got %q",
+			ProxyHeaderXForwardedFor, ProxyHeaderForwarded, ProxyHeaderXRealIP, config.Server.ProxyHeader)
+	}
+	if config.RateLimiter.Allowlist, err = getPrefixListEnv("RATE_LIMITER_ALLOWLIST"); err != nil {
+		return nil, err
+	}
+	if err := config.RateLimiter.loadPolicies(); err != nil {
+		return nil, err
+	}
+	for _, route := range config.Auth.RequiredRoutes {
+		if err := validateRoute(route); err != nil {
+			return nil, fmt.Errorf("AUTH_REQUIRED_ROUTES: %w", err)
+		}
+	}
+	if config.Auth.SessionTTL <= 0 {
+		return nil, fmt.Errorf("AUTH_SESSION_TTL must be positive, got %v", config.Auth.SessionTTL)
+	}
+	if config.Auth.AllowRegistration && !config.Auth.AllowPasswordLogin {
+		return nil, errors.New("AUTH_ALLOW_REGISTRATION requires AUTH_ALLOW_PASSWORD_LOGIN")
+	}
+	if err := config.Auth.OIDC.validate(config.App.BaseURL); err != nil {
+		return nil, err
+	}
+
+	switch config.Generator.Strategy {
+	case GeneratorStrategyRandom, GeneratorStrategySequential:
+	case GeneratorStrategyReadable, GeneratorStrategyWords:
+		config.Generator.CaseInsensitive = true
+	default:
+		return nil, fmt.Errorf("GENERATOR_STRATEGY must be one of %q, %q, %q or %q, got %q",
+			GeneratorStrategyRandom, GeneratorStrategySequential, GeneratorStrategyReadable, GeneratorStrategyWords, config.Generator.Strategy)
+	}
+	config.Generator.CaseInsensitive = getBoolEnv("GENERATOR_CASE_INSENSITIVE", config.Generator.CaseInsensitive)
+
+	switch config.Scanner.Backend {
+	case ScannerBackendNone:
+	case ScannerBackendHashList:
+		if config.Scanner.HashListFile == "" {
+			return nil, fmt.Errorf("SCANNER_HASHLIST_FILE is required for the %q scanner", ScannerBackendHashList)
+		}
+	case ScannerBackendWebhook:
+		if config.Scanner.WebhookURL == "" {
+			return nil, fmt.Errorf("SCANNER_WEBHOOK_URL is required for the %q scanner", ScannerBackendWebhook)
+		}
+	default:
+		return nil, fmt.Errorf("SCANNER_BACKEND must be one of %q, %q or %q, got %q",
+			ScannerBackendNone, ScannerBackendHashList, ScannerBackendWebhook, config.Scanner.Backend)
+	}
+
 	return config, nil
 }
 
+func (c *OIDCConfig) validate(baseURL string) error {
+	if c.Issuer == "" {
+		return nil
+	}
+	if u, err := url.Parse(c.Issuer); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("AUTH_OIDC_ISSUER must be an http(s) URL, got %q", c.Issuer)
+	}
+	if c.ClientID == "" {
+		return errors.New("AUTH_OIDC_CLIENT_ID is required with AUTH_OIDC_ISSUER")
+	}
+	if !slices.Contains(c.Scopes, "openid") {
+		return fmt.Errorf("AUTH_OIDC_SCOPES must include %q", "openid")
+	}
+	if c.RedirectURL == "" {
+		c.RedirectURL = strings.TrimSuffix(baseURL, "/") + "/auth/oidc/callback"
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -65,6 +440,122 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// loadPolicies reads the policy file, or falls back to exempting allowlisted
+// clients, throttling link creation to CreateLimit and everything else to
+// Limit.
+func (c *RateLimiterConfig) loadPolicies() error {
+	if c.PolicyFile == "" {
+		c.Policies = []RateLimitPolicyConfig{
+			{Name: "allowlisted", Identities: []string{RateLimitIdentityAllowlisted}},
+			{Name: "create", Routes: []string{"POST /shorten", "POST /api/v1/links"}, Limit: c.CreateLimit, Window: c.Window},
+			{Name: "default", Limit: c.Limit, Window: c.Window},
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(c.PolicyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read rate limit policies: %w", err)
+	}
+
+	var entries []struct {
+		Name       string   `json:"name"`
+		Routes     []string `json:"routes"`
+		Identities []string `json:"identities"`
+		Limit      int      `json:"limit"`
+		Window     string   `json:"window"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to parse rate limit policies: %w", err)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	c.Policies = make([]RateLimitPolicyConfig, 0, len(entries))
+	for i, e := range entries {
+		policy := RateLimitPolicyConfig{
+			Name:       e.Name,
+			Routes:     e.Routes,
+			Identities: e.Identities,
+			Limit:      e.Limit,
+			Window:     c.Window,
+		}
+		if e.Window != "" {
+			if policy.Window, err = time.ParseDuration(e.Window); err != nil || policy.Window <= 0 {
+				return fmt.Errorf("rate limit policy %d: invalid window %q", i+1, e.Window)
+			}
+		}
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("rate limit policy %d: %w", i+1, err)
+		}
+		if seen[policy.Name] {
+			return fmt.Errorf("rate limit policy %d: name %q is used twice", i+1, policy.Name)
+		}
+		seen[policy.Name] = true
+		c.Policies = append(c.Policies, policy)
+	}
+	return nil
+}
+
+func (p RateLimitPolicyConfig) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if p.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	for _, route := range p.Routes {
+		if err := validateRoute(route); err != nil {
+			return err
+		}
+	}
+	for _, identity := range p.Identities {
+		switch identity {
+		case RateLimitIdentityAnonymous, RateLimitIdentityAPIKey, RateLimitIdentityAllowlisted:
+		default:
+			return fmt.Errorf("identity must be one of %q, %q or %q, got %q",
+				RateLimitIdentityAnonymous, RateLimitIdentityAPIKey, RateLimitIdentityAllowlisted, identity)
+		}
+	}
+	return nil
+}
+
+func validateRoute(route string) error {
+	path := route
+	if _, rest, ok := strings.Cut(route, " "); ok {
+		path = strings.TrimSpace(rest)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("route %q must be a path, optionally preceded by a method", route)
+	}
+	return nil
+}
+
+// getListEnv splits a comma-separated value, dropping empty items.
+func getListEnv(key string, defaultValue []string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
+func getPrefixListEnv(key string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, cidr := range getListEnv(key, nil) {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
 func getIntEnv(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -1,7 +1,9 @@
 package configs_test
 
 import (
+	"net/netip"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 	"url-shortener/configs"
@@ -326,3 +328,473 @@ func TestRateLimiterConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_Storage(t *testing.T) {
+	tests := []struct {
+		name           string
+		driver         string
+		dsn            string
+		expectedDriver string
+		expectedDSN    string
+	}{
+		{
+			name:           "defaults to memory",
+			expectedDriver: configs.StorageDriverMemory,
+			expectedDSN:    "",
+		},
+		{
+			name:           "sqlite with default path",
+			driver:         "sqlite",
+			expectedDriver: configs.StorageDriverSQLite,
+			expectedDSN:    "url-shortener.db",
+		},
+		{
+			name:           "sqlite with custom DSN",
+			driver:         "sqlite",
+			dsn:            "/var/lib/shortener/links.db",
+			expectedDriver: configs.StorageDriverSQLite,
+			expectedDSN:    "/var/lib/shortener/links.db",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STORAGE_DRIVER", tt.driver)
+			t.Setenv("STORAGE_DSN", tt.dsn)
+
+			cfg, err := configs.Load()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDriver, cfg.Storage.Driver)
+			assert.Equal(t, tt.expectedDSN, cfg.Storage.DSN)
+		})
+	}
+}
+
+func TestLoad_StoragePool(t *testing.T) {
+	t.Setenv("STORAGE_DRIVER", "postgres")
+	t.Setenv("STORAGE_DSN", "postgres://localhost/shortener")
+	t.Setenv("STORAGE_MAX_OPEN_CONNS", "50")
+	t.Setenv("STORAGE_CONN_MAX_LIFETIME", "1h")
+
+	cfg, err := configs.Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, configs.StorageDriverPostgres, cfg.Storage.Driver)
+	assert.Equal(t, "postgres://localhost/shortener", cfg.Storage.DSN)
+	assert.Equal(t, 50, cfg.Storage.MaxOpenConns)
+	assert.Equal(t, 5, cfg.Storage.MaxIdleConns)
+	assert.Equal(t, 1*time.Hour, cfg.Storage.ConnMaxLifetime)
+	assert.Equal(t, 5*time.Minute, cfg.Storage.ConnMaxIdleTime)
+}
+
+func TestLoad_Redis(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "redis.internal:6380")
+	t.Setenv("REDIS_DB", "2")
+	t.Setenv("RATE_LIMITER_BACKEND", "redis")
+
+	cfg, err := configs.Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "redis.internal:6380", cfg.Redis.Addr)
+	assert.Equal(t, 2, cfg.Redis.DB)
+	assert.Equal(t, configs.RateLimiterBackendRedis, cfg.RateLimiter.Backend)
+}
+
+func TestLoad_Analytics(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Analytics.Enabled)
+	assert.Equal(t, 10000, cfg.Analytics.BufferSize)
+	assert.Equal(t, 100, cfg.Analytics.BatchSize)
+	assert.Equal(t, 1*time.Second, cfg.Analytics.FlushInterval)
+
+	t.Setenv("ANALYTICS_ENABLED", "false")
+	t.Setenv("ANALYTICS_BATCH_SIZE", "500")
+	t.Setenv("ANALYTICS_FLUSH_INTERVAL", "5s")
+
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Analytics.Enabled)
+	assert.Equal(t, 500, cfg.Analytics.BatchSize)
+	assert.Equal(t, 5*time.Second, cfg.Analytics.FlushInterval)
+}
+
+func TestLoad_ManageSettings(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "", cfg.App.Secret)
+	assert.Equal(t, 30*24*time.Hour, cfg.Storage.TombstoneTTL)
+
+	t.Setenv("APP_SECRET", "s3cret")
+	t.Setenv("STORAGE_TOMBSTONE_TTL", "48h")
+
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.App.Secret)
+	assert.Equal(t, 48*time.Hour, cfg.Storage.TombstoneTTL)
+}
+
+func TestLoad_Redirect(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 302, cfg.Redirect.DefaultStatus)
+	assert.Equal(t, 24*time.Hour, cfg.Redirect.CacheMaxAge)
+
+	t.Setenv("REDIRECT_STATUS", "308")
+	t.Setenv("REDIRECT_CACHE_MAX_AGE", "1h")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 308, cfg.Redirect.DefaultStatus)
+	assert.Equal(t, time.Hour, cfg.Redirect.CacheMaxAge)
+
+	t.Setenv("REDIRECT_STATUS", "303")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_RedirectPasswords(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.Redirect.PasswordAttempts)
+	assert.Equal(t, 15*time.Minute, cfg.Redirect.PasswordWindow)
+
+	t.Setenv("REDIRECT_PASSWORD_ATTEMPTS", "0")
+	t.Setenv("REDIRECT_PASSWORD_WINDOW", "1h")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Zero(t, cfg.Redirect.PasswordAttempts)
+	assert.Equal(t, time.Hour, cfg.Redirect.PasswordWindow)
+
+	t.Setenv("REDIRECT_PASSWORD_ATTEMPTS", "-1")
+	_, err = configs.Load()
+	assert.Error(t, err)
+
+	t.Setenv("REDIRECT_PASSWORD_ATTEMPTS", "5")
+	t.Setenv("REDIRECT_PASSWORD_WINDOW", "0s")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_Generator(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 6, cfg.Generator.Length)
+	assert.Equal(t, 12, cfg.Generator.MaxLength)
+	assert.Len(t, cfg.Generator.Alphabet, 62)
+	assert.Equal(t, 3, cfg.Generator.CollisionThreshold)
+
+	t.Setenv("GENERATOR_LENGTH", "8")
+	t.Setenv("GENERATOR_MAX_LENGTH", "10")
+	t.Setenv("GENERATOR_ALPHABET", "abcdef0123")
+	t.Setenv("GENERATOR_COLLISION_THRESHOLD", "5")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 8, cfg.Generator.Length)
+	assert.Equal(t, 10, cfg.Generator.MaxLength)
+	assert.Equal(t, "abcdef0123", cfg.Generator.Alphabet)
+	assert.Equal(t, 5, cfg.Generator.CollisionThreshold)
+}
+
+func TestLoad_GeneratorStrategy(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, configs.GeneratorStrategyRandom, cfg.Generator.Strategy)
+	assert.Equal(t, "", cfg.Generator.Key)
+
+	t.Setenv("GENERATOR_STRATEGY", "sequential")
+	t.Setenv("GENERATOR_KEY", "k3y")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, configs.GeneratorStrategySequential, cfg.Generator.Strategy)
+	assert.Equal(t, "k3y", cfg.Generator.Key)
+
+	t.Setenv("GENERATOR_STRATEGY", "uuid")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_Deduplicate(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.App.Deduplicate)
+
+	t.Setenv("APP_DEDUPLICATE", "true")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.App.Deduplicate)
+}
+
+func TestLoad_GeneratorCaseInsensitive(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Generator.CaseInsensitive)
+
+	for _, strategy := range []string{configs.GeneratorStrategyWords, configs.GeneratorStrategyReadable} {
+		t.Setenv("GENERATOR_STRATEGY", strategy)
+		cfg, err = configs.Load()
+		assert.NoError(t, err)
+		assert.True(t, cfg.Generator.CaseInsensitive, strategy)
+	}
+
+	t.Setenv("GENERATOR_CASE_INSENSITIVE", "false")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Generator.CaseInsensitive)
+
+	t.Setenv("GENERATOR_STRATEGY", configs.GeneratorStrategyRandom)
+	t.Setenv("GENERATOR_CASE_INSENSITIVE", "true")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Generator.CaseInsensitive)
+}
+
+func TestLoad_Destination(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http", "https"}, cfg.Destination.Schemes)
+	assert.True(t, cfg.Destination.RejectSelf)
+	assert.False(t, cfg.Destination.RejectPrivate)
+	assert.Empty(t, cfg.Destination.BlocklistFile)
+	assert.Zero(t, cfg.Destination.ListReloadInterval)
+
+	t.Setenv("DESTINATION_SCHEMES", " https, mailto ,")
+	t.Setenv("DESTINATION_REJECT_SELF", "false")
+	t.Setenv("DESTINATION_REJECT_PRIVATE", "true")
+	t.Setenv("DESTINATION_BLOCKLIST_FILE", "/etc/shortener/blocklist.txt")
+	t.Setenv("DESTINATION_ALLOWLIST_FILE", "/etc/shortener/allowlist.txt")
+	t.Setenv("DESTINATION_LIST_RELOAD_INTERVAL", "5m")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https", "mailto"}, cfg.Destination.Schemes)
+	assert.False(t, cfg.Destination.RejectSelf)
+	assert.True(t, cfg.Destination.RejectPrivate)
+	assert.Equal(t, "/etc/shortener/blocklist.txt", cfg.Destination.BlocklistFile)
+	assert.Equal(t, "/etc/shortener/allowlist.txt", cfg.Destination.AllowlistFile)
+	assert.Equal(t, 5*time.Minute, cfg.Destination.ListReloadInterval)
+}
+
+func TestLoad_Scanner(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, configs.ScannerBackendNone, cfg.Scanner.Backend)
+	assert.Equal(t, 2*time.Second, cfg.Scanner.Timeout)
+	assert.False(t, cfg.Scanner.OnRedirect)
+	assert.False(t, cfg.Scanner.FailClosed)
+
+	t.Setenv("SCANNER_BACKEND", "webhook")
+	_, err = configs.Load()
+	assert.Error(t, err, "the webhook backend needs a URL")
+
+	t.Setenv("SCANNER_WEBHOOK_URL", "https://scanner.internal/check")
+	t.Setenv("SCANNER_WEBHOOK_TOKEN", "t0ken")
+	t.Setenv("SCANNER_TIMEOUT", "500ms")
+	t.Setenv("SCANNER_ON_REDIRECT", "true")
+	t.Setenv("SCANNER_FAIL_CLOSED", "true")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://scanner.internal/check", cfg.Scanner.WebhookURL)
+	assert.Equal(t, "t0ken", cfg.Scanner.WebhookToken)
+	assert.Equal(t, 500*time.Millisecond, cfg.Scanner.Timeout)
+	assert.True(t, cfg.Scanner.OnRedirect)
+	assert.True(t, cfg.Scanner.FailClosed)
+
+	t.Setenv("SCANNER_BACKEND", "hashlist")
+	_, err = configs.Load()
+	assert.Error(t, err, "the hashlist backend needs a file")
+
+	t.Setenv("SCANNER_BACKEND", "safebrowsing")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_RateLimiterAlgorithm(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, configs.RateLimiterAlgorithmFixedWindow, cfg.RateLimiter.Algorithm)
+
+	for _, algorithm := range []string{configs.RateLimiterAlgorithmTokenBucket, configs.RateLimiterAlgorithmSlidingWindow} {
+		t.Setenv("RATE_LIMITER_ALGORITHM", algorithm)
+		cfg, err = configs.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, algorithm, cfg.RateLimiter.Algorithm)
+	}
+
+	t.Setenv("RATE_LIMITER_ALGORITHM", "leaky_bucket")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_TrustedProxies(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Server.TrustedProxies)
+
+	t.Setenv("SERVER_TRUSTED_PROXIES", "10.1.2.3/8, 192.168.0.10/32, ::1/128")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.0.10/32"),
+		netip.MustParsePrefix("::1/128"),
+	}, cfg.Server.TrustedProxies)
+
+	t.Setenv("SERVER_TRUSTED_PROXIES", "192.168.0.10")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_ProxyHeader(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, configs.ProxyHeaderXForwardedFor, cfg.Server.ProxyHeader)
+
+	for _, header := range []string{"Forwarded", "x-real-ip", "X-Forwarded-For"} {
+		t.Setenv("SERVER_PROXY_HEADER", header)
+		cfg, err = configs.Load()
+		assert.NoError(t, err, header)
+		assert.Equal(t, header, cfg.Server.ProxyHeader)
+	}
+
+	t.Setenv("SERVER_PROXY_HEADER", "CF-Connecting-IP")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_AuthRequiredRoutes(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Auth.RequiredRoutes)
+
+	t.Setenv("AUTH_REQUIRED_ROUTES", "POST /api/v1/links, /api/v1/links/")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"POST /api/v1/links", "/api/v1/links/"}, cfg.Auth.RequiredRoutes)
+
+	t.Setenv("AUTH_REQUIRED_ROUTES", "POST api/v1/links")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_AuthSessions(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, cfg.Auth.SessionTTL)
+	assert.True(t, cfg.Auth.AllowRegistration)
+	assert.True(t, cfg.Auth.AllowPasswordLogin)
+
+	t.Setenv("AUTH_SESSION_TTL", "12h")
+	t.Setenv("AUTH_ALLOW_REGISTRATION", "false")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, 12*time.Hour, cfg.Auth.SessionTTL)
+	assert.False(t, cfg.Auth.AllowRegistration)
+
+	t.Setenv("AUTH_SESSION_TTL", "0s")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
+
+func TestLoad_LocalAccountsWithOIDC(t *testing.T) {
+	t.Setenv("APP_BASE_URL", "https://sho.rt/")
+	t.Setenv("AUTH_OIDC_ISSUER", "https://idp.example.com")
+	t.Setenv("AUTH_OIDC_CLIENT_ID", "shortener")
+	t.Setenv("AUTH_OIDC_ALLOWED_DOMAINS", "example.com")
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Auth.AllowRegistration, "registration would bypass the allowed domains")
+	assert.False(t, cfg.Auth.AllowPasswordLogin, "password login would bypass the allowed domains")
+
+	t.Setenv("AUTH_ALLOW_PASSWORD_LOGIN", "true")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Auth.AllowRegistration)
+	assert.True(t, cfg.Auth.AllowPasswordLogin)
+
+	t.Setenv("AUTH_ALLOW_REGISTRATION", "true")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Auth.AllowRegistration)
+
+	t.Setenv("AUTH_ALLOW_PASSWORD_LOGIN", "false")
+	_, err = configs.Load()
+	assert.ErrorContains(t, err, "AUTH_ALLOW_PASSWORD_LOGIN")
+}
+
+func TestLoad_OIDC(t *testing.T) {
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Auth.OIDC.Issuer, "single sign-on is off by default")
+
+	t.Setenv("APP_BASE_URL", "https://sho.rt/")
+	t.Setenv("AUTH_OIDC_ISSUER", "https://idp.example.com")
+	_, err = configs.Load()
+	assert.ErrorContains(t, err, "AUTH_OIDC_CLIENT_ID")
+
+	t.Setenv("AUTH_OIDC_CLIENT_ID", "shortener")
+	t.Setenv("AUTH_OIDC_ALLOWED_DOMAINS", "example.com, example.org")
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://sho.rt/auth/oidc/callback", cfg.Auth.OIDC.RedirectURL)
+	assert.Equal(t, []string{"openid", "email", "profile"}, cfg.Auth.OIDC.Scopes)
+	assert.Equal(t, []string{"example.com", "example.org"}, cfg.Auth.OIDC.AllowedDomains)
+	assert.Equal(t, "groups", cfg.Auth.OIDC.GroupsClaim)
+
+	t.Setenv("AUTH_OIDC_SCOPES", "email,profile")
+	_, err = configs.Load()
+	assert.ErrorContains(t, err, "openid")
+
+	t.Setenv("AUTH_OIDC_SCOPES", "openid")
+	t.Setenv("AUTH_OIDC_ISSUER", "idp.example.com")
+	_, err = configs.Load()
+	assert.ErrorContains(t, err, "AUTH_OIDC_ISSUER")
+}
+
+func TestLoad_RateLimitPolicies(t *testing.T) {
+	t.Setenv("RATE_LIMITER_LIMIT", "500")
+	t.Setenv("RATE_LIMITER_CREATE_LIMIT", "5")
+	t.Setenv("RATE_LIMITER_ALLOWLIST", "10.0.0.0/8, 2001:db8::1/64")
+
+	cfg, err := configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("2001:db8::/64")}, cfg.RateLimiter.Allowlist)
+	assert.Equal(t, []configs.RateLimitPolicyConfig{
+		{Name: "allowlisted", Identities: []string{"allowlisted"}},
+		{Name: "create", Routes: []string{"POST /shorten", "POST /api/v1/links"}, Limit: 5, Window: time.Minute},
+		{Name: "default", Limit: 500, Window: time.Minute},
+	}, cfg.RateLimiter.Policies)
+
+	path := filepath.Join(t.TempDir(), "policies.json")
+	os.WriteFile(path, []byte(`[
+		{"name": "create", "routes": ["POST /shorten"], "identities": ["anonymous"], "limit": 3, "window": "1h"},
+		{"name": "redirects", "routes": ["GET /"], "limit": 1000}
+	]`), 0o600)
+	t.Setenv("RATE_LIMITER_POLICY_FILE", path)
+
+	cfg, err = configs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []configs.RateLimitPolicyConfig{
+		{Name: "create", Routes: []string{"POST /shorten"}, Identities: []string{"anonymous"}, Limit: 3, Window: time.Hour},
+		{Name: "redirects", Routes: []string{"GET /"}, Limit: 1000, Window: time.Minute},
+	}, cfg.RateLimiter.Policies)
+
+	for name, policies := range map[string]string{
+		"malformed json":   `[{"name": "create"`,
+		"missing name":     `[{"limit": 1}]`,
+		"duplicate name":   `[{"name": "a"}, {"name": "a"}]`,
+		"bad route":        `[{"name": "a", "routes": ["POST shorten"]}]`,
+		"unknown identity": `[{"name": "a", "identities": ["admin"]}]`,
+		"bad window":       `[{"name": "a", "limit": 1, "window": "soon"}]`,
+		"negative limit":   `[{"name": "a", "limit": -1}]`,
+		"zero window":      `[{"name": "a", "limit": 1, "window": "0s"}]`,
+	} {
+		os.WriteFile(path, []byte(policies), 0o600)
+		_, err = configs.Load()
+		assert.Error(t, err, name)
+	}
+
+	t.Setenv("RATE_LIMITER_POLICY_FILE", "")
+	t.Setenv("RATE_LIMITER_ALLOWLIST", "10.0.0.0")
+	_, err = configs.Load()
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+package application
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"url-shortener/internal/domain"
+)
+
+const (
+	MinAliasLength = 3
+	MaxAliasLength = 32
+)
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// reservedAliases holds path segments used by the server's own routes, so a
+// vanity code can never shadow them.
+var reservedAliases = map[string]struct{}{
+	"admin":     {},
+	"api":       {},
+	"assets":    {},
+	"dashboard": {},
+	"health":    {},
+	"login":     {},
+	"logout":    {},
+	"manage":    {},
+	"qrcode":    {},
+	"register":  {},
+	"shorten":   {},
+	"static":    {},
+}
+
+func ValidateAlias(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength {
+		return fmt.Errorf("%w: must be between %d and %d characters", domain.ErrInvalidAlias, MinAliasLength, MaxAliasLength)
+	}
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: only letters, digits, '-' and '_' are allowed", domain.ErrInvalidAlias)
+	}
+	if IsReservedAlias(alias) {
+		return fmt.Errorf("%w: %q is reserved", domain.ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+func IsReservedAlias(alias string) bool {
+	_, reserved := reservedAliases[strings.ToLower(alias)]
+	return reserved
+}
@@ -0,0 +1,37 @@
+package application_test
+
+import (
+	"testing"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+	}{
+		{name: "valid alias", alias: "spring-sale", wantErr: false},
+		{name: "valid with underscore and digits", alias: "promo_2024", wantErr: false},
+		{name: "too short", alias: "ab", wantErr: true},
+		{name: "too long", alias: "abcdefghijklmnopqrstuvwxyz0123456", wantErr: true},
+		{name: "invalid characters", alias: "spring sale!", wantErr: true},
+		{name: "path separator", alias: "foo/bar", wantErr: true},
+		{name: "reserved word", alias: "shorten", wantErr: true},
+		{name: "reserved word any case", alias: "QRCode", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := application.ValidateAlias(tt.alias)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, domain.ErrInvalidAlias)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+const (
+	DefaultClickBufferSize    = 10000
+	DefaultClickBatchSize     = 100
+	DefaultClickFlushInterval = time.Second
+
+	// MaxStatsBuckets bounds the size of a single stats response.
+	MaxStatsBuckets = 1000
+
+	clickWriteTimeout = 5 * time.Second
+)
+
+type AnalyticsConfig struct {
+	BufferSize    int           // clicks queued before new ones are dropped
+	BatchSize     int           // clicks written per repository call
+	FlushInterval time.Duration // maximum time a click waits in a partial batch
+}
+
+// AnalyticsService queues clicks in memory and writes them in batches from a
+// background goroutine, so redirects never wait on click storage. When the
+// queue is full new clicks are dropped rather than slowing redirects down.
+type AnalyticsService struct {
+	repo          domain.ClickRepository
+	queue         chan *domain.Click
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Int64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewAnalyticsService(repo domain.ClickRepository, cfg AnalyticsConfig) *AnalyticsService {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultClickBufferSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultClickBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = DefaultClickFlushInterval
+	}
+
+	s := &AnalyticsService{
+		repo:          repo,
+		queue:         make(chan *domain.Click, cfg.BufferSize),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Record enqueues click without blocking.
+func (s *AnalyticsService) Record(click *domain.Click) {
+	select {
+	case s.queue <- click:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped reports how many clicks were discarded because the queue was full.
+func (s *AnalyticsService) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close flushes the clicks queued so far and stops the writer.
+func (s *AnalyticsService) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+func (s *AnalyticsService) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*domain.Click, 0, s.batchSize)
+	for {
+		select {
+		case click := <-s.queue:
+			batch = append(batch, click)
+			if len(batch) >= s.batchSize {
+				batch = s.flush(batch)
+			}
+		case <-ticker.C:
+			batch = s.flush(batch)
+		case <-s.stop:
+			for {
+				select {
+				case click := <-s.queue:
+					batch = append(batch, click)
+					if len(batch) >= s.batchSize {
+						batch = s.flush(batch)
+					}
+				default:
+					s.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *AnalyticsService) flush(batch []*domain.Click) []*domain.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clickWriteTimeout)
+	defer cancel()
+
+	if err := s.repo.SaveClicks(ctx, batch); err != nil {
+		log.Printf("Error saving %d clicks: %v", len(batch), err)
+	}
+	return batch[:0]
+}
+
+type StatsQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval time.Duration
+}
+
+// GetStats returns the link's total clicks and one bucket per interval
+// between From and To, including empty ones. From is aligned down to the
+// interval so buckets start on round boundaries. Only clicks since the link
+// was created count, so a reissued short code starts from zero.
+func (s *AnalyticsService) GetStats(ctx context.Context, url *domain.URL, q StatsQuery) (*domain.ClickStats, error) {
+	if q.Interval <= 0 {
+		return nil, fmt.Errorf("%w: interval must be positive", domain.ErrInvalidStatsQuery)
+	}
+	from := q.From.Truncate(q.Interval)
+	if !q.To.After(from) {
+		return nil, fmt.Errorf("%w: end must be after start", domain.ErrInvalidStatsQuery)
+	}
+	buckets := int((q.To.Sub(from) + q.Interval - 1) / q.Interval)
+	if buckets > MaxStatsBuckets {
+		return nil, fmt.Errorf("%w: range spans more than %d buckets", domain.ErrInvalidStatsQuery, MaxStatsBuckets)
+	}
+
+	stats, err := s.repo.Stats(ctx, url.ShortCode, url.CreatedAt, from, q.To, q.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load click stats: %w", err)
+	}
+
+	counts := make(map[int64]int64, len(stats.Buckets))
+	for _, b := range stats.Buckets {
+		counts[b.Start.UnixNano()] = b.Count
+	}
+	stats.Buckets = make([]domain.ClickBucket, buckets)
+	for i := range stats.Buckets {
+		start := from.Add(time.Duration(i) * q.Interval)
+		stats.Buckets[i] = domain.ClickBucket{Start: start, Count: counts[start.UnixNano()]}
+	}
+
+	return stats, nil
+}
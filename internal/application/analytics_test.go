@@ -0,0 +1,195 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockClickRepository struct {
+	mock.Mock
+}
+
+func (m *MockClickRepository) SaveClicks(ctx context.Context, clicks []*domain.Click) error {
+	args := m.Called(ctx, clicks)
+	return args.Error(0)
+}
+
+func (m *MockClickRepository) Stats(ctx context.Context, shortCode string, since, from, to time.Time, interval time.Duration) (*domain.ClickStats, error) {
+	args := m.Called(ctx, shortCode, since, from, to, interval)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.ClickStats), args.Error(1)
+}
+
+func TestAnalyticsService_RecordFlushesOnClose(t *testing.T) {
+	repo := repository.NewMemoryClickRepository()
+	service := application.NewAnalyticsService(repo, application.AnalyticsConfig{
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+	})
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		service.Record(&domain.Click{ShortCode: "abc123", Timestamp: now})
+	}
+	service.Close()
+	service.Close() // idempotent
+
+	stats, err := repo.Stats(context.Background(), "abc123", time.Time{}, now.Add(-time.Minute), now.Add(time.Minute), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stats.Total)
+	assert.Zero(t, service.Dropped())
+}
+
+func TestAnalyticsService_FlushesOnInterval(t *testing.T) {
+	repo := repository.NewMemoryClickRepository()
+	service := application.NewAnalyticsService(repo, application.AnalyticsConfig{
+		BatchSize:     100,
+		FlushInterval: 10 * time.Millisecond,
+	})
+	defer service.Close()
+
+	now := time.Now()
+	service.Record(&domain.Click{ShortCode: "abc123", Timestamp: now})
+
+	assert.Eventually(t, func() bool {
+		stats, err := repo.Stats(context.Background(), "abc123", time.Time{}, now.Add(-time.Minute), now.Add(time.Minute), time.Hour)
+		return err == nil && stats.Total == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestAnalyticsService_DropsWhenQueueIsFull(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	repo := new(MockClickRepository)
+	repo.On("SaveClicks", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+		}).
+		Return(nil)
+
+	service := application.NewAnalyticsService(repo, application.AnalyticsConfig{
+		BufferSize: 2,
+		BatchSize:  1,
+	})
+
+	// the first click is taken by the writer, which then blocks in SaveClicks
+	service.Record(&domain.Click{ShortCode: "abc123"})
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("writer did not pick up the first click")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			service.Record(&domain.Click{ShortCode: "abc123"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked on a slow repository")
+	}
+	assert.Equal(t, int64(3), service.Dropped())
+
+	close(release)
+	service.Close()
+}
+
+func TestAnalyticsService_GetStats(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	link := &domain.URL{ShortCode: "abc123", CreatedAt: from.Add(-time.Hour)}
+
+	tests := []struct {
+		name          string
+		query         application.StatsQuery
+		expectedFrom  time.Time
+		expectedCount int
+		expectedError error
+	}{
+		{
+			name:          "daily buckets",
+			query:         application.StatsQuery{From: from, To: from.Add(72 * time.Hour), Interval: 24 * time.Hour},
+			expectedFrom:  from,
+			expectedCount: 3,
+		},
+		{
+			name:          "start aligned to the interval",
+			query:         application.StatsQuery{From: from.Add(90 * time.Minute), To: from.Add(3 * time.Hour), Interval: time.Hour},
+			expectedFrom:  from.Add(time.Hour),
+			expectedCount: 2,
+		},
+		{
+			name:          "partial last bucket",
+			query:         application.StatsQuery{From: from, To: from.Add(150 * time.Minute), Interval: time.Hour},
+			expectedFrom:  from,
+			expectedCount: 3,
+		},
+		{
+			name:          "end before start",
+			query:         application.StatsQuery{From: from, To: from.Add(-time.Hour), Interval: time.Hour},
+			expectedError: domain.ErrInvalidStatsQuery,
+		},
+		{
+			name:          "too many buckets",
+			query:         application.StatsQuery{From: from, To: from.Add(2000 * time.Hour), Interval: time.Hour},
+			expectedError: domain.ErrInvalidStatsQuery,
+		},
+		{
+			name:          "missing interval",
+			query:         application.StatsQuery{From: from, To: from.Add(time.Hour)},
+			expectedError: domain.ErrInvalidStatsQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockClickRepository)
+			if tt.expectedError == nil {
+				repo.On("Stats", mock.Anything, "abc123", link.CreatedAt, tt.expectedFrom, tt.query.To, tt.query.Interval).Return(&domain.ClickStats{
+					ShortCode: "abc123",
+					Total:     7,
+					Buckets:   []domain.ClickBucket{{Start: tt.expectedFrom, Count: 4}},
+				}, nil)
+			}
+			service := application.NewAnalyticsService(repo, application.AnalyticsConfig{})
+			defer service.Close()
+
+			stats, err := service.GetStats(context.Background(), link, tt.query)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, stats)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), stats.Total)
+			require.Len(t, stats.Buckets, tt.expectedCount)
+			assert.Equal(t, int64(4), stats.Buckets[0].Count)
+			for i, b := range stats.Buckets {
+				assert.True(t, tt.expectedFrom.Add(time.Duration(i)*tt.query.Interval).Equal(b.Start))
+				if i > 0 {
+					assert.Zero(t, b.Count)
+				}
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// apiKeyPrefix makes leaked keys easy to recognise in logs and secret
+// scanners.
+const apiKeyPrefix = "usk_"
+
+type APIKeyService struct {
+	repo domain.APIKeyRepository
+}
+
+func NewAPIKeyService(repo domain.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// Issue creates a key with the given role whose links belong to ownerID, or
+// to the key itself when ownerID is empty. The returned secret is not stored
+// anywhere and cannot be shown again.
+func (s *APIKeyService) Issue(ctx context.Context, name, ownerID string, role domain.Role) (*domain.APIKey, string, error) {
+	if _, err := domain.ParseRole(string(role)); err != nil {
+		return nil, "", err
+	}
+
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	secret = apiKeyPrefix + secret
+
+	if ownerID == "" {
+		ownerID = id
+	}
+	key := &domain.APIKey{
+		ID:        id,
+		Name:      name,
+		OwnerID:   ownerID,
+		Role:      role,
+		Hash:      HashAPIKey(secret),
+		CreatedAt: time.Now(),
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to save api key: %w", err)
+	}
+	return key, secret, nil
+}
+
+// Authenticate returns the live key for secret, or ErrUnauthorized.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	key, err := s.repo.FindByHash(ctx, HashAPIKey(secret))
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return nil, domain.ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return nil, domain.ErrUnauthorized
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]*domain.APIKey, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id string) error {
+	if err := s.repo.Revoke(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, domain.ErrAPIKeyNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+// HashAPIKey derives the stored form of a key. Unlike passwords, keys carry
+// 256 random bits, so a fast hash is safe and allows looking keys up by it.
+func HashAPIKey(secret string) string {
+	return hashSecret(secret)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
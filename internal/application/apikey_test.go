@@ -0,0 +1,52 @@
+package application_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryAPIKeyRepository()
+	service := application.NewAPIKeyService(repo)
+
+	key, secret, err := service.Issue(ctx, "ci", "", domain.RoleCreator)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "usk_"))
+	assert.Equal(t, key.ID, key.OwnerID, "a key without an owner owns its links")
+	assert.Equal(t, application.HashAPIKey(secret), key.Hash)
+	assert.NotContains(t, key.Hash, secret)
+
+	shared, _, err := service.Issue(ctx, "deploy", "team-a", domain.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", shared.OwnerID)
+	assert.Equal(t, domain.RoleAdmin, shared.Role)
+
+	_, _, err = service.Issue(ctx, "root", "", "superuser")
+	assert.ErrorIs(t, err, domain.ErrInvalidRole)
+
+	got, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, got.ID)
+
+	for _, bad := range []string{"", "usk_unknown", strings.TrimPrefix(secret, "usk_")} {
+		_, err := service.Authenticate(ctx, bad)
+		assert.ErrorIs(t, err, domain.ErrUnauthorized, bad)
+	}
+
+	keys, err := service.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	require.NoError(t, service.Revoke(ctx, key.ID))
+	_, err = service.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+	assert.ErrorIs(t, service.Revoke(ctx, "missing"), domain.ErrAPIKeyNotFound)
+}
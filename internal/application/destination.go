@@ -0,0 +1,289 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"url-shortener/internal/domain"
+)
+
+var defaultSchemes = []string{"http", "https"}
+
+// cgnatPrefix is the shared address space carriers use behind NAT; netip does
+// not count it as private, but it is just as unreachable from outside.
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// Resolver looks up the addresses of a host name; *net.Resolver satisfies it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+type DestinationConfig struct {
+	Schemes   []string // allowed schemes; http and https when empty
+	SelfHosts []string // host names serving the shortener, whose links would loop
+
+	// RejectPrivate refuses loopback, private and link-local destinations,
+	// including host names that resolve to them.
+	RejectPrivate bool
+	Resolver      Resolver // used by RejectPrivate; net.DefaultResolver when nil
+
+	// Domain lists hold one domain per line and also match its subdomains.
+	// When an allowlist is set, only the domains on it are accepted; the
+	// blocklist wins over the allowlist.
+	BlocklistFile string
+	AllowlistFile string
+}
+
+// DestinationPolicy decides which long URLs may be shortened. The domain
+// lists can be reloaded while the policy is in use.
+type DestinationPolicy struct {
+	schemes       map[string]bool
+	selfHosts     map[string]bool
+	rejectPrivate bool
+	resolver      Resolver
+	blocklistFile string
+	allowlistFile string
+	lists         atomic.Pointer[domainLists]
+}
+
+type domainLists struct {
+	block map[string]bool
+	allow map[string]bool // nil when every domain is allowed
+}
+
+func NewDestinationPolicy(cfg DestinationConfig) (*DestinationPolicy, error) {
+	if len(cfg.Schemes) == 0 {
+		cfg.Schemes = defaultSchemes
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = net.DefaultResolver
+	}
+
+	p := &DestinationPolicy{
+		schemes:       make(map[string]bool, len(cfg.Schemes)),
+		selfHosts:     make(map[string]bool, len(cfg.SelfHosts)),
+		rejectPrivate: cfg.RejectPrivate,
+		resolver:      cfg.Resolver,
+		blocklistFile: cfg.BlocklistFile,
+		allowlistFile: cfg.AllowlistFile,
+	}
+	for _, scheme := range cfg.Schemes {
+		p.schemes[strings.ToLower(scheme)] = true
+	}
+	for _, host := range cfg.SelfHosts {
+		p.selfHosts[normalizeHost(host)] = true
+	}
+
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload rereads the domain lists. On error the previous lists stay in effect.
+func (p *DestinationPolicy) Reload() error {
+	lists := &domainLists{}
+
+	var err error
+	if lists.block, err = loadDomainList(p.blocklistFile); err != nil {
+		return err
+	}
+	if lists.allow, err = loadDomainList(p.allowlistFile); err != nil {
+		return err
+	}
+
+	p.lists.Store(lists)
+	return nil
+}
+
+// Check returns an error wrapping domain.ErrDisallowedURL when rawURL may
+// not be shortened, and domain.ErrInvalidURL when it can't be parsed.
+func (p *DestinationPolicy) Check(ctx context.Context, rawURL string) error {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return domain.ErrInvalidURL
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if !p.schemes[scheme] {
+		return fmt.Errorf("%w: scheme %q is not allowed", domain.ErrDisallowedURL, scheme)
+	}
+
+	host := normalizeHost(u.Hostname())
+	if host == "" {
+		if u.Opaque != "" {
+			// mailto: and similar have no host to check.
+			return nil
+		}
+		return domain.ErrInvalidURL
+	}
+
+	if p.selfHosts[host] {
+		return fmt.Errorf("%w: links to this shortener are not allowed", domain.ErrDisallowedURL)
+	}
+
+	lists := p.lists.Load()
+	if matchDomain(lists.block, host) {
+		return fmt.Errorf("%w: %s is blocked", domain.ErrDisallowedURL, host)
+	}
+	if lists.allow != nil && !matchDomain(lists.allow, host) {
+		return fmt.Errorf("%w: %s is not on the allowlist", domain.ErrDisallowedURL, host)
+	}
+
+	if p.rejectPrivate {
+		return p.checkPublic(ctx, host)
+	}
+	return nil
+}
+
+func (p *DestinationPolicy) checkPublic(ctx context.Context, host string) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s is not a public address", domain.ErrDisallowedURL, host)
+	}
+
+	if addr, ok := parseHostIP(host); ok {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s is not a public address", domain.ErrDisallowedURL, host)
+		}
+		return nil
+	}
+
+	// A name that doesn't resolve yet can't be checked; it is let through
+	// rather than refusing links to domains during a DNS outage.
+	addrs, err := p.resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s resolves to a non-public address", domain.ErrDisallowedURL, host)
+		}
+	}
+	return nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !(addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		cgnatPrefix.Contains(addr))
+}
+
+// parseHostIP recognizes the IPv4 spellings browsers accept besides dotted
+// decimal, such as 2130706433, 0x7f.1 or 0177.0.0.1, so they can't be used to
+// sneak a private address past the check.
+func parseHostIP(host string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr, true
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return netip.Addr{}, false
+	}
+	var ip uint64
+	for i, part := range parts {
+		n, ok := parseIPv4Part(part)
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if i < len(parts)-1 {
+			if n > 0xff {
+				return netip.Addr{}, false
+			}
+			ip = ip<<8 | n
+			continue
+		}
+		// The last part fills all remaining bytes.
+		remaining := 4 - i
+		if n >= 1<<(8*remaining) {
+			return netip.Addr{}, false
+		}
+		ip = ip<<(8*remaining) | n
+	}
+	return netip.AddrFrom4([4]byte{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)}), true
+}
+
+func parseIPv4Part(part string) (uint64, bool) {
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X"):
+		base, part = 16, part[2:]
+		if part == "" {
+			return 0, true
+		}
+	case len(part) > 1 && part[0] == '0':
+		base, part = 8, part[1:]
+	}
+	if part == "" || strings.ContainsAny(part, "+-_") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(part, base, 32)
+	return n, err == nil
+}
+
+// matchDomain reports whether host or one of its parent domains is listed.
+func matchDomain(list map[string]bool, host string) bool {
+	for {
+		if list[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// loadDomainList reads one domain per line, ignoring blank lines and
+// comments starting with #. An empty path means no list.
+func loadDomainList(path string) (map[string]bool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open domain list: %w", err)
+	}
+	defer f.Close()
+
+	list := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		entry := scanner.Text()
+		if i := strings.IndexByte(entry, '#'); i >= 0 {
+			entry = entry[:i]
+		}
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		entry = strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")), ".")
+		if entry == "" || strings.ContainsAny(entry, "/:[] \t") {
+			return nil, fmt.Errorf("invalid entry on line %d of %s", line, path)
+		}
+		list[entry] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain list: %w", err)
+	}
+
+	return list, nil
+}
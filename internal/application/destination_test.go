@@ -0,0 +1,190 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticResolver map[string][]netip.Addr
+
+func (r staticResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func writeList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func newPolicy(t *testing.T, cfg application.DestinationConfig) *application.DestinationPolicy {
+	t.Helper()
+	policy, err := application.NewDestinationPolicy(cfg)
+	require.NoError(t, err)
+	return policy
+}
+
+func assertAllowed(t *testing.T, policy *application.DestinationPolicy, allowed, rejected []string) {
+	t.Helper()
+	ctx := context.Background()
+	for _, u := range allowed {
+		assert.NoError(t, policy.Check(ctx, u), u)
+	}
+	for _, u := range rejected {
+		assert.ErrorIs(t, policy.Check(ctx, u), domain.ErrDisallowedURL, u)
+	}
+}
+
+func TestDestinationPolicy_Schemes(t *testing.T) {
+	assertAllowed(t, newPolicy(t, application.DestinationConfig{}),
+		[]string{"http://example.com", "HTTPS://example.com/path"},
+		[]string{"javascript:alert(1)", "data:text/html,hi", "file:///etc/passwd", "ftp://example.com/file"},
+	)
+
+	assertAllowed(t, newPolicy(t, application.DestinationConfig{Schemes: []string{"https", "mailto"}}),
+		[]string{"https://example.com", "mailto:support@example.com"},
+		[]string{"http://example.com"},
+	)
+
+	assert.ErrorIs(t, newPolicy(t, application.DestinationConfig{}).Check(context.Background(), "not a url"), domain.ErrInvalidURL)
+	assert.ErrorIs(t, newPolicy(t, application.DestinationConfig{}).Check(context.Background(), "http:///path"), domain.ErrInvalidURL)
+}
+
+func TestDestinationPolicy_SelfHosts(t *testing.T) {
+	policy := newPolicy(t, application.DestinationConfig{SelfHosts: []string{"sho.rt:8181"}})
+
+	assertAllowed(t, policy,
+		[]string{"https://example.com/sho.rt", "https://www.sho.rt.example/"},
+		[]string{"https://sho.rt/abc", "http://SHO.RT:443/abc", "https://sho.rt./abc"},
+	)
+}
+
+func TestDestinationPolicy_RejectPrivate(t *testing.T) {
+	policy := newPolicy(t, application.DestinationConfig{
+		RejectPrivate: true,
+		Resolver: staticResolver{
+			"intranet.example": {netip.MustParseAddr("10.0.0.5")},
+			"mixed.example":    {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("127.0.0.1")},
+			"example.com":      {netip.MustParseAddr("93.184.216.34")},
+		},
+	})
+
+	assertAllowed(t, policy,
+		[]string{
+			"https://example.com",
+			"https://93.184.216.34/",
+			"https://[2606:2800:220:1::]/",
+			"https://unresolvable.example/",
+		},
+		[]string{
+			"http://127.0.0.1/",
+			"http://10.1.2.3/",
+			"http://192.168.0.1/",
+			"http://172.16.0.1/",
+			"http://169.254.169.254/latest/meta-data/",
+			"http://100.64.0.1/",
+			"http://0.0.0.0/",
+			"http://[::1]/",
+			"http://[fd00::1]/",
+			"http://[::ffff:127.0.0.1]/",
+			"http://2130706433/",
+			"http://0x7f.1/",
+			"http://0177.0.0.1/",
+			"http://127.1/",
+			"http://localhost:8080/",
+			"http://app.localhost/",
+			"http://intranet.example/",
+			"http://mixed.example/",
+		},
+	)
+
+	assertAllowed(t, newPolicy(t, application.DestinationConfig{}),
+		[]string{"http://127.0.0.1/", "http://localhost/"},
+		nil,
+	)
+}
+
+func TestDestinationPolicy_DomainLists(t *testing.T) {
+	blocklist := writeList(t, "# known bad\nevil.com\n*.phish.example # and everything below\n\n")
+	allowlist := writeList(t, "example.com\nphish.example\n")
+
+	policy := newPolicy(t, application.DestinationConfig{BlocklistFile: blocklist, AllowlistFile: allowlist})
+
+	assertAllowed(t, policy,
+		[]string{"https://example.com", "https://docs.example.com/a", "https://EXAMPLE.com."},
+		[]string{
+			"https://evil.com",
+			"https://www.evil.com",
+			"https://login.phish.example",
+			"https://phish.example",
+			"https://other.org",
+			"https://notexample.com",
+		},
+	)
+}
+
+func TestDestinationPolicy_Reload(t *testing.T) {
+	blocklist := writeList(t, "evil.com\n")
+	policy := newPolicy(t, application.DestinationConfig{BlocklistFile: blocklist})
+
+	assertAllowed(t, policy, []string{"https://other.org"}, []string{"https://evil.com"})
+
+	require.NoError(t, os.WriteFile(blocklist, []byte("other.org\n"), 0o600))
+	require.NoError(t, policy.Reload())
+	assertAllowed(t, policy, []string{"https://evil.com"}, []string{"https://other.org"})
+
+	require.NoError(t, os.WriteFile(blocklist, []byte("https://bad entry/\n"), 0o600))
+	assert.Error(t, policy.Reload())
+	assertAllowed(t, policy, []string{"https://evil.com"}, []string{"https://other.org"})
+
+	require.NoError(t, os.Remove(blocklist))
+	assert.Error(t, policy.Reload())
+	assertAllowed(t, policy, []string{"https://evil.com"}, []string{"https://other.org"})
+}
+
+func TestNewDestinationPolicy_MissingList(t *testing.T) {
+	_, err := application.NewDestinationPolicy(application.DestinationConfig{
+		BlocklistFile: filepath.Join(t.TempDir(), "missing.txt"),
+	})
+	assert.Error(t, err)
+}
+
+func TestShortenerService_DestinationPolicy(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	ctx := context.Background()
+
+	policy := newPolicy(t, application.DestinationConfig{
+		SelfHosts:     []string{"sho.rt"},
+		BlocklistFile: writeList(t, "evil.com\n"),
+	})
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithDestinationPolicy(policy))
+
+	_, err := service.CreateShortURL(ctx, "https://sho.rt/loop", application.CreateOptions{Alias: "loop"})
+	assert.ErrorIs(t, err, domain.ErrDisallowedURL)
+
+	url, err := service.CreateShortURL(ctx, "https://example.com", application.CreateOptions{Alias: "fine"})
+	require.NoError(t, err)
+
+	_, err = service.UpdateURL(ctx, "fine", service.ManageToken(url), application.UpdateOptions{LongURL: "https://evil.com/x"})
+	assert.ErrorIs(t, err, domain.ErrDisallowedURL)
+
+	stored, err := service.GetURL(ctx, "fine")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", stored.LongURL)
+}
@@ -2,64 +2,622 @@ package application
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"strings"
 	"time"
 	"url-shortener/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	// DefaultTombstoneTTL is how long a deleted short code stays reserved.
+	DefaultTombstoneTTL = 30 * 24 * time.Hour
+
+	// DefaultRedirectStatus is used for links without their own status.
+	// Temporary redirects keep edits, expiry and click counts effective for
+	// returning visitors.
+	DefaultRedirectStatus = http.StatusFound
 )
 
 type ShortenerService struct {
-	repo      domain.URLRepository
-	generator domain.ShortCodeGenerator
+	repo                  domain.URLRepository
+	generator             domain.ShortCodeGenerator
+	manageSecret          []byte
+	tombstoneTTL          time.Duration
+	defaultRedirectStatus int
+	deduplicate           bool
+	caseInsensitive       bool
+	destinations          *DestinationPolicy
+	scanner               domain.URLScanner
+	scanPolicy            ScanPolicy
+	passwordAttempts      domain.RateLimiter
+}
+
+type ServiceOption func(*ShortenerService)
+
+// WithManageSecret sets the key that signs manage tokens. Without it a random
+// key is used, so tokens stop working when the process restarts.
+func WithManageSecret(secret []byte) ServiceOption {
+	return func(s *ShortenerService) {
+		s.manageSecret = secret
+	}
+}
+
+func WithTombstoneTTL(ttl time.Duration) ServiceOption {
+	return func(s *ShortenerService) {
+		s.tombstoneTTL = ttl
+	}
+}
+
+// WithDefaultRedirectStatus sets the status used for links that did not pick
+// one. It must be one accepted by domain.ValidRedirectStatus.
+func WithDefaultRedirectStatus(code int) ServiceOption {
+	return func(s *ShortenerService) {
+		s.defaultRedirectStatus = code
+	}
+}
+
+// WithDeduplication makes CreateShortURL return an existing live link for the
+// same canonical long URL instead of minting a new code. Concurrent requests
+// for the same URL may still create separate links.
+func WithDeduplication() ServiceOption {
+	return func(s *ShortenerService) {
+		s.deduplicate = true
+	}
+}
+
+// WithCaseInsensitiveCodes stores new aliases in lowercase and lets lookups
+// fall back to the lowercase form of a code, for codes that are read aloud or
+// typed by hand. Codes that already contain uppercase letters still resolve.
+func WithCaseInsensitiveCodes() ServiceOption {
+	return func(s *ShortenerService) {
+		s.caseInsensitive = true
+	}
+}
+
+// WithDestinationPolicy sets which long URLs may be shortened. Without it only
+// the scheme is checked, allowing http and https.
+func WithDestinationPolicy(policy *DestinationPolicy) ServiceOption {
+	return func(s *ShortenerService) {
+		s.destinations = policy
+	}
+}
+
+type ScanPolicy struct {
+	// OnRedirect rescans destinations when visitors follow a link, catching
+	// sites that turned malicious after the link was created.
+	OnRedirect bool
+
+	// FailClosed refuses new destinations while the scanner is failing.
+	// By default they are let through.
+	FailClosed bool
+}
+
+// WithURLScanner refuses destinations the scanner flags when links are
+// created or edited.
+func WithURLScanner(scanner domain.URLScanner, policy ScanPolicy) ServiceOption {
+	return func(s *ShortenerService) {
+		s.scanner = scanner
+		s.scanPolicy = policy
+	}
+}
+
+// WithPasswordAttemptLimiter limits how many wrong passwords each client may
+// try on each protected link. Without it attempts are only slowed down by
+// bcrypt.
+func WithPasswordAttemptLimiter(limiter domain.RateLimiter) ServiceOption {
+	return func(s *ShortenerService) {
+		s.passwordAttempts = limiter
+	}
 }
 
-func NewShortenerService(repo domain.URLRepository, generator domain.ShortCodeGenerator) *ShortenerService {
-	return &ShortenerService{
-		repo:      repo,
-		generator: generator,
+func NewShortenerService(repo domain.URLRepository, generator domain.ShortCodeGenerator, opts ...ServiceOption) *ShortenerService {
+	s := &ShortenerService{
+		repo:                  repo,
+		generator:             generator,
+		tombstoneTTL:          DefaultTombstoneTTL,
+		defaultRedirectStatus: DefaultRedirectStatus,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(s.manageSecret) == 0 {
+		s.manageSecret = randomSecret()
+	}
+	if s.destinations == nil {
+		// Without list files there is nothing that can fail.
+		s.destinations, _ = NewDestinationPolicy(DestinationConfig{})
+	}
+	return s
+}
+
+type CreateOptions struct {
+	Alias string
+
+	// At most one of the expiry fields may be set. Without any of them the
+	// storage TTL applies, and the storage TTL also caps whatever is chosen.
+	ExpiresAt *time.Time    // absolute expiry
+	ExpiresIn time.Duration // expiry relative to creation
+	NoExpiry  bool          // keep the link for as long as storage allows
+
+	RedirectStatus int // zero uses the service default
+
+	// Password must be entered by visitors before they are redirected.
+	// Empty leaves the link open to anyone.
+	Password string
+}
+
+type UpdateOptions struct {
+	LongURL        string // empty keeps the current destination
+	RedirectStatus int    // zero keeps the current status
 }
 
-func (s *ShortenerService) CreateShortURL(ctx context.Context, longURL string) (*domain.URL, error) {
-	shortCode := s.generator.Generate()
+// AdminUpdateOptions are the changes only admins may make to a link. Nil
+// fields are left as they are.
+type AdminUpdateOptions struct {
+	Disabled *bool
+	OwnerID  *string // "" makes the link anonymous
+}
+
+// reusable reports whether the request leaves every option at its default,
+// so any existing link for the same destination satisfies it.
+func (o CreateOptions) reusable() bool {
+	return o.Alias == "" && o.ExpiresAt == nil && o.ExpiresIn == 0 && !o.NoExpiry && o.RedirectStatus == 0 && o.Password == ""
+}
+
+func (o CreateOptions) expiresAt(now time.Time) (*time.Time, error) {
+	set := 0
+	if o.ExpiresAt != nil {
+		set++
+	}
+	if o.ExpiresIn != 0 {
+		set++
+	}
+	if o.NoExpiry {
+		set++
+	}
+	if set > 1 {
+		return nil, fmt.Errorf("%w: choose only one of an expiry time, a duration or no expiry", domain.ErrInvalidExpiry)
+	}
+
+	switch {
+	case o.ExpiresAt != nil:
+		if !o.ExpiresAt.After(now) {
+			return nil, fmt.Errorf("%w: expiry time must be in the future", domain.ErrInvalidExpiry)
+		}
+		expiresAt := *o.ExpiresAt
+		return &expiresAt, nil
+	case o.ExpiresIn != 0:
+		if o.ExpiresIn < 0 {
+			return nil, fmt.Errorf("%w: duration must be positive", domain.ErrInvalidExpiry)
+		}
+		expiresAt := now.Add(o.ExpiresIn)
+		return &expiresAt, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (s *ShortenerService) CreateShortURL(ctx context.Context, longURL string, opts CreateOptions) (*domain.URL, error) {
+	if err := requireRole(ctx, domain.ActionCreateLinks); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt, err := opts.expiresAt(now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkDestination(ctx, longURL); err != nil {
+		return nil, err
+	}
+
+	owner := callerOwner(ctx)
+	if s.deduplicate && opts.reusable() {
+		existing, err := s.repo.FindByLongURL(ctx, longURL)
+		// Authenticated callers only get their own links back, which they
+		// can manage. Disabled and protected links are never handed out
+		// again.
+		if err == nil && !existing.IsDisabled() && !existing.IsProtected() && (owner == "" || existing.OwnerID == owner) {
+			reused := *existing
+			reused.Reused = true
+			return &reused, nil
+		}
+		if err != nil && !errors.Is(err, domain.ErrURLNotFound) {
+			return nil, fmt.Errorf("failed to look up existing url: %w", err)
+		}
+	}
+
+	passwordHash, err := hashLinkPassword(opts.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	url := &domain.URL{
+		LongURL:        longURL,
+		CreatedAt:      now,
+		ExpiresAt:      expiresAt,
+		RedirectStatus: opts.RedirectStatus,
+		OwnerID:        owner,
+		PasswordHash:   passwordHash,
+	}
+
+	if opts.Alias != "" {
+		if s.caseInsensitive {
+			opts.Alias = strings.ToLower(opts.Alias)
+		}
+		if err := ValidateAlias(opts.Alias); err != nil {
+			return nil, err
+		}
+		url.ShortCode = opts.Alias
+		if err := s.create(ctx, url); err != nil {
+			return nil, err
+		}
+		return url, nil
+	}
 
 	maxRetries := 5
 	for i := 0; i < maxRetries; i++ {
-		exists, err := s.repo.Exists(ctx, shortCode)
+		code, err := s.generate(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to check short code existence: %w", err)
+			return nil, err
 		}
-		if !exists {
-			break
+		url.ShortCode = code
+		if IsReservedAlias(url.ShortCode) {
+			continue
 		}
-		shortCode = s.generator.Generate()
+
+		err = s.create(ctx, url)
+		if errors.Is(err, domain.ErrShortCodeExists) {
+			s.observeCollision(true)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.observeCollision(false)
+		return url, nil
 	}
 
-	url := &domain.URL{
-		ShortCode: shortCode,
-		LongURL:   longURL,
-		CreatedAt: time.Now(),
+	return nil, fmt.Errorf("failed to generate a unique short code after %d attempts: %w", maxRetries, domain.ErrShortCodeUnavailable)
+}
+
+func hashLinkPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > MaxPasswordLength {
+		return "", fmt.Errorf("%w: use at most %d bytes", domain.ErrInvalidLinkPassword, MaxPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash link password: %w", err)
+	}
+	return string(hash), nil
+}
+
+func (s *ShortenerService) generate(ctx context.Context) (string, error) {
+	g, ok := s.generator.(domain.ContextShortCodeGenerator)
+	if !ok {
+		return s.generator.Generate(), nil
+	}
+	code, err := g.GenerateContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate short code: %w", err)
+	}
+	return code, nil
+}
+
+func (s *ShortenerService) observeCollision(collided bool) {
+	if g, ok := s.generator.(domain.AdaptiveShortCodeGenerator); ok {
+		g.Observe(collided)
+	}
+}
+
+func (s *ShortenerService) checkDestination(ctx context.Context, longURL string) error {
+	if err := s.destinations.Check(ctx, longURL); err != nil {
+		return fmt.Errorf("invalid link: %w", err)
+	}
+	if s.scanner == nil {
+		return nil
+	}
+
+	result, err := s.scanner.Scan(ctx, longURL)
+	if err != nil {
+		if s.scanPolicy.FailClosed {
+			return fmt.Errorf("failed to scan url: %w", err)
+		}
+		log.Printf("Error scanning %s, allowing it: %v", longURL, err)
+		return nil
+	}
+	if result.Flagged() {
+		return fmt.Errorf("%w: %s", domain.ErrUnsafeURL, result.Threat)
 	}
+	return nil
+}
 
+// ScanRedirect rescans url's destination before a visitor is sent there. It
+// returns nil when redirect scanning is off or nothing was found.
+func (s *ShortenerService) ScanRedirect(ctx context.Context, url *domain.URL) (*domain.ScanResult, error) {
+	if s.scanner == nil || !s.scanPolicy.OnRedirect {
+		return nil, nil
+	}
+
+	result, err := s.scanner.Scan(ctx, url.LongURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan url: %w", err)
+	}
+	if !result.Flagged() {
+		return nil, nil
+	}
+	return result, nil
+}
+
+func (s *ShortenerService) create(ctx context.Context, url *domain.URL) error {
 	if err := url.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid url: %w", err)
+		return fmt.Errorf("invalid link: %w", err)
 	}
 
-	if err := s.repo.Save(ctx, url); err != nil {
-		return nil, fmt.Errorf("failed to save url: %w", err)
+	if err := s.repo.Create(ctx, url); err != nil {
+		if errors.Is(err, domain.ErrShortCodeExists) {
+			return err
+		}
+		return fmt.Errorf("failed to save url: %w", err)
 	}
 
-	return url, nil
+	return nil
 }
 
-func (s *ShortenerService) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+func (s *ShortenerService) GetURL(ctx context.Context, shortCode string) (*domain.URL, error) {
 	url, err := s.repo.FindByShortCode(ctx, shortCode)
+	if errors.Is(err, domain.ErrURLNotFound) && s.caseInsensitive {
+		if lower := strings.ToLower(shortCode); lower != shortCode {
+			url, err = s.repo.FindByShortCode(ctx, lower)
+		}
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get url: %w", err)
+		return nil, fmt.Errorf("failed to get url: %w", err)
 	}
 
 	if url.IsExpired() {
-		return "", domain.ErrURLNotFound
+		return nil, domain.ErrURLNotFound
+	}
+
+	return url, nil
+}
+
+func (s *ShortenerService) GetLongURL(ctx context.Context, shortCode string) (string, error) {
+	url, err := s.GetURL(ctx, shortCode)
+	if err != nil {
+		return "", err
 	}
 
 	return url.LongURL, nil
 }
+
+// ListURLs lists the caller's links. Anonymous callers get ErrUnauthorized:
+// they own nothing, and everyone's links are only listed to admins, by
+// ListAllURLs.
+func (s *ShortenerService) ListURLs(ctx context.Context, limit, offset int) ([]*domain.URL, error) {
+	if err := requireRole(ctx, domain.ActionViewLinks); err != nil {
+		return nil, err
+	}
+	owner := callerOwner(ctx)
+	if owner == "" {
+		return nil, domain.ErrUnauthorized
+	}
+	return s.list(ctx, limit, offset, owner)
+}
+
+// ListAllURLs lists every link, or those of ownerID if it is set. Only
+// admins may call it.
+func (s *ShortenerService) ListAllURLs(ctx context.Context, limit, offset int, ownerID string) ([]*domain.URL, error) {
+	if err := requireRole(ctx, domain.ActionAdminLinks); err != nil {
+		return nil, err
+	}
+	return s.list(ctx, limit, offset, ownerID)
+}
+
+func (s *ShortenerService) list(ctx context.Context, limit, offset int, ownerID string) ([]*domain.URL, error) {
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		limit = MaxListLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	urls, err := s.repo.List(ctx, domain.ListOptions{Limit: limit, Offset: offset, OwnerID: ownerID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	return urls, nil
+}
+
+// UnlockURL checks password before a visitor of a protected link is
+// redirected, returning ErrWrongPassword if it does not match and a
+// *domain.AttemptsError once client guessed wrong too often. Only wrong
+// passwords count, per link and client, so visitors who know the password
+// are never locked out by others guessing. A client that is locked out is
+// refused even the right password; otherwise it could still tell the right
+// one by it not being refused. Attempts are refused while the limiter is
+// failing, so an outage does not open the link to unlimited guessing.
+func (s *ShortenerService) UnlockURL(ctx context.Context, url *domain.URL, password, client string) error {
+	if !url.IsProtected() {
+		return nil
+	}
+
+	key := "password:" + url.ShortCode + ":" + client
+	if s.passwordAttempts != nil {
+		result, err := s.passwordAttempts.Peek(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to limit password attempts: %w", err)
+		}
+		if !result.Allowed {
+			return &domain.AttemptsError{RetryAfter: result.RetryAfter}
+		}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(password)) != nil {
+		if s.passwordAttempts != nil {
+			if _, err := s.passwordAttempts.Take(ctx, key); err != nil {
+				return fmt.Errorf("failed to count password attempt: %w", err)
+			}
+		}
+		return domain.ErrWrongPassword
+	}
+	return nil
+}
+
+// RedirectStatus returns the status visitors of url are redirected with.
+func (s *ShortenerService) RedirectStatus(url *domain.URL) int {
+	if url.RedirectStatus != 0 {
+		return url.RedirectStatus
+	}
+	return s.defaultRedirectStatus
+}
+
+// UpdateURL changes an existing link's destination or redirect status.
+// manageToken must be the token issued for the link when it was created,
+// unless the caller owns the link or is an admin.
+func (s *ShortenerService) UpdateURL(ctx context.Context, shortCode, manageToken string, opts UpdateOptions) (*domain.URL, error) {
+	url, err := s.Authorize(ctx, shortCode, manageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *url
+	if opts.LongURL != "" {
+		if err := s.checkDestination(ctx, opts.LongURL); err != nil {
+			return nil, err
+		}
+		updated.LongURL = opts.LongURL
+	}
+	if opts.RedirectStatus != 0 {
+		updated.RedirectStatus = opts.RedirectStatus
+	}
+	if err := updated.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+
+	if err := s.repo.Update(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to update url: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// DeleteURL takes a link down. Its short code stays reserved for the
+// tombstone TTL so it is not handed to someone else straight away.
+func (s *ShortenerService) DeleteURL(ctx context.Context, shortCode, manageToken string) error {
+	url, err := s.Authorize(ctx, shortCode, manageToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, url.ShortCode, time.Now().Add(s.tombstoneTTL)); err != nil {
+		return fmt.Errorf("failed to delete url: %w", err)
+	}
+
+	return nil
+}
+
+// AdminUpdateURL disables, restores or reassigns any link. Only admins may
+// call it.
+func (s *ShortenerService) AdminUpdateURL(ctx context.Context, shortCode string, opts AdminUpdateOptions) (*domain.URL, error) {
+	if err := requireRole(ctx, domain.ActionAdminLinks); err != nil {
+		return nil, err
+	}
+
+	url, err := s.GetURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *url
+	if opts.Disabled != nil {
+		switch {
+		case *opts.Disabled && !url.IsDisabled():
+			now := time.Now()
+			updated.DisabledAt = &now
+		case !*opts.Disabled:
+			updated.DisabledAt = nil
+		}
+	}
+	if opts.OwnerID != nil {
+		updated.OwnerID = *opts.OwnerID
+	}
+
+	if err := s.repo.Moderate(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to moderate url: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// Authorize returns the link if the caller may change it: admins may change
+// any link, other roles that may manage links only their own or those they
+// hold a manage token for.
+func (s *ShortenerService) Authorize(ctx context.Context, shortCode, manageToken string) (*domain.URL, error) {
+	role := domain.PrincipalFromContext(ctx).EffectiveRole()
+	if !role.Can(domain.ActionManageLinks) {
+		return nil, &domain.PermissionError{Role: role, Action: domain.ActionManageLinks}
+	}
+
+	url, err := s.GetURL(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.CanManage(ctx, url, manageToken) {
+		return nil, &domain.PermissionError{Role: role, Action: domain.ActionManageLinks, ShortCode: url.ShortCode}
+	}
+
+	return url, nil
+}
+
+// CanManage reports whether the caller may change url, being an admin, its
+// owner or holding its manage token.
+func (s *ShortenerService) CanManage(ctx context.Context, url *domain.URL, manageToken string) bool {
+	role := domain.PrincipalFromContext(ctx).EffectiveRole()
+	switch {
+	case role.Can(domain.ActionAdminLinks):
+		return true
+	case !role.Can(domain.ActionManageLinks):
+		return false
+	case url.OwnerID != "" && url.OwnerID == callerOwner(ctx):
+		return true
+	default:
+		return s.VerifyManageToken(url, manageToken)
+	}
+}
+
+// requireRole returns a *domain.PermissionError unless the caller's role
+// allows action.
+func requireRole(ctx context.Context, action domain.Action) error {
+	role := domain.PrincipalFromContext(ctx).EffectiveRole()
+	if !role.Can(action) {
+		return &domain.PermissionError{Role: role, Action: action}
+	}
+	return nil
+}
+
+// callerOwner returns the owner ID of the authenticated caller, or "" for
+// anonymous requests.
+func callerOwner(ctx context.Context) string {
+	if p := domain.PrincipalFromContext(ctx); p != nil {
+		return p.OwnerID
+	}
+	return ""
+}
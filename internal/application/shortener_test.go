@@ -2,19 +2,33 @@ package application_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 	"url-shortener/internal/application"
 	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/ratelimiter"
+	"url-shortener/internal/infrastructure/repository"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockURLRepository struct {
 	mock.Mock
 }
 
+func (m *MockURLRepository) Create(ctx context.Context, url *domain.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
 func (m *MockURLRepository) Save(ctx context.Context, url *domain.URL) error {
 	args := m.Called(ctx, url)
 	return args.Error(0)
@@ -28,11 +42,42 @@ func (m *MockURLRepository) FindByShortCode(ctx context.Context, shortCode strin
 	return args.Get(0).(*domain.URL), args.Error(1)
 }
 
+func (m *MockURLRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URL, error) {
+	args := m.Called(ctx, longURL)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.URL), args.Error(1)
+}
+
 func (m *MockURLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
 	args := m.Called(ctx, shortCode)
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *MockURLRepository) List(ctx context.Context, opts domain.ListOptions) ([]*domain.URL, error) {
+	args := m.Called(ctx, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.URL), args.Error(1)
+}
+
+func (m *MockURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) Moderate(ctx context.Context, url *domain.URL) error {
+	args := m.Called(ctx, url)
+	return args.Error(0)
+}
+
+func (m *MockURLRepository) Delete(ctx context.Context, shortCode string, tombstoneUntil time.Time) error {
+	args := m.Called(ctx, shortCode, tombstoneUntil)
+	return args.Error(0)
+}
+
 type MockShortCodeGenerator struct {
 	mock.Mock
 }
@@ -54,8 +99,7 @@ func TestShortenerService_CreateShortURL(t *testing.T) {
 			longURL: "https://example.com",
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
 				gen.On("Generate").Return("abc12345")
-				repo.On("Exists", mock.Anything, "abc12345").Return(false, nil)
-				repo.On("Save", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
 					return url.ShortCode == "abc12345" && url.LongURL == "https://example.com"
 				})).Return(nil)
 			},
@@ -65,8 +109,7 @@ func TestShortenerService_CreateShortURL(t *testing.T) {
 			name:    "empty URL",
 			longURL: "",
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
-				gen.On("Generate").Return("abc12345")
-				repo.On("Exists", mock.Anything, "abc12345").Return(false, nil)
+				gen.On("Generate").Return("abc12345").Maybe()
 			},
 			expectedError: true,
 		},
@@ -76,30 +119,33 @@ func TestShortenerService_CreateShortURL(t *testing.T) {
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
 				gen.On("Generate").Return("abc12345").Once()
 				gen.On("Generate").Return("xyz67890").Once()
-				repo.On("Exists", mock.Anything, "abc12345").Return(true, nil).Once()
-				repo.On("Exists", mock.Anything, "xyz67890").Return(false, nil).Once()
-				repo.On("Save", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+					return url.ShortCode == "abc12345"
+				})).Return(domain.ErrShortCodeExists).Once()
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
 					return url.ShortCode == "xyz67890" && url.LongURL == "https://example.com"
-				})).Return(nil)
+				})).Return(nil).Once()
 			},
 			expectedError: false,
 		},
 		{
-			name:    "error checking existence",
+			name:    "skips reserved generated code",
 			longURL: "https://example.com",
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
-				gen.On("Generate").Return("abc12345")
-				repo.On("Exists", mock.Anything, "abc12345").Return(false, assert.AnError)
+				gen.On("Generate").Return("api").Once()
+				gen.On("Generate").Return("xyz").Once()
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+					return url.ShortCode == "xyz"
+				})).Return(nil).Once()
 			},
-			expectedError: true,
+			expectedError: false,
 		},
 		{
 			name:    "error saving URL",
 			longURL: "https://example.com",
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
 				gen.On("Generate").Return("abc12345")
-				repo.On("Exists", mock.Anything, "abc12345").Return(false, nil)
-				repo.On("Save", mock.Anything, mock.Anything).Return(assert.AnError)
+				repo.On("Create", mock.Anything, mock.Anything).Return(assert.AnError)
 			},
 			expectedError: true,
 		},
@@ -107,16 +153,12 @@ func TestShortenerService_CreateShortURL(t *testing.T) {
 			name:    "max retries exceeded - all codes exist",
 			longURL: "https://example.com",
 			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
-				// Generate 5 codes, all exist
 				for i := 0; i < 5; i++ {
 					gen.On("Generate").Return("code" + string(rune('0'+i))).Once()
-					repo.On("Exists", mock.Anything, "code"+string(rune('0'+i))).Return(true, nil).Once()
 				}
-				// After max retries, it will still try to save with the last generated code
-				gen.On("Generate").Return("code4").Once()
-				repo.On("Save", mock.Anything, mock.Anything).Return(nil)
+				repo.On("Create", mock.Anything, mock.Anything).Return(domain.ErrShortCodeExists).Times(5)
 			},
-			expectedError: false, // It will save with the last code even if it exists
+			expectedError: true, // never saves a code that is already taken
 		},
 	}
 
@@ -129,7 +171,7 @@ func TestShortenerService_CreateShortURL(t *testing.T) {
 			service := application.NewShortenerService(repo, gen)
 			ctx := context.Background()
 
-			result, err := service.CreateShortURL(ctx, tt.longURL)
+			result, err := service.CreateShortURL(ctx, tt.longURL, application.CreateOptions{})
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -146,6 +188,281 @@ func TestShortenerService_CreateShortURL(t *testing.T) {
 	}
 }
 
+// poolGenerator cycles through a tiny set of codes so concurrent creates are
+// guaranteed to collide.
+type poolGenerator struct {
+	mu    sync.Mutex
+	codes []string
+	next  int
+}
+
+func (g *poolGenerator) Generate() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	code := g.codes[g.next%len(g.codes)]
+	g.next++
+	return code
+}
+
+func TestShortenerService_CreateShortURL_ConcurrentNoOverwrite(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	gen := &poolGenerator{codes: []string{"aaa", "bbb", "ccc", "ddd", "eee", "fff", "ggg", "hhh"}}
+	service := application.NewShortenerService(repo, gen)
+	ctx := context.Background()
+
+	const workers = 40
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := map[string]string{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			longURL := fmt.Sprintf("https://example.com/%d", i)
+			url, err := service.CreateShortURL(ctx, longURL, application.CreateOptions{})
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrShortCodeUnavailable)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			_, dup := created[url.ShortCode]
+			assert.False(t, dup, "short code %s handed out twice", url.ShortCode)
+			created[url.ShortCode] = longURL
+		}(i)
+	}
+	wg.Wait()
+
+	assert.NotEmpty(t, created)
+	for code, longURL := range created {
+		stored, err := service.GetLongURL(ctx, code)
+		assert.NoError(t, err)
+		assert.Equal(t, longURL, stored, "link %s was overwritten", code)
+	}
+}
+
+type observingGenerator struct {
+	MockShortCodeGenerator
+	observed []bool
+}
+
+func (g *observingGenerator) Observe(collided bool) {
+	g.observed = append(g.observed, collided)
+}
+
+func TestShortenerService_CreateShortURL_ObservesCollisions(t *testing.T) {
+	repo := new(MockURLRepository)
+	gen := new(observingGenerator)
+	gen.On("Generate").Return("taken1").Twice()
+	gen.On("Generate").Return("free01").Once()
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.ShortCode == "taken1"
+	})).Return(domain.ErrShortCodeExists)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+		return url.ShortCode == "free01"
+	})).Return(nil)
+
+	service := application.NewShortenerService(repo, gen)
+	url, err := service.CreateShortURL(context.Background(), "https://example.com", application.CreateOptions{})
+
+	require.NoError(t, err)
+	assert.Equal(t, "free01", url.ShortCode)
+	assert.Equal(t, []bool{true, true, false}, gen.observed)
+}
+
+func TestShortenerService_CreateShortURL_Unavailable(t *testing.T) {
+	repo := new(MockURLRepository)
+	gen := new(MockShortCodeGenerator)
+	gen.On("Generate").Return("taken1")
+	repo.On("Create", mock.Anything, mock.Anything).Return(domain.ErrShortCodeExists)
+
+	service := application.NewShortenerService(repo, gen)
+	url, err := service.CreateShortURL(context.Background(), "https://example.com", application.CreateOptions{})
+
+	assert.ErrorIs(t, err, domain.ErrShortCodeUnavailable)
+	assert.NotErrorIs(t, err, domain.ErrShortCodeExists)
+	assert.Nil(t, url)
+}
+
+type contextGenerator struct {
+	MockShortCodeGenerator
+}
+
+func (g *contextGenerator) GenerateContext(ctx context.Context) (string, error) {
+	args := g.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func TestShortenerService_CreateShortURL_ContextGenerator(t *testing.T) {
+	t.Run("uses GenerateContext", func(t *testing.T) {
+		repo := new(MockURLRepository)
+		gen := new(contextGenerator)
+		gen.On("GenerateContext", mock.Anything).Return("seq001", nil)
+		repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+			return url.ShortCode == "seq001"
+		})).Return(nil)
+
+		service := application.NewShortenerService(repo, gen)
+		url, err := service.CreateShortURL(context.Background(), "https://example.com", application.CreateOptions{})
+
+		require.NoError(t, err)
+		assert.Equal(t, "seq001", url.ShortCode)
+		gen.AssertNotCalled(t, "Generate")
+	})
+
+	t.Run("generator error", func(t *testing.T) {
+		repo := new(MockURLRepository)
+		gen := new(contextGenerator)
+		gen.On("GenerateContext", mock.Anything).Return("", errors.New("sequence unavailable"))
+
+		service := application.NewShortenerService(repo, gen)
+		url, err := service.CreateShortURL(context.Background(), "https://example.com", application.CreateOptions{})
+
+		assert.Error(t, err)
+		assert.Nil(t, url)
+		repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestShortenerService_CreateShortURL_WithAlias(t *testing.T) {
+	tests := []struct {
+		name          string
+		alias         string
+		setupMocks    func(*MockURLRepository)
+		expectedError error
+	}{
+		{
+			name:  "available alias",
+			alias: "spring-sale",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(url *domain.URL) bool {
+					return url.ShortCode == "spring-sale"
+				})).Return(nil)
+			},
+		},
+		{
+			name:  "alias taken",
+			alias: "spring-sale",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("Create", mock.Anything, mock.Anything).Return(domain.ErrShortCodeExists)
+			},
+			expectedError: domain.ErrShortCodeExists,
+		},
+		{
+			name:          "reserved alias",
+			alias:         "api",
+			setupMocks:    func(repo *MockURLRepository) {},
+			expectedError: domain.ErrInvalidAlias,
+		},
+		{
+			name:          "invalid alias",
+			alias:         "no spaces",
+			setupMocks:    func(repo *MockURLRepository) {},
+			expectedError: domain.ErrInvalidAlias,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			tt.setupMocks(repo)
+
+			service := application.NewShortenerService(repo, gen)
+			result, err := service.CreateShortURL(context.Background(), "https://example.com", application.CreateOptions{Alias: tt.alias})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.alias, result.ShortCode)
+			}
+
+			repo.AssertExpectations(t)
+			gen.AssertNotCalled(t, "Generate")
+		})
+	}
+}
+
+func TestShortenerService_CreateShortURL_Expiry(t *testing.T) {
+	future := time.Now().Add(2 * time.Hour)
+	past := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name          string
+		opts          application.CreateOptions
+		expectedIn    time.Duration // zero means no expiry is set by the service
+		expectedError error
+	}{
+		{
+			name: "default leaves expiry to storage",
+			opts: application.CreateOptions{},
+		},
+		{
+			name: "never",
+			opts: application.CreateOptions{NoExpiry: true},
+		},
+		{
+			name:       "relative duration",
+			opts:       application.CreateOptions{ExpiresIn: 30 * time.Minute},
+			expectedIn: 30 * time.Minute,
+		},
+		{
+			name:       "absolute timestamp",
+			opts:       application.CreateOptions{ExpiresAt: &future},
+			expectedIn: 2 * time.Hour,
+		},
+		{
+			name:          "timestamp in the past",
+			opts:          application.CreateOptions{ExpiresAt: &past},
+			expectedError: domain.ErrInvalidExpiry,
+		},
+		{
+			name:          "negative duration",
+			opts:          application.CreateOptions{ExpiresIn: -time.Minute},
+			expectedError: domain.ErrInvalidExpiry,
+		},
+		{
+			name:          "conflicting options",
+			opts:          application.CreateOptions{ExpiresIn: time.Hour, NoExpiry: true},
+			expectedError: domain.ErrInvalidExpiry,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			if tt.expectedError == nil {
+				gen.On("Generate").Return("abc123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			}
+
+			service := application.NewShortenerService(repo, gen)
+			result, err := service.CreateShortURL(context.Background(), "https://example.com", tt.opts)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, result)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tt.expectedIn == 0 {
+				assert.Nil(t, result.ExpiresAt)
+			} else {
+				assert.NotNil(t, result.ExpiresAt)
+				assert.WithinDuration(t, time.Now().Add(tt.expectedIn), *result.ExpiresAt, time.Second)
+			}
+		})
+	}
+}
+
 func TestShortenerService_GetLongURL(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -218,3 +535,700 @@ func TestShortenerService_GetLongURL(t *testing.T) {
 		})
 	}
 }
+
+func TestShortenerService_ListURLs(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		offset        int
+		expectedOpts  domain.ListOptions
+		repoErr       error
+		expectedError bool
+	}{
+		{
+			name:         "default limit",
+			limit:        0,
+			offset:       0,
+			expectedOpts: domain.ListOptions{Limit: application.DefaultListLimit, OwnerID: "owner1"},
+		},
+		{
+			name:         "limit capped",
+			limit:        1000,
+			offset:       5,
+			expectedOpts: domain.ListOptions{Limit: application.MaxListLimit, Offset: 5, OwnerID: "owner1"},
+		},
+		{
+			name:         "negative offset",
+			limit:        10,
+			offset:       -3,
+			expectedOpts: domain.ListOptions{Limit: 10, OwnerID: "owner1"},
+		},
+		{
+			name:          "repository error",
+			limit:         10,
+			expectedOpts:  domain.ListOptions{Limit: 10, OwnerID: "owner1"},
+			repoErr:       assert.AnError,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			if tt.repoErr != nil {
+				repo.On("List", mock.Anything, tt.expectedOpts).Return(nil, tt.repoErr)
+			} else {
+				repo.On("List", mock.Anything, tt.expectedOpts).Return([]*domain.URL{}, nil)
+			}
+
+			service := application.NewShortenerService(repo, gen)
+			ctx := domain.ContextWithPrincipal(context.Background(), &domain.Principal{OwnerID: "owner1"})
+			result, err := service.ListURLs(ctx, tt.limit, tt.offset)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, result)
+			}
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShortenerService_ManageToken(t *testing.T) {
+	url := &domain.URL{ShortCode: "abc123", CreatedAt: time.Now()}
+
+	service := application.NewShortenerService(new(MockURLRepository), new(MockShortCodeGenerator), application.WithManageSecret([]byte("secret")))
+	token := service.ManageToken(url)
+
+	assert.NotEmpty(t, token)
+	assert.True(t, service.VerifyManageToken(url, token))
+	assert.False(t, service.VerifyManageToken(url, ""))
+	assert.False(t, service.VerifyManageToken(url, token+"x"))
+
+	reissued := &domain.URL{ShortCode: "abc123", CreatedAt: url.CreatedAt.Add(time.Hour)}
+	assert.False(t, service.VerifyManageToken(reissued, token), "a reissued code must not accept the old token")
+
+	other := application.NewShortenerService(new(MockURLRepository), new(MockShortCodeGenerator), application.WithManageSecret([]byte("other")))
+	assert.False(t, other.VerifyManageToken(url, token))
+}
+
+func TestShortenerService_UpdateURL(t *testing.T) {
+	existing := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/typo", CreatedAt: time.Now()}
+
+	tests := []struct {
+		name          string
+		token         func(*application.ShortenerService) string
+		longURL       string
+		setupMocks    func(*MockURLRepository)
+		expectedError error
+	}{
+		{
+			name:    "success",
+			token:   func(s *application.ShortenerService) string { return s.ManageToken(existing) },
+			longURL: "https://example.com/fixed",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(existing, nil)
+				repo.On("Update", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
+					return u.ShortCode == "abc123" && u.LongURL == "https://example.com/fixed"
+				})).Return(nil)
+			},
+		},
+		{
+			name:    "wrong token",
+			token:   func(*application.ShortenerService) string { return "guess" },
+			longURL: "https://example.com/fixed",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(existing, nil)
+			},
+			expectedError: domain.ErrForbidden,
+		},
+		{
+			name:    "invalid destination",
+			token:   func(s *application.ShortenerService) string { return s.ManageToken(existing) },
+			longURL: "not-a-url",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(existing, nil)
+			},
+			expectedError: domain.ErrInvalidURL,
+		},
+		{
+			name:    "unknown link",
+			token:   func(*application.ShortenerService) string { return "" },
+			longURL: "https://example.com/fixed",
+			setupMocks: func(repo *MockURLRepository) {
+				repo.On("FindByShortCode", mock.Anything, "abc123").Return(nil, domain.ErrURLNotFound)
+			},
+			expectedError: domain.ErrURLNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			tt.setupMocks(repo)
+			service := application.NewShortenerService(repo, new(MockShortCodeGenerator))
+
+			updated, err := service.UpdateURL(context.Background(), "abc123", tt.token(service), application.UpdateOptions{LongURL: tt.longURL})
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Nil(t, updated)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.longURL, updated.LongURL)
+				assert.Equal(t, "https://example.com/typo", existing.LongURL, "the stored URL must not be mutated")
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShortenerService_DeleteURL(t *testing.T) {
+	existing := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}
+
+	repo := new(MockURLRepository)
+	repo.On("FindByShortCode", mock.Anything, "abc123").Return(existing, nil)
+	repo.On("Delete", mock.Anything, "abc123", mock.MatchedBy(func(until time.Time) bool {
+		return time.Until(until) > 47*time.Hour && time.Until(until) <= 48*time.Hour
+	})).Return(nil).Once()
+
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithTombstoneTTL(48*time.Hour))
+
+	assert.ErrorIs(t, service.DeleteURL(context.Background(), "abc123", "guess"), domain.ErrForbidden)
+	assert.NoError(t, service.DeleteURL(context.Background(), "abc123", service.ManageToken(existing)))
+	repo.AssertExpectations(t)
+}
+
+func TestShortenerService_RedirectStatus(t *testing.T) {
+	repo := new(MockURLRepository)
+	gen := new(MockShortCodeGenerator)
+
+	service := application.NewShortenerService(repo, gen)
+	assert.Equal(t, http.StatusFound, service.RedirectStatus(&domain.URL{}))
+	assert.Equal(t, http.StatusPermanentRedirect, service.RedirectStatus(&domain.URL{RedirectStatus: http.StatusPermanentRedirect}))
+
+	service = application.NewShortenerService(repo, gen, application.WithDefaultRedirectStatus(http.StatusMovedPermanently))
+	assert.Equal(t, http.StatusMovedPermanently, service.RedirectStatus(&domain.URL{}))
+}
+
+func TestShortenerService_CreateShortURL_RedirectStatus(t *testing.T) {
+	repo := new(MockURLRepository)
+	gen := new(MockShortCodeGenerator)
+	gen.On("Generate").Return("abc123")
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(u *domain.URL) bool {
+		return u.RedirectStatus == http.StatusTemporaryRedirect
+	})).Return(nil).Once()
+
+	service := application.NewShortenerService(repo, gen)
+
+	url, err := service.CreateShortURL(context.Background(), "https://example.com", application.CreateOptions{RedirectStatus: http.StatusTemporaryRedirect})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, url.RedirectStatus)
+
+	_, err = service.CreateShortURL(context.Background(), "https://example.com", application.CreateOptions{RedirectStatus: http.StatusSeeOther})
+	assert.ErrorIs(t, err, domain.ErrInvalidRedirect)
+	repo.AssertExpectations(t)
+}
+
+func TestShortenerService_CreateShortURL_Deduplication(t *testing.T) {
+	existing := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/", CreatedAt: time.Now()}
+
+	tests := []struct {
+		name        string
+		dedup       bool
+		opts        application.CreateOptions
+		setupMocks  func(*MockURLRepository, *MockShortCodeGenerator)
+		wantCode    string
+		wantReused  bool
+		expectError bool
+	}{
+		{
+			name:  "returns existing link",
+			dedup: true,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("FindByLongURL", mock.Anything, "https://Example.com").Return(existing, nil)
+			},
+			wantCode:   "abc123",
+			wantReused: true,
+		},
+		{
+			name:  "creates link when none exists",
+			dedup: true,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("FindByLongURL", mock.Anything, "https://Example.com").Return(nil, domain.ErrURLNotFound)
+				gen.On("Generate").Return("new123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			},
+			wantCode: "new123",
+		},
+		{
+			name:  "lookup failure",
+			dedup: true,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				repo.On("FindByLongURL", mock.Anything, "https://Example.com").Return(nil, assert.AnError)
+			},
+			expectError: true,
+		},
+		{
+			name: "disabled",
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("new123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			},
+			wantCode: "new123",
+		},
+		{
+			name:  "protected link is not reused",
+			dedup: true,
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				protected := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/", PasswordHash: "$2a$10$hash"}
+				repo.On("FindByLongURL", mock.Anything, "https://Example.com").Return(protected, nil)
+				gen.On("Generate").Return("new123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			},
+			wantCode: "new123",
+		},
+		{
+			name:  "password skips deduplication",
+			dedup: true,
+			opts:  application.CreateOptions{Password: "s3cret"},
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("new123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			},
+			wantCode: "new123",
+		},
+		{
+			name:  "custom options skip deduplication",
+			dedup: true,
+			opts:  application.CreateOptions{ExpiresIn: time.Hour},
+			setupMocks: func(repo *MockURLRepository, gen *MockShortCodeGenerator) {
+				gen.On("Generate").Return("new123")
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			},
+			wantCode: "new123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockURLRepository)
+			gen := new(MockShortCodeGenerator)
+			tt.setupMocks(repo, gen)
+
+			var opts []application.ServiceOption
+			if tt.dedup {
+				opts = append(opts, application.WithDeduplication())
+			}
+			service := application.NewShortenerService(repo, gen, opts...)
+
+			url, err := service.CreateShortURL(context.Background(), "https://Example.com", tt.opts)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, url)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantCode, url.ShortCode)
+				assert.Equal(t, tt.wantReused, url.Reused)
+			}
+			assert.False(t, existing.Reused, "the repository's copy must not be modified")
+
+			repo.AssertExpectations(t)
+			gen.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShortenerService_CaseInsensitiveCodes(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "MixedCase", LongURL: "https://example.com/mixed", CreatedAt: time.Now()}))
+
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithCaseInsensitiveCodes())
+
+	created, err := service.CreateShortURL(ctx, "https://example.com/alias", application.CreateOptions{Alias: "Brave-Otter-42"})
+	require.NoError(t, err)
+	assert.Equal(t, "brave-otter-42", created.ShortCode)
+
+	for _, code := range []string{"brave-otter-42", "BRAVE-OTTER-42", "Brave-Otter-42"} {
+		url, err := service.GetURL(ctx, code)
+		require.NoError(t, err, code)
+		assert.Equal(t, "brave-otter-42", url.ShortCode)
+	}
+
+	url, err := service.GetURL(ctx, "MixedCase")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/mixed", url.LongURL, "existing mixed-case codes keep resolving")
+
+	_, err = service.CreateShortURL(ctx, "https://example.com/other", application.CreateOptions{Alias: "BRAVE-otter-42"})
+	assert.ErrorIs(t, err, domain.ErrShortCodeExists)
+
+	require.NoError(t, service.DeleteURL(ctx, "BRAVE-OTTER-42", service.ManageToken(created)))
+	_, err = service.GetURL(ctx, "brave-otter-42")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+func TestShortenerService_CaseSensitiveByDefault(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	ctx := context.Background()
+
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator))
+
+	created, err := service.CreateShortURL(ctx, "https://example.com", application.CreateOptions{Alias: "Spring-Sale"})
+	require.NoError(t, err)
+	assert.Equal(t, "Spring-Sale", created.ShortCode)
+
+	_, err = service.GetURL(ctx, "spring-sale")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
+
+type scannerFunc func(rawURL string) (*domain.ScanResult, error)
+
+func (f scannerFunc) Scan(ctx context.Context, rawURL string) (*domain.ScanResult, error) {
+	return f(rawURL)
+}
+
+func TestShortenerService_URLScanner(t *testing.T) {
+	scanner := scannerFunc(func(rawURL string) (*domain.ScanResult, error) {
+		switch rawURL {
+		case "https://phish.example/":
+			return &domain.ScanResult{Threat: domain.ThreatPhishing}, nil
+		case "https://timeout.example/":
+			return nil, errors.New("scanner unavailable")
+		}
+		return &domain.ScanResult{}, nil
+	})
+	ctx := context.Background()
+
+	t.Run("refuses flagged destinations", func(t *testing.T) {
+		repo := repository.NewMemoryURLRepository(0)
+		defer repo.(*repository.MemoryURLRepository).Close()
+		service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithURLScanner(scanner, application.ScanPolicy{}))
+
+		_, err := service.CreateShortURL(ctx, "https://phish.example/", application.CreateOptions{Alias: "phish"})
+		assert.ErrorIs(t, err, domain.ErrUnsafeURL)
+
+		url, err := service.CreateShortURL(ctx, "https://example.com/", application.CreateOptions{Alias: "clean"})
+		require.NoError(t, err)
+
+		_, err = service.UpdateURL(ctx, "clean", service.ManageToken(url), application.UpdateOptions{LongURL: "https://phish.example/"})
+		assert.ErrorIs(t, err, domain.ErrUnsafeURL)
+	})
+
+	t.Run("fails open by default", func(t *testing.T) {
+		repo := repository.NewMemoryURLRepository(0)
+		defer repo.(*repository.MemoryURLRepository).Close()
+		service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithURLScanner(scanner, application.ScanPolicy{}))
+
+		_, err := service.CreateShortURL(ctx, "https://timeout.example/", application.CreateOptions{Alias: "open"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("fails closed when configured", func(t *testing.T) {
+		repo := repository.NewMemoryURLRepository(0)
+		defer repo.(*repository.MemoryURLRepository).Close()
+		service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithURLScanner(scanner, application.ScanPolicy{FailClosed: true}))
+
+		_, err := service.CreateShortURL(ctx, "https://timeout.example/", application.CreateOptions{Alias: "closed"})
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, domain.ErrUnsafeURL)
+	})
+
+	t.Run("scans on redirect when enabled", func(t *testing.T) {
+		repo := repository.NewMemoryURLRepository(0)
+		defer repo.(*repository.MemoryURLRepository).Close()
+		link := &domain.URL{ShortCode: "turned", LongURL: "https://phish.example/", CreatedAt: time.Now()}
+
+		service := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithURLScanner(scanner, application.ScanPolicy{}))
+		result, err := service.ScanRedirect(ctx, link)
+		require.NoError(t, err)
+		assert.Nil(t, result, "redirect scanning is off by default")
+
+		service = application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithURLScanner(scanner, application.ScanPolicy{OnRedirect: true}))
+		result, err = service.ScanRedirect(ctx, link)
+		require.NoError(t, err)
+		assert.Equal(t, domain.ThreatPhishing, result.Threat)
+
+		result, err = service.ScanRedirect(ctx, &domain.URL{ShortCode: "clean", LongURL: "https://example.com/"})
+		require.NoError(t, err)
+		assert.Nil(t, result)
+
+		_, err = service.ScanRedirect(ctx, &domain.URL{ShortCode: "slow", LongURL: "https://timeout.example/"})
+		assert.Error(t, err)
+	})
+}
+
+func TestShortenerService_Ownership(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	gen := new(MockShortCodeGenerator)
+	gen.On("Generate").Return("abc123").Once()
+	gen.On("Generate").Return("def456").Once()
+	gen.On("Generate").Return("ghi789").Once()
+	service := application.NewShortenerService(repo, gen, application.WithDeduplication())
+
+	alice := domain.ContextWithPrincipal(context.Background(), &domain.Principal{OwnerID: "alice", APIKeyID: "key1"})
+	bob := domain.ContextWithPrincipal(context.Background(), &domain.Principal{OwnerID: "bob", APIKeyID: "key2"})
+
+	owned, err := service.CreateShortURL(alice, "https://example.com", application.CreateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", owned.OwnerID)
+
+	reused, err := service.CreateShortURL(alice, "https://example.com", application.CreateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", reused.ShortCode)
+	assert.True(t, reused.Reused)
+
+	// Deduplication never hands out another owner's link.
+	other, err := service.CreateShortURL(bob, "https://example.com", application.CreateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "def456", other.ShortCode)
+	assert.False(t, other.Reused)
+
+	anonymous, err := service.CreateShortURL(context.Background(), "https://example.org", application.CreateOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, anonymous.OwnerID)
+
+	urls, err := service.ListURLs(alice, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "abc123", urls[0].ShortCode)
+
+	_, err = service.ListURLs(context.Background(), 10, 0)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized, "anonymous callers must not list everyone's links")
+
+	_, err = service.UpdateURL(bob, "abc123", "", application.UpdateOptions{LongURL: "https://example.com/bob"})
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	assert.ErrorIs(t, service.DeleteURL(context.Background(), "abc123", ""), domain.ErrForbidden)
+	// Anonymous links can only be managed with their token.
+	assert.ErrorIs(t, service.DeleteURL(alice, "ghi789", ""), domain.ErrForbidden)
+
+	updated, err := service.UpdateURL(alice, "abc123", "", application.UpdateOptions{LongURL: "https://example.com/alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/alice", updated.LongURL)
+	assert.NoError(t, service.DeleteURL(alice, "abc123", ""))
+	gen.AssertExpectations(t)
+}
+
+func TestShortenerService_RolePolicy(t *testing.T) {
+	callers := map[string]*domain.Principal{
+		"anonymous": nil,
+		"viewer":    {OwnerID: "alice", Role: domain.RoleViewer},
+		"creator":   {OwnerID: "alice", Role: domain.RoleCreator},
+		"legacy":    {OwnerID: "alice"},
+		"admin":     {OwnerID: "alice", Role: domain.RoleAdmin},
+	}
+	disabled := true
+	operations := map[string]func(ctx context.Context, s *application.ShortenerService) error{
+		"create": func(ctx context.Context, s *application.ShortenerService) error {
+			_, err := s.CreateShortURL(ctx, "https://example.com/new", application.CreateOptions{Alias: "new-link"})
+			return err
+		},
+		"list": func(ctx context.Context, s *application.ShortenerService) error {
+			_, err := s.ListURLs(ctx, 10, 0)
+			return err
+		},
+		"list all": func(ctx context.Context, s *application.ShortenerService) error {
+			_, err := s.ListAllURLs(ctx, 10, 0, "")
+			return err
+		},
+		"update own": func(ctx context.Context, s *application.ShortenerService) error {
+			_, err := s.UpdateURL(ctx, "alice1", "", application.UpdateOptions{LongURL: "https://example.com/moved"})
+			return err
+		},
+		"update with token": func(ctx context.Context, s *application.ShortenerService) error {
+			url, err := s.GetURL(ctx, "bob001")
+			require.NoError(t, err)
+			_, err = s.UpdateURL(ctx, "bob001", s.ManageToken(url), application.UpdateOptions{LongURL: "https://example.com/moved"})
+			return err
+		},
+		"update other's": func(ctx context.Context, s *application.ShortenerService) error {
+			_, err := s.UpdateURL(ctx, "bob001", "", application.UpdateOptions{LongURL: "https://example.com/moved"})
+			return err
+		},
+		"delete other's": func(ctx context.Context, s *application.ShortenerService) error {
+			return s.DeleteURL(ctx, "bob001", "")
+		},
+		"disable": func(ctx context.Context, s *application.ShortenerService) error {
+			_, err := s.AdminUpdateURL(ctx, "bob001", application.AdminUpdateOptions{Disabled: &disabled})
+			return err
+		},
+		"reassign": func(ctx context.Context, s *application.ShortenerService) error {
+			owner := "alice"
+			_, err := s.AdminUpdateURL(ctx, "bob001", application.AdminUpdateOptions{OwnerID: &owner})
+			return err
+		},
+	}
+
+	// allowed lists the operations each caller may perform; all others must
+	// fail with a permission error.
+	allowed := map[string][]string{
+		"anonymous": {"create", "update with token"},
+		"viewer":    {"list"},
+		"creator":   {"create", "list", "update own", "update with token"},
+		"legacy":    {"create", "list", "update own", "update with token"},
+		"admin":     {"create", "list", "list all", "update own", "update with token", "update other's", "delete other's", "disable", "reassign"},
+	}
+
+	for caller, principal := range callers {
+		for operation, run := range operations {
+			t.Run(caller+"/"+operation, func(t *testing.T) {
+				ctx := context.Background()
+				repo := repository.NewMemoryURLRepository(0)
+				defer repo.(*repository.MemoryURLRepository).Close()
+				require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "alice1", LongURL: "https://example.com/a", CreatedAt: time.Now(), OwnerID: "alice"}))
+				require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "bob001", LongURL: "https://example.com/b", CreatedAt: time.Now(), OwnerID: "bob"}))
+				service := application.NewShortenerService(repo, new(MockShortCodeGenerator))
+
+				if principal != nil {
+					ctx = domain.ContextWithPrincipal(ctx, principal)
+				}
+				err := run(ctx, service)
+				if slices.Contains(allowed[caller], operation) {
+					assert.NoError(t, err)
+					return
+				}
+				if principal == nil && operation == "list" {
+					// Anonymous callers own no links to list.
+					assert.ErrorIs(t, err, domain.ErrUnauthorized)
+					return
+				}
+				var permErr *domain.PermissionError
+				require.ErrorAs(t, err, &permErr)
+				assert.ErrorIs(t, err, domain.ErrForbidden)
+				assert.Equal(t, principal.EffectiveRole(), permErr.Role)
+			})
+		}
+	}
+}
+
+func TestShortenerService_AdminUpdateURL(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	gen := new(MockShortCodeGenerator)
+	gen.On("Generate").Return("fresh1").Once()
+	service := application.NewShortenerService(repo, gen, application.WithDeduplication())
+	admin := domain.ContextWithPrincipal(ctx, &domain.Principal{OwnerID: "root", Role: domain.RoleAdmin})
+	bob := domain.ContextWithPrincipal(ctx, &domain.Principal{OwnerID: "bob"})
+
+	created, err := service.CreateShortURL(bob, "https://example.com", application.CreateOptions{Alias: "bobs-link"})
+	require.NoError(t, err)
+	bobsToken := service.ManageToken(created)
+
+	disable, enable := true, false
+	url, err := service.AdminUpdateURL(admin, created.ShortCode, application.AdminUpdateOptions{Disabled: &disable})
+	require.NoError(t, err)
+	require.True(t, url.IsDisabled())
+	disabledAt := *url.DisabledAt
+
+	again, err := service.AdminUpdateURL(admin, created.ShortCode, application.AdminUpdateOptions{Disabled: &disable})
+	require.NoError(t, err)
+	assert.Equal(t, disabledAt, *again.DisabledAt, "disabling twice keeps the first time")
+
+	// A disabled link is not handed out again for the same destination.
+	fresh, err := service.CreateShortURL(bob, "https://example.com", application.CreateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "fresh1", fresh.ShortCode)
+	assert.False(t, fresh.Reused)
+
+	carol := "carol"
+	url, err = service.AdminUpdateURL(admin, created.ShortCode, application.AdminUpdateOptions{Disabled: &enable, OwnerID: &carol})
+	require.NoError(t, err)
+	assert.False(t, url.IsDisabled())
+	assert.Equal(t, "carol", url.OwnerID)
+
+	listed, err := service.ListAllURLs(admin, 10, 0, "carol")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, created.ShortCode, listed[0].ShortCode)
+
+	_, err = service.UpdateURL(bob, created.ShortCode, "", application.UpdateOptions{LongURL: "https://example.com/bob"})
+	assert.ErrorIs(t, err, domain.ErrForbidden, "bob no longer owns the link")
+	_, err = service.UpdateURL(ctx, created.ShortCode, bobsToken, application.UpdateOptions{LongURL: "https://example.com/bob"})
+	assert.ErrorIs(t, err, domain.ErrForbidden, "the token bob saw is refused after reassignment")
+	assert.ErrorIs(t, service.DeleteURL(ctx, created.ShortCode, bobsToken), domain.ErrForbidden)
+	_, err = service.UpdateURL(ctx, created.ShortCode, service.ManageToken(url), application.UpdateOptions{LongURL: "https://example.com/carol"})
+	assert.NoError(t, err, "the new owner's token works")
+
+	_, err = service.AdminUpdateURL(admin, "missing", application.AdminUpdateOptions{Disabled: &disable})
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	gen.AssertExpectations(t)
+}
+
+// failingLimiter stands in for a rate limiter whose store is down.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return false, assert.AnError
+}
+
+func (failingLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return nil, assert.AnError
+}
+
+func (failingLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return nil, assert.AnError
+}
+
+func TestShortenerService_ProtectedLinks(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+	limiter := ratelimiter.NewMemoryRateLimiter(3, time.Minute)
+	defer limiter.(*ratelimiter.MemoryRateLimiter).Close()
+	service := application.NewShortenerService(repo, new(MockShortCodeGenerator),
+		application.WithDeduplication(),
+		application.WithPasswordAttemptLimiter(limiter),
+	)
+
+	_, err := service.CreateShortURL(ctx, "https://example.com/doc", application.CreateOptions{Alias: "too-long", Password: strings.Repeat("x", 73)})
+	assert.ErrorIs(t, err, domain.ErrInvalidLinkPassword)
+
+	created, err := service.CreateShortURL(ctx, "https://example.com/doc", application.CreateOptions{Alias: "handbook", Password: "s3cret"})
+	require.NoError(t, err)
+	assert.True(t, created.IsProtected())
+	assert.NotContains(t, created.PasswordHash, "s3cret", "the password is stored hashed")
+
+	open, err := service.CreateShortURL(ctx, "https://example.com/doc", application.CreateOptions{Alias: "open-doc"})
+	require.NoError(t, err)
+	assert.NoError(t, service.UnlockURL(ctx, open, "", "192.0.2.1"), "open links need no password")
+
+	url, err := service.GetURL(ctx, "handbook")
+	require.NoError(t, err)
+
+	// Right passwords are not counted, however many visitors share the link.
+	for i := 0; i < 20; i++ {
+		require.NoError(t, service.UnlockURL(ctx, url, "s3cret", "192.0.2.1"), "unlock %d", i+1)
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, service.UnlockURL(ctx, url, "guess", "198.51.100.7"), domain.ErrWrongPassword)
+	}
+
+	// The guesser is refused the right password too once its attempts are used up.
+	err = service.UnlockURL(ctx, url, "s3cret", "198.51.100.7")
+	require.ErrorIs(t, err, domain.ErrTooManyAttempts)
+	var attemptsErr *domain.AttemptsError
+	require.ErrorAs(t, err, &attemptsErr)
+	assert.Positive(t, attemptsErr.RetryAfter)
+
+	assert.NoError(t, service.UnlockURL(ctx, url, "s3cret", "192.0.2.1"), "other visitors are not locked out by a guesser")
+
+	other, err := service.CreateShortURL(ctx, "https://example.com/other", application.CreateOptions{Alias: "other-doc", Password: "hunter2"})
+	require.NoError(t, err)
+	assert.NoError(t, service.UnlockURL(ctx, other, "hunter2", "198.51.100.7"), "attempts are limited per link")
+
+	// A failing limiter refuses every attempt rather than allowing unlimited guesses.
+	failing := application.NewShortenerService(repo, new(MockShortCodeGenerator), application.WithPasswordAttemptLimiter(failingLimiter{}))
+	err = failing.UnlockURL(ctx, other, "hunter2", "192.0.2.1")
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NotErrorIs(t, err, domain.ErrWrongPassword)
+}
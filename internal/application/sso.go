@@ -0,0 +1,92 @@
+package application
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"url-shortener/internal/domain"
+)
+
+// SSOPolicy restricts who may log in through single sign-on. A user is let
+// in if their verified email is in one of AllowedDomains or they are in one
+// of AllowedGroups; with neither set, everyone the provider vouches for is.
+type SSOPolicy struct {
+	AllowedDomains []string
+	AllowedGroups  []string
+}
+
+func (p SSOPolicy) allows(identity *domain.ExternalIdentity) bool {
+	if len(p.AllowedDomains) == 0 && len(p.AllowedGroups) == 0 {
+		return true
+	}
+
+	if identity.EmailVerified {
+		_, emailDomain, _ := strings.Cut(identity.Email, "@")
+		for _, allowed := range p.AllowedDomains {
+			if strings.EqualFold(emailDomain, allowed) {
+				return true
+			}
+		}
+	}
+	for _, group := range identity.Groups {
+		if slices.Contains(p.AllowedGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// SSOFlow is what the browser keeps while the user is away at the provider.
+type SSOFlow struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+type SSOService struct {
+	provider domain.IdentityProvider
+	users    *UserService
+	policy   SSOPolicy
+}
+
+func NewSSOService(provider domain.IdentityProvider, users *UserService, policy SSOPolicy) *SSOService {
+	return &SSOService{
+		provider: provider,
+		users:    users,
+		policy:   policy,
+	}
+}
+
+// Begin starts a login, returning the flow to keep and the provider URL to
+// send the browser to.
+func (s *SSOService) Begin() (*SSOFlow, string, error) {
+	var flow SSOFlow
+	for _, v := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		var err error
+		if *v, err = randomString(32, base64.RawURLEncoding.EncodeToString); err != nil {
+			return nil, "", fmt.Errorf("failed to start sign-on: %w", err)
+		}
+	}
+	return &flow, s.provider.AuthCodeURL(flow.State, flow.Nonce, flow.Verifier), nil
+}
+
+// Complete finishes a login when the provider redirects back with state and
+// code. It returns ErrUnauthorized if state does not belong to flow, and
+// ErrAccessDenied if the policy keeps the user out.
+func (s *SSOService) Complete(ctx context.Context, flow *SSOFlow, state, code string) (*domain.Session, string, error) {
+	if flow == nil || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(flow.State)) != 1 {
+		return nil, "", domain.ErrUnauthorized
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, flow.Verifier, flow.Nonce)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to complete sign-on: %w", err)
+	}
+	if !s.policy.allows(identity) {
+		return nil, "", fmt.Errorf("%w: %s is not in an allowed domain or group", domain.ErrAccessDenied, identity.Email)
+	}
+	return s.users.LoginWithIdentity(ctx, identity)
+}
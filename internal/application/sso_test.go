@@ -0,0 +1,131 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://idp.example.com"
+
+// stubIdentityProvider hands out the identity registered for each code.
+type stubIdentityProvider struct {
+	identities map[string]*domain.ExternalIdentity
+	verifier   string
+	nonce      string
+}
+
+func (p *stubIdentityProvider) AuthCodeURL(state, nonce, verifier string) string {
+	p.verifier, p.nonce = verifier, nonce
+	return testIssuer + "/authorize?state=" + state
+}
+
+func (p *stubIdentityProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*domain.ExternalIdentity, error) {
+	if verifier != p.verifier || nonce != p.nonce {
+		return nil, errors.New("invalid_grant")
+	}
+	identity, ok := p.identities[code]
+	if !ok {
+		return nil, errors.New("invalid_grant")
+	}
+	return identity, nil
+}
+
+func TestSSOService_Complete(t *testing.T) {
+	ctx := context.Background()
+	users := newUserService()
+	provider := &stubIdentityProvider{identities: map[string]*domain.ExternalIdentity{
+		"ada":        {Issuer: testIssuer, Subject: "1", Email: "ada@example.com", EmailVerified: true},
+		"ada-moved":  {Issuer: testIssuer, Subject: "1", Email: "ada@elsewhere.org", EmailVerified: true},
+		"grace":      {Issuer: testIssuer, Subject: "2", Email: "Grace@Example.com", EmailVerified: true},
+		"unverified": {Issuer: testIssuer, Subject: "3", Email: "eve@example.com"},
+	}}
+	sso := application.NewSSOService(provider, users, application.SSOPolicy{})
+
+	login := func(code string) (*domain.User, error) {
+		flow, authURL, err := sso.Begin()
+		require.NoError(t, err)
+		assert.Contains(t, authURL, flow.State)
+
+		_, token, err := sso.Complete(ctx, flow, flow.State, code)
+		if err != nil {
+			return nil, err
+		}
+		return users.Authenticate(ctx, token)
+	}
+
+	ada, err := login("ada")
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", ada.Email)
+	assert.Empty(t, ada.PasswordHash, "SSO accounts have no password")
+
+	again, err := login("ada-moved")
+	require.NoError(t, err)
+	assert.Equal(t, ada.ID, again.ID, "the subject, not the email, identifies the user")
+
+	// Someone registered grace's address before she first used SSO.
+	squatted, err := users.Register(ctx, "grace@example.com", "correct horse")
+	require.NoError(t, err)
+	_, squatterToken, err := users.Login(ctx, "grace@example.com", "correct horse")
+	require.NoError(t, err)
+	grace, err := login("grace")
+	require.NoError(t, err)
+	assert.Equal(t, squatted.ID, grace.ID, "linked to the account with the verified email")
+	_, _, err = users.Login(ctx, "grace@example.com", "correct horse")
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials, "the unproven password is dropped")
+	_, err = users.Authenticate(ctx, squatterToken)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized, "and so are the sessions it started")
+
+	_, err = login("unverified")
+	assert.ErrorIs(t, err, domain.ErrAccessDenied)
+
+	flow, _, err := sso.Begin()
+	require.NoError(t, err)
+	_, _, err = sso.Complete(ctx, flow, "forged state", "ada")
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+	_, _, err = sso.Complete(ctx, nil, "", "ada")
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+}
+
+func TestSSOPolicy(t *testing.T) {
+	policy := application.SSOPolicy{
+		AllowedDomains: []string{"example.com"},
+		AllowedGroups:  []string{"url-shortener-users"},
+	}
+
+	tests := []struct {
+		name     string
+		identity domain.ExternalIdentity
+		allowed  bool
+	}{
+		{name: "allowed domain", identity: domain.ExternalIdentity{Email: "ada@Example.COM", EmailVerified: true}, allowed: true},
+		{name: "unverified email in allowed domain", identity: domain.ExternalIdentity{Email: "ada@example.com"}},
+		{name: "subdomain", identity: domain.ExternalIdentity{Email: "ada@evil.example.com", EmailVerified: true}},
+		{name: "other domain", identity: domain.ExternalIdentity{Email: "ada@example.org", EmailVerified: true}},
+		{name: "allowed group", identity: domain.ExternalIdentity{Email: "ada@example.org", EmailVerified: true, Groups: []string{"staff", "url-shortener-users"}}, allowed: true},
+		{name: "other groups", identity: domain.ExternalIdentity{Email: "ada@example.org", EmailVerified: true, Groups: []string{"staff"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := tt.identity
+			identity.Issuer, identity.Subject = testIssuer, tt.name
+			provider := &stubIdentityProvider{identities: map[string]*domain.ExternalIdentity{"code": &identity}}
+			sso := application.NewSSOService(provider, newUserService(), policy)
+
+			flow, _, err := sso.Begin()
+			require.NoError(t, err)
+			_, _, err = sso.Complete(context.Background(), flow, flow.State, "code")
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, domain.ErrAccessDenied)
+			}
+		})
+	}
+}
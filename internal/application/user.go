@@ -0,0 +1,267 @@
+package application
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"url-shortener/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	DefaultSessionTTL = 7 * 24 * time.Hour
+
+	MinPasswordLength = 8
+	// MaxPasswordLength is the most bcrypt looks at.
+	MaxPasswordLength = 72
+)
+
+type UserService struct {
+	users      domain.UserRepository
+	sessions   domain.SessionRepository
+	sessionTTL time.Duration
+	cost       int
+
+	// dummyHash is compared against when an email is unknown, so that a
+	// failed login takes as long whether or not the account exists.
+	dummyHash []byte
+}
+
+type UserServiceOption func(*UserService)
+
+// WithSessionTTL sets how long a login lasts.
+func WithSessionTTL(ttl time.Duration) UserServiceOption {
+	return func(s *UserService) {
+		s.sessionTTL = ttl
+	}
+}
+
+// WithPasswordCost sets the bcrypt cost of new password hashes.
+func WithPasswordCost(cost int) UserServiceOption {
+	return func(s *UserService) {
+		s.cost = cost
+	}
+}
+
+func NewUserService(users domain.UserRepository, sessions domain.SessionRepository, opts ...UserServiceOption) *UserService {
+	s := &UserService{
+		users:      users,
+		sessions:   sessions,
+		sessionTTL: DefaultSessionTTL,
+		cost:       bcrypt.DefaultCost,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), s.cost)
+	return s
+}
+
+// Register creates an account. Emails are compared case-insensitively.
+func (s *UserService) Register(ctx context.Context, email, password string) (*domain.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if len(password) < MinPasswordLength {
+		return nil, fmt.Errorf("%w: use at least %d characters", domain.ErrWeakPassword, MinPasswordLength)
+	}
+	if len(password) > MaxPasswordLength {
+		return nil, fmt.Errorf("%w: use at most %d bytes", domain.ErrWeakPassword, MaxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate user id: %w", err)
+	}
+
+	user := &domain.User{
+		ID:           id,
+		Email:        email,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now(),
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		if errors.Is(err, domain.ErrUserExists) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+	return user, nil
+}
+
+// Login checks a user's password and starts a session for them. Unknown
+// emails and wrong passwords both return ErrInvalidCredentials.
+func (s *UserService) Login(ctx context.Context, email, password string) (*domain.Session, string, error) {
+	user, err := s.users.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if errors.Is(err, domain.ErrUserNotFound) {
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, "", domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look up user: %w", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, "", domain.ErrInvalidCredentials
+	}
+
+	return s.StartSession(ctx, user.ID)
+}
+
+// LoginWithIdentity logs in the user an SSO identity is linked to. On an
+// identity's first login it is linked to the account with its verified
+// email, or to a new account without a password.
+func (s *UserService) LoginWithIdentity(ctx context.Context, identity *domain.ExternalIdentity) (*domain.Session, string, error) {
+	user, err := s.users.FindByIdentity(ctx, identity.Issuer, identity.Subject)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		user, err = s.linkIdentity(ctx, identity)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return s.StartSession(ctx, user.ID)
+}
+
+func (s *UserService) linkIdentity(ctx context.Context, identity *domain.ExternalIdentity) (*domain.User, error) {
+	if !identity.EmailVerified {
+		return nil, fmt.Errorf("%w: the provider has not verified the email address", domain.ErrAccessDenied)
+	}
+	email, err := normalizeEmail(identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		// Registering does not prove an email address is yours, so whoever
+		// set the password may not be the person the provider vouches for.
+		// Their sessions go with the password.
+		if user.PasswordHash != "" {
+			if err := s.users.SetPasswordHash(ctx, user.ID, ""); err != nil {
+				return nil, fmt.Errorf("failed to drop password: %w", err)
+			}
+			if err := s.sessions.DeleteByUser(ctx, user.ID); err != nil {
+				return nil, fmt.Errorf("failed to end sessions: %w", err)
+			}
+		}
+	case errors.Is(err, domain.ErrUserNotFound):
+		id, err := randomString(8, hex.EncodeToString)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate user id: %w", err)
+		}
+		user = &domain.User{ID: id, Email: email, CreatedAt: time.Now()}
+		if err := s.users.Create(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to save user: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	err = s.users.LinkIdentity(ctx, identity.Issuer, identity.Subject, user.ID)
+	if errors.Is(err, domain.ErrUserExists) {
+		// A concurrent first login linked it already.
+		return s.users.FindByIdentity(ctx, identity.Issuer, identity.Subject)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	return user, nil
+}
+
+// StartSession logs a user in, returning the session and the token for its
+// cookie. The token itself is not stored.
+func (s *UserService) StartSession(ctx context.Context, userID string) (*domain.Session, string, error) {
+	token, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+
+	now := time.Now()
+	session := &domain.Session{
+		Hash:      hashSecret(token),
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionTTL),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, "", fmt.Errorf("failed to save session: %w", err)
+	}
+	return session, token, nil
+}
+
+// Authenticate returns the user logged in with token, or ErrUnauthorized.
+func (s *UserService) Authenticate(ctx context.Context, token string) (*domain.User, error) {
+	if token == "" {
+		return nil, domain.ErrUnauthorized
+	}
+
+	session, err := s.sessions.Find(ctx, hashSecret(token))
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		return nil, domain.ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	user, err := s.users.FindByID(ctx, session.UserID)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, domain.ErrUnauthorized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	return user, nil
+}
+
+// SetRole changes the role of the user with email. Sessions pick up the
+// new role on their next request.
+func (s *UserService) SetRole(ctx context.Context, email string, role domain.Role) (*domain.User, error) {
+	if _, err := domain.ParseRole(string(role)); err != nil {
+		return nil, err
+	}
+	user, err := s.users.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	if err := s.users.SetRole(ctx, user.ID, role); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	user.Role = role
+	return user, nil
+}
+
+// Logout ends the session of token.
+func (s *UserService) Logout(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	if err := s.sessions.Delete(ctx, hashSecret(token)); err != nil {
+		return fmt.Errorf("failed to end session: %w", err)
+	}
+	return nil
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	// Reject display names such as "Ada <ada@example.com>".
+	if err != nil || addr.Address != email {
+		return "", domain.ErrInvalidEmail
+	}
+	return strings.ToLower(email), nil
+}
@@ -0,0 +1,121 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/application"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newUserService(opts ...application.UserServiceOption) *application.UserService {
+	opts = append([]application.UserServiceOption{application.WithPasswordCost(bcrypt.MinCost)}, opts...)
+	return application.NewUserService(repository.NewMemoryUserRepository(), repository.NewMemorySessionRepository(), opts...)
+}
+
+func TestUserService_Register(t *testing.T) {
+	ctx := context.Background()
+	service := newUserService()
+
+	user, err := service.Register(ctx, " Ada@Example.com ", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", user.Email)
+	assert.NotEmpty(t, user.ID)
+	assert.NotContains(t, user.PasswordHash, "correct horse")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("correct horse")))
+
+	_, err = service.Register(ctx, "ADA@example.com", "another password")
+	assert.ErrorIs(t, err, domain.ErrUserExists)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{name: "missing at sign", email: "ada.example.com", password: "correct horse", wantErr: domain.ErrInvalidEmail},
+		{name: "display name", email: "Ada <ada@example.org>", password: "correct horse", wantErr: domain.ErrInvalidEmail},
+		{name: "short password", email: "ada@example.org", password: "short", wantErr: domain.ErrWeakPassword},
+		{name: "long password", email: "ada@example.org", password: string(make([]byte, 73)), wantErr: domain.ErrWeakPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Register(ctx, tt.email, tt.password)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUserService_Sessions(t *testing.T) {
+	ctx := context.Background()
+	service := newUserService(application.WithSessionTTL(time.Hour))
+
+	user, err := service.Register(ctx, "ada@example.com", "correct horse")
+	require.NoError(t, err)
+
+	_, _, err = service.Login(ctx, "ada@example.com", "wrong horse")
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	_, _, err = service.Login(ctx, "grace@example.com", "correct horse")
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+
+	session, token, err := service.Login(ctx, "ADA@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, session.UserID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), session.ExpiresAt, time.Minute)
+	assert.NotEqual(t, token, session.Hash, "only a hash of the token is stored")
+
+	got, err := service.Authenticate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+
+	_, err = service.Authenticate(ctx, "forged")
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+	_, err = service.Authenticate(ctx, "")
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+
+	require.NoError(t, service.Logout(ctx, token))
+	_, err = service.Authenticate(ctx, token)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+}
+
+func TestUserService_ExpiredSession(t *testing.T) {
+	ctx := context.Background()
+	service := newUserService(application.WithSessionTTL(-time.Second))
+
+	user, err := service.Register(ctx, "ada@example.com", "correct horse")
+	require.NoError(t, err)
+	_, token, err := service.StartSession(ctx, user.ID)
+	require.NoError(t, err)
+
+	_, err = service.Authenticate(ctx, token)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+}
+
+func TestUserService_SetRole(t *testing.T) {
+	ctx := context.Background()
+	service := newUserService()
+
+	user, err := service.Register(ctx, "ada@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Empty(t, user.Role)
+	_, token, err := service.StartSession(ctx, user.ID)
+	require.NoError(t, err)
+
+	updated, err := service.SetRole(ctx, "Ada@Example.com", domain.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleAdmin, updated.Role)
+
+	got, err := service.Authenticate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleAdmin, got.Role, "existing sessions see the new role")
+
+	_, err = service.SetRole(ctx, "ada@example.com", "owner")
+	assert.ErrorIs(t, err, domain.ErrInvalidRole)
+	_, err = service.SetRole(ctx, "grace@example.com", domain.RoleViewer)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidStatsQuery = errors.New("invalid stats query")
+
+type Click struct {
+	ShortCode string
+	Timestamp time.Time
+	Referrer  string
+	UserAgent string
+	Country   string // reserved for geo lookup, empty until one is configured
+}
+
+type ClickBucket struct {
+	Start time.Time
+	Count int64
+}
+
+type ClickStats struct {
+	ShortCode string
+	Total     int64 // all clicks recorded for the link since it was created
+	Buckets   []ClickBucket
+}
+
+// ClickRecorder accepts clicks from the redirect path. Implementations must
+// not block on storage.
+type ClickRecorder interface {
+	Record(click *Click)
+}
+
+type ClickRepository interface {
+	SaveClicks(ctx context.Context, clicks []*Click) error
+	// Stats returns the link's total and the non-empty buckets of the given
+	// width covering [from, to). Bucket starts are from + n*interval. Clicks
+	// before since are left out: they belong to an earlier link that held
+	// the same short code.
+	Stats(ctx context.Context, shortCode string, since, from, to time.Time, interval time.Duration) (*ClickStats, error)
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrUnauthorized   = errors.New("unauthorized")
+)
+
+// APIKey authenticates API clients. Only a hash of the secret key is kept;
+// the key itself is shown once, when it is issued.
+type APIKey struct {
+	ID        string
+	Name      string
+	OwnerID   string // owner of the links created with the key
+	Role      Role   // empty for keys issued before roles, which act as creators
+	Hash      string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	// FindByHash returns the key with the given hash, revoked or not, or
+	// ErrAPIKeyNotFound.
+	FindByHash(ctx context.Context, hash string) (*APIKey, error)
+	List(ctx context.Context) ([]*APIKey, error)
+	// Revoke marks a key as revoked, returning ErrAPIKeyNotFound if there is
+	// none.
+	Revoke(ctx context.Context, id string, revokedAt time.Time) error
+}
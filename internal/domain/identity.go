@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrAccessDenied = errors.New("access denied")
+
+// ExternalIdentity is a user as vouched for by a single sign-on provider.
+// Issuer and Subject identify the user for good; the email may change.
+type ExternalIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// IdentityProvider signs users in with an OpenID Connect authorization code
+// flow. state, nonce and the PKCE verifier are chosen by the caller and kept
+// by the browser between the two steps.
+type IdentityProvider interface {
+	// AuthCodeURL is where the browser is sent to log in.
+	AuthCodeURL(state, nonce, verifier string) string
+	// Exchange redeems the code the provider redirected back with and
+	// returns the identity from the verified ID token.
+	Exchange(ctx context.Context, code, verifier, nonce string) (*ExternalIdentity, error)
+}
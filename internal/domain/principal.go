@@ -0,0 +1,37 @@
+package domain
+
+import "context"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	OwnerID  string // owns the links the caller creates
+	APIKeyID string // set when the caller used an API key
+	UserID   string // set when the caller logged in to the web UI
+	Email    string // the logged in user's email, for display
+
+	// Role limits what the caller may do; empty means RoleCreator, which is
+	// what keys issued before roles existed get.
+	Role Role
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// EffectiveRole returns the caller's role. Anonymous callers are creators,
+// as they were before accounts existed; routes that should not be open to
+// them require authentication instead.
+func (p *Principal) EffectiveRole() Role {
+	if p == nil || p.Role == "" {
+		return RoleCreator
+	}
+	return p.Role
+}
+
+// PrincipalFromContext returns the caller, or nil for anonymous requests.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
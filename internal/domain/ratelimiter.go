@@ -1,7 +1,26 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// RateLimitResult describes a client's quota after a request was counted.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int           // requests allowed per window
+	Remaining  int           // requests left right now
+	Reset      time.Duration // until the full quota is available again
+	RetryAfter time.Duration // until the next request would be allowed; zero when Remaining > 0
+}
 
 type RateLimiter interface {
 	Allow(ctx context.Context, identifier string) (bool, error)
+
+	// Take decides on a request like Allow and reports the quota left.
+	Take(ctx context.Context, identifier string) (*RateLimitResult, error)
+
+	// Peek reports whether a request would be allowed and the quota left,
+	// without counting one.
+	Peek(ctx context.Context, identifier string) (*RateLimitResult, error)
 }
@@ -1,9 +1,36 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+type ListOptions struct {
+	Limit   int
+	Offset  int
+	OwnerID string // only list this owner's links; empty lists all
+}
 
 type URLRepository interface {
+	// Create stores url only if its short code is free, returning
+	// ErrShortCodeExists otherwise. The check and insert happen atomically.
+	Create(ctx context.Context, url *URL) error
 	Save(ctx context.Context, url *URL) error
 	FindByShortCode(ctx context.Context, shortCode string) (*URL, error)
+	// FindByLongURL returns a live, unexpired link whose destination has the
+	// same CanonicalURL as longURL, or ErrURLNotFound. Implementations may
+	// only track the most recently stored link for each destination.
+	FindByLongURL(ctx context.Context, longURL string) (*URL, error)
+	// Exists reports whether shortCode is taken, including by a tombstone.
 	Exists(ctx context.Context, shortCode string) (bool, error)
+	List(ctx context.Context, opts ListOptions) ([]*URL, error)
+	// Update changes the destination and redirect status of a live link,
+	// returning ErrURLNotFound if there is none.
+	Update(ctx context.Context, url *URL) error
+	// Moderate changes the owner and disabled state of a live link,
+	// returning ErrURLNotFound if there is none.
+	Moderate(ctx context.Context, url *URL) error
+	// Delete replaces a live link with a tombstone that keeps its short code
+	// reserved until tombstoneUntil, returning ErrURLNotFound if there is none.
+	Delete(ctx context.Context, shortCode string, tombstoneUntil time.Time) error
 }
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidRole = errors.New("invalid role")
+
+// Role decides what a caller may do with links.
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"  // may only list their links
+	RoleCreator Role = "creator" // may also create links and manage their own
+	RoleAdmin   Role = "admin"   // may manage every link
+)
+
+// Action is an operation roles are checked against.
+type Action string
+
+const (
+	ActionViewLinks   Action = "view links"
+	ActionCreateLinks Action = "create links"
+	ActionManageLinks Action = "manage links"
+	ActionAdminLinks  Action = "administer links"
+)
+
+var rolePermissions = map[Role][]Action{
+	RoleViewer:  {ActionViewLinks},
+	RoleCreator: {ActionViewLinks, ActionCreateLinks, ActionManageLinks},
+	RoleAdmin:   {ActionViewLinks, ActionCreateLinks, ActionManageLinks, ActionAdminLinks},
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("%w %q: use %s, %s or %s", ErrInvalidRole, s, RoleViewer, RoleCreator, RoleAdmin)
+	}
+	return role, nil
+}
+
+// Can reports whether the role allows action.
+func (r Role) Can(action Action) bool {
+	for _, a := range rolePermissions[r] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionError is returned when a caller may not perform an action. It
+// matches ErrForbidden, so code that only cares whether access was denied
+// need not know about roles.
+type PermissionError struct {
+	Role   Role
+	Action Action
+	// ShortCode is set when the role allows the action, just not on this
+	// link, which only its owner or a holder of its manage token may change.
+	ShortCode string
+}
+
+func (e *PermissionError) Error() string {
+	if e.ShortCode != "" {
+		return fmt.Sprintf("%s may not %s: %s is not theirs and no valid manage token was given", e.Role, e.Action, e.ShortCode)
+	}
+	return fmt.Sprintf("%s may not %s", e.Role, e.Action)
+}
+
+func (e *PermissionError) Is(target error) bool {
+	return target == ErrForbidden
+}
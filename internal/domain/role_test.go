@@ -0,0 +1,51 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+	"url-shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRole_Can(t *testing.T) {
+	actions := []domain.Action{domain.ActionViewLinks, domain.ActionCreateLinks, domain.ActionManageLinks, domain.ActionAdminLinks}
+	matrix := map[domain.Role][]bool{
+		domain.RoleViewer:   {true, false, false, false},
+		domain.RoleCreator:  {true, true, true, false},
+		domain.RoleAdmin:    {true, true, true, true},
+		domain.Role("root"): {false, false, false, false},
+	}
+
+	for role, allowed := range matrix {
+		for i, action := range actions {
+			assert.Equal(t, allowed[i], role.Can(action), "%s may %s", role, action)
+		}
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := domain.ParseRole("admin")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleAdmin, role)
+
+	_, err = domain.ParseRole("Admin")
+	assert.ErrorIs(t, err, domain.ErrInvalidRole)
+	_, err = domain.ParseRole("")
+	assert.ErrorIs(t, err, domain.ErrInvalidRole)
+}
+
+func TestPermissionError(t *testing.T) {
+	var err error = &domain.PermissionError{Role: domain.RoleViewer, Action: domain.ActionCreateLinks}
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	assert.EqualError(t, err, "viewer may not create links")
+
+	var perr *domain.PermissionError
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, domain.RoleViewer, perr.Role)
+
+	var anonymous *domain.Principal
+	assert.Equal(t, domain.RoleCreator, anonymous.EffectiveRole())
+	assert.Equal(t, domain.RoleViewer, (&domain.Principal{Role: domain.RoleViewer}).EffectiveRole())
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrUnsafeURL = errors.New("destination flagged as unsafe")
+
+type Threat string
+
+const (
+	ThreatPhishing Threat = "phishing"
+	ThreatMalware  Threat = "malware"
+	ThreatUnwanted Threat = "unwanted" // deceptive or unwanted software
+)
+
+type ScanResult struct {
+	Threat Threat // empty when nothing was found
+	Detail string // optional explanation from the scanner
+}
+
+// Flagged reports whether the scanned URL should not be visited.
+func (r *ScanResult) Flagged() bool {
+	return r != nil && r.Threat != ""
+}
+
+// URLScanner checks destinations against a source of known phishing and
+// malware sites.
+type URLScanner interface {
+	Scan(ctx context.Context, rawURL string) (*ScanResult, error)
+}
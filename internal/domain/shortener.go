@@ -1,5 +1,29 @@
 package domain
 
+import "context"
+
 type ShortCodeGenerator interface {
 	Generate() string
 }
+
+// AdaptiveShortCodeGenerator is told whether each generated code was free,
+// so it can react to a crowded code space, for example by growing the code
+// length.
+type AdaptiveShortCodeGenerator interface {
+	ShortCodeGenerator
+	Observe(collided bool)
+}
+
+// ContextShortCodeGenerator is implemented by generators that need storage to
+// produce a code; callers should prefer GenerateContext over Generate so
+// failures surface as errors.
+type ContextShortCodeGenerator interface {
+	ShortCodeGenerator
+	GenerateContext(ctx context.Context) (string, error)
+}
+
+// IDSequence hands out increasing IDs, starting at 1. An ID is never handed
+// out twice, even across restarts.
+type IDSequence interface {
+	NextID(ctx context.Context) (uint64, error)
+}
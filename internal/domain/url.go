@@ -2,6 +2,11 @@ package domain
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -9,14 +14,76 @@ var (
 	ErrURLNotFound     = errors.New("url not found")
 	ErrInvalidURL      = errors.New("invalid url")
 	ErrShortCodeExists = errors.New("short code already exists")
+	ErrInvalidAlias    = errors.New("invalid alias")
+	ErrInvalidExpiry   = errors.New("invalid expiry")
+	ErrForbidden       = errors.New("forbidden")
+	ErrInvalidRedirect = errors.New("invalid redirect status")
+	ErrDisallowedURL   = errors.New("destination not allowed")
+
+	ErrInvalidLinkPassword = errors.New("invalid link password")
+	ErrWrongPassword       = errors.New("wrong password")
+	ErrTooManyAttempts     = errors.New("too many password attempts")
+
+	// ErrShortCodeUnavailable means no free code could be generated; unlike
+	// ErrShortCodeExists it is not caused by the caller's choice of alias.
+	ErrShortCodeUnavailable = errors.New("no short code available")
 )
 
+// ValidRedirectStatus reports whether code may be used to redirect visitors.
+func ValidRedirectStatus(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// PermanentRedirect reports whether browsers may cache code indefinitely.
+func PermanentRedirect(code int) bool {
+	return code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect
+}
+
+// AttemptsError is returned when a link's password was tried too often. It
+// matches ErrTooManyAttempts.
+type AttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("%v, retry in %v", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *AttemptsError) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
 type URL struct {
 	ID        string
 	ShortCode string
 	LongURL   string
 	CreatedAt time.Time
 	ExpiresAt *time.Time
+	DeletedAt *time.Time // set on tombstones, which repositories never return
+
+	// RedirectStatus is the HTTP status used to redirect visitors; zero means
+	// the service-wide default.
+	RedirectStatus int
+
+	// OwnerID identifies whoever created the link while authenticated; empty
+	// for anonymous links.
+	OwnerID string
+
+	// DisabledAt is set while an admin has taken the link down. Unlike a
+	// deleted link it stays visible to its owner and can be restored.
+	DisabledAt *time.Time
+
+	// PasswordHash is the bcrypt hash of the password visitors must enter
+	// before being redirected; empty for links anyone may follow.
+	PasswordHash string
+
+	// Reused is set, and never stored, when creating a link returned an
+	// existing link for the same destination instead.
+	Reused bool
 }
 
 func (u *URL) IsExpired() bool {
@@ -26,12 +93,74 @@ func (u *URL) IsExpired() bool {
 	return time.Now().After(*u.ExpiresAt)
 }
 
+func (u *URL) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *URL) IsProtected() bool {
+	return u.PasswordHash != ""
+}
+
 func (u *URL) Validate() error {
 	if u.LongURL == "" {
 		return ErrInvalidURL
 	}
+	if _, err := url.ParseRequestURI(u.LongURL); err != nil {
+		return ErrInvalidURL
+	}
 	if u.ShortCode == "" {
 		return ErrInvalidURL
 	}
+	if u.RedirectStatus != 0 && !ValidRedirectStatus(u.RedirectStatus) {
+		return ErrInvalidRedirect
+	}
 	return nil
 }
+
+// CanonicalURL normalizes rawURL so that URLs which differ only in scheme or
+// host case, an explicit default port, a trailing slash or the order of query
+// parameters compare equal.
+func CanonicalURL(rawURL string) (string, error) {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if host, port, err := net.SplitHostPort(u.Host); err == nil &&
+		(u.Scheme == "http" && port == "80" || u.Scheme == "https" && port == "443") {
+		u.Host = host
+		if strings.Contains(host, ":") {
+			u.Host = "[" + host + "]"
+		}
+	}
+
+	// Work on the escaped path so an encoded "/" stays distinct from a real one.
+	path := u.EscapedPath()
+	if trimmed := strings.TrimRight(path, "/"); trimmed != "" {
+		path = trimmed
+	} else {
+		path = "/"
+	}
+
+	var b strings.Builder
+	b.WriteString(u.Scheme)
+	b.WriteString("://")
+	if u.User != nil {
+		b.WriteString(u.User.String())
+		b.WriteByte('@')
+	}
+	b.WriteString(u.Host)
+	b.WriteString(path)
+	if u.RawQuery != "" {
+		// Encode sorts by key and keeps the order of repeated keys.
+		b.WriteByte('?')
+		b.WriteString(u.Query().Encode())
+	}
+	if u.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(u.EscapedFragment())
+	}
+	return b.String(), nil
+}
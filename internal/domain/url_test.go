@@ -30,6 +30,14 @@ func TestURL_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "malformed long URL",
+			url: &domain.URL{
+				ShortCode: "abc123",
+				LongURL:   "not-a-url",
+			},
+			wantErr: true,
+		},
 		{
 			name: "empty short code",
 			url: &domain.URL{
@@ -38,6 +46,24 @@ func TestURL_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "explicit redirect status",
+			url: &domain.URL{
+				ShortCode:      "abc123",
+				LongURL:        "https://example.com",
+				RedirectStatus: 308,
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported redirect status",
+			url: &domain.URL{
+				ShortCode:      "abc123",
+				LongURL:        "https://example.com",
+				RedirectStatus: 303,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -52,6 +78,20 @@ func TestURL_Validate(t *testing.T) {
 	}
 }
 
+func TestValidRedirectStatus(t *testing.T) {
+	for _, code := range []int{301, 302, 307, 308} {
+		assert.True(t, domain.ValidRedirectStatus(code), code)
+	}
+	for _, code := range []int{0, 200, 300, 303, 304, 404} {
+		assert.False(t, domain.ValidRedirectStatus(code), code)
+	}
+
+	assert.True(t, domain.PermanentRedirect(301))
+	assert.True(t, domain.PermanentRedirect(308))
+	assert.False(t, domain.PermanentRedirect(302))
+	assert.False(t, domain.PermanentRedirect(307))
+}
+
 func TestURL_IsExpired(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -94,3 +134,44 @@ func TestURL_IsExpired(t *testing.T) {
 		})
 	}
 }
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{"scheme and host case", "HTTPS://Example.COM/Path", "https://example.com/Path", true},
+		{"path case matters", "https://example.com/Path", "https://example.com/path", false},
+		{"default https port", "https://example.com:443/a", "https://example.com/a", true},
+		{"default http port", "http://example.com:80/a", "http://example.com/a", true},
+		{"non-default port", "https://example.com:8443/a", "https://example.com/a", false},
+		{"port of the other scheme", "http://example.com:443/a", "http://example.com/a", false},
+		{"ipv6 default port", "http://[::1]:80/a", "http://[::1]/a", true},
+		{"trailing slash", "https://example.com/a/", "https://example.com/a", true},
+		{"empty path", "https://example.com", "https://example.com/", true},
+		{"query order", "https://example.com/?b=2&a=1", "https://example.com/?a=1&b=2", true},
+		{"repeated key order matters", "https://example.com/?a=1&a=2", "https://example.com/?a=2&a=1", false},
+		{"escaped slash", "https://example.com/a%2Fb", "https://example.com/a/b", false},
+		{"fragment", "https://example.com/#top", "https://example.com/", false},
+		{"scheme", "http://example.com/", "https://example.com/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := domain.CanonicalURL(tt.a)
+			assert.NoError(t, err)
+			b, err := domain.CanonicalURL(tt.b)
+			assert.NoError(t, err)
+
+			if tt.same {
+				assert.Equal(t, a, b)
+			} else {
+				assert.NotEqual(t, a, b)
+			}
+		})
+	}
+
+	_, err := domain.CanonicalURL("not a url")
+	assert.ErrorIs(t, err, domain.ErrInvalidURL)
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrWeakPassword       = errors.New("password is too weak")
+	ErrInvalidEmail       = errors.New("invalid email address")
+	ErrSessionNotFound    = errors.New("session not found")
+)
+
+// User is a local account for the web UI. Emails are stored lowercased.
+type User struct {
+	ID           string
+	Email        string
+	PasswordHash string // empty for users who only log in through SSO
+	Role         Role   // empty for accounts created before roles, which act as creators
+	CreatedAt    time.Time
+}
+
+type UserRepository interface {
+	// Create returns ErrUserExists if the email is taken.
+	Create(ctx context.Context, user *User) error
+	FindByID(ctx context.Context, id string) (*User, error)
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	// SetPasswordHash replaces a user's password hash, returning
+	// ErrUserNotFound if there is no such user.
+	SetPasswordHash(ctx context.Context, id, hash string) error
+	// SetRole changes a user's role, returning ErrUserNotFound if there is no
+	// such user.
+	SetRole(ctx context.Context, id string, role Role) error
+
+	// LinkIdentity lets the identity log in as the user. It returns
+	// ErrUserExists if the identity is already linked.
+	LinkIdentity(ctx context.Context, issuer, subject, userID string) error
+	// FindByIdentity returns the user an identity is linked to.
+	FindByIdentity(ctx context.Context, issuer, subject string) (*User, error)
+}
+
+// Session is a browser login. Like API keys, only a hash of the token in the
+// cookie is kept.
+type Session struct {
+	Hash      string
+	UserID    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *Session) error
+	// Find returns ErrSessionNotFound for unknown and expired sessions.
+	Find(ctx context.Context, hash string) (*Session, error)
+	// Delete ends a session; deleting an unknown one is not an error.
+	Delete(ctx context.Context, hash string) error
+	// DeleteByUser ends every session of a user.
+	DeleteByUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,6 @@
+package generator
+
+var (
+	Adjectives = adjectives
+	Animals    = animals
+)
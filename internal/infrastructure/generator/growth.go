@@ -0,0 +1,38 @@
+package generator
+
+import "sync"
+
+// lengthGrowth tracks consecutive collisions and lengthens codes once they
+// reach the threshold, for generators implementing
+// domain.AdaptiveShortCodeGenerator.
+type lengthGrowth struct {
+	mu         sync.Mutex
+	length     int
+	maxLength  int
+	threshold  int
+	collisions int
+}
+
+func (g *lengthGrowth) current() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.length
+}
+
+// Observe counts consecutive collisions and grows the code length once they
+// reach the threshold.
+func (g *lengthGrowth) Observe(collided bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !collided {
+		g.collisions = 0
+		return
+	}
+
+	g.collisions++
+	if g.collisions >= g.threshold && g.length < g.maxLength {
+		g.length++
+		g.collisions = 0
+	}
+}
@@ -2,36 +2,127 @@ package generator
 
 import (
 	"crypto/rand"
-	"encoding/base64"
+	"errors"
+	"fmt"
 	"url-shortener/internal/domain"
 )
 
 const (
-	ShortCodeLength = 3 // length of the generated short code
+	ShortCodeLength           = 6  // default length of the generated short code
+	DefaultMaxLength          = 12 // default length codes stop growing at
+	DefaultCollisionThreshold = 3  // default consecutive collisions before growing
+	DefaultAlphabet           = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// ReadableAlphabet leaves out uppercase letters and the characters that
+	// are easily confused when read aloud or handwritten: 0/o, 1/l/i, - and _.
+	ReadableAlphabet = "23456789abcdefghjkmnpqrstuvwxyz"
 )
 
-type RandomShortCodeGenerator struct{}
+var ErrInvalidConfig = errors.New("invalid generator config")
+
+type RandomConfig struct {
+	Length             int    // initial code length
+	MaxLength          int    // codes never grow past this length
+	Alphabet           string // characters codes are drawn from
+	CollisionThreshold int    // consecutive collisions that trigger a longer code
+}
 
-func NewRandomShortCodeGenerator() domain.ShortCodeGenerator {
-	return &RandomShortCodeGenerator{}
+// RandomShortCodeGenerator draws each character uniformly from its alphabet.
+// After CollisionThreshold consecutive collisions it assumes the space at the
+// current length is getting crowded and starts generating longer codes.
+type RandomShortCodeGenerator struct {
+	lengthGrowth
+	alphabet string
+}
+
+func NewRandomShortCodeGenerator(cfg RandomConfig) (domain.ShortCodeGenerator, error) {
+	if cfg.Length == 0 {
+		cfg.Length = ShortCodeLength
+	}
+	if cfg.MaxLength == 0 {
+		cfg.MaxLength = max(DefaultMaxLength, cfg.Length)
+	}
+	if cfg.Alphabet == "" {
+		cfg.Alphabet = DefaultAlphabet
+	}
+	if cfg.CollisionThreshold == 0 {
+		cfg.CollisionThreshold = DefaultCollisionThreshold
+	}
+
+	if err := validateAlphabet(cfg.Alphabet); err != nil {
+		return nil, err
+	}
+	if cfg.Length < 1 || cfg.MaxLength < cfg.Length {
+		return nil, fmt.Errorf("%w: length must be at least 1 and no more than the max length", ErrInvalidConfig)
+	}
+	if cfg.CollisionThreshold < 1 {
+		return nil, fmt.Errorf("%w: collision threshold must be positive", ErrInvalidConfig)
+	}
+
+	return &RandomShortCodeGenerator{
+		lengthGrowth: lengthGrowth{
+			length:    cfg.Length,
+			maxLength: cfg.MaxLength,
+			threshold: cfg.CollisionThreshold,
+		},
+		alphabet: cfg.Alphabet,
+	}, nil
 }
 
 func (g *RandomShortCodeGenerator) Generate() string {
-	bytes := make([]byte, ShortCodeLength)
-	rand.Read(bytes)
-	code := base64.URLEncoding.EncodeToString(bytes)[:ShortCodeLength]
-	code = sanitizeCode(code)
-	return code
-}
-
-func sanitizeCode(code string) string {
-	result := make([]rune, 0, len(code))
-	for _, r := range code {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
-			result = append(result, r)
-		} else {
-			result = append(result, 'x')
+	return randomString(g.alphabet, g.current())
+}
+
+// Length returns the length of the codes currently generated.
+func (g *RandomShortCodeGenerator) Length() int {
+	return g.current()
+}
+
+// randomString picks length characters from alphabet without modulo bias by
+// rejecting random bytes past the largest multiple of the alphabet size.
+func randomString(alphabet string, length int) string {
+	n := len(alphabet)
+	limit := 256 - 256%n
+
+	code := make([]byte, 0, length)
+	buf := make([]byte, length*2)
+	for len(code) < length {
+		if _, err := rand.Read(buf); err != nil {
+			panic("failed to read random bytes: " + err.Error())
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, alphabet[int(b)%n])
+			if len(code) == length {
+				break
+			}
+		}
+	}
+	return string(code)
+}
+
+// validateAlphabet accepts between 2 and 256 distinct characters that are
+// safe in a URL path without escaping.
+func validateAlphabet(alphabet string) error {
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return fmt.Errorf("%w: alphabet must have between 2 and 256 characters", ErrInvalidConfig)
+	}
+
+	seen := make(map[rune]bool, len(alphabet))
+	for _, r := range alphabet {
+		if !isURLSafe(r) {
+			return fmt.Errorf("%w: alphabet character %q is not URL-safe", ErrInvalidConfig, r)
 		}
+		if seen[r] {
+			return fmt.Errorf("%w: alphabet character %q is repeated", ErrInvalidConfig, r)
+		}
+		seen[r] = true
 	}
-	return string(result)
+	return nil
+}
+
+func isURLSafe(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
 }
@@ -2,20 +2,51 @@ package generator_test
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 	"url-shortener/internal/infrastructure/generator"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func newGenerator(t *testing.T, cfg generator.RandomConfig) *generator.RandomShortCodeGenerator {
+	t.Helper()
+	gen, err := generator.NewRandomShortCodeGenerator(cfg)
+	require.NoError(t, err)
+	return gen.(*generator.RandomShortCodeGenerator)
+}
+
 func TestNewRandomShortCodeGenerator(t *testing.T) {
-	gen := generator.NewRandomShortCodeGenerator()
+	gen, err := generator.NewRandomShortCodeGenerator(generator.RandomConfig{})
 
+	require.NoError(t, err)
 	assert.NotNil(t, gen)
 }
 
+func TestNewRandomShortCodeGenerator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  generator.RandomConfig
+	}{
+		{"single character alphabet", generator.RandomConfig{Alphabet: "a"}},
+		{"repeated character", generator.RandomConfig{Alphabet: "abca"}},
+		{"unsafe character", generator.RandomConfig{Alphabet: "ab/c"}},
+		{"negative length", generator.RandomConfig{Length: -1}},
+		{"max below length", generator.RandomConfig{Length: 8, MaxLength: 6}},
+		{"negative threshold", generator.RandomConfig{CollisionThreshold: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generator.NewRandomShortCodeGenerator(tt.cfg)
+			assert.ErrorIs(t, err, generator.ErrInvalidConfig)
+		})
+	}
+}
+
 func TestRandomShortCodeGenerator_Generate(t *testing.T) {
-	gen := generator.NewRandomShortCodeGenerator()
+	gen := newGenerator(t, generator.RandomConfig{})
 
 	// Generate multiple codes to ensure randomness
 	codes := make(map[string]bool)
@@ -37,33 +68,59 @@ func TestRandomShortCodeGenerator_Generate(t *testing.T) {
 	assert.Greater(t, len(codes), 50, "Should generate mostly unique codes")
 }
 
-func TestSanitizeCode(t *testing.T) {
-	gen := generator.NewRandomShortCodeGenerator()
+func TestRandomShortCodeGenerator_Alphabet(t *testing.T) {
+	gen := newGenerator(t, generator.RandomConfig{Alphabet: "abc", Length: 10})
 
-	// Generate many codes to test sanitization
-	// Since sanitizeCode is private, we test it indirectly through Generate
-	// Base64 encoding can produce characters like +, /, = which need sanitization
 	for i := 0; i < 1000; i++ {
 		code := gen.Generate()
 
-		// Verify all characters are valid
-		validPattern := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-		assert.True(t, validPattern.MatchString(code), "Code should be sanitized: %s", code)
+		assert.Len(t, code, 10)
+		assert.Empty(t, strings.Trim(code, "abc"), "Code should only use the alphabet: %s", code)
+	}
+}
 
-		// Verify no invalid characters
-		invalidPattern := regexp.MustCompile(`[^a-zA-Z0-9_-]`)
-		assert.False(t, invalidPattern.MatchString(code), "Code should not contain invalid characters: %s", code)
+func TestRandomShortCodeGenerator_Uniform(t *testing.T) {
+	// 62 doesn't divide 256, so a plain modulo would favour the first
+	// characters of the alphabet noticeably.
+	gen := newGenerator(t, generator.RandomConfig{Length: 100})
+
+	counts := make(map[rune]int)
+	const codes = 2000
+	for i := 0; i < codes; i++ {
+		for _, r := range gen.Generate() {
+			counts[r]++
+		}
+	}
 
-		// Verify length is maintained after sanitization
-		assert.Equal(t, generator.ShortCodeLength, len(code), "Code length should be maintained: %s", code)
+	expected := float64(codes*100) / float64(len(generator.DefaultAlphabet))
+	assert.Len(t, counts, len(generator.DefaultAlphabet))
+	for r, n := range counts {
+		assert.InDelta(t, expected, float64(n), expected*0.1, "character %q is skewed", r)
 	}
 }
 
 func TestRandomShortCodeGenerator_Generate_Length(t *testing.T) {
-	gen := generator.NewRandomShortCodeGenerator()
+	gen := newGenerator(t, generator.RandomConfig{Length: 4})
 
 	for i := 0; i < 100; i++ {
 		code := gen.Generate()
-		assert.Equal(t, generator.ShortCodeLength, len(code), "All generated codes should have length %d", generator.ShortCodeLength)
+		assert.Equal(t, 4, len(code), "All generated codes should have length %d", 4)
 	}
 }
+
+func TestRandomShortCodeGenerator_GrowsOnCollisions(t *testing.T) {
+	gen := newGenerator(t, generator.RandomConfig{Length: 4, MaxLength: 5, CollisionThreshold: 2})
+
+	gen.Observe(true)
+	gen.Observe(false)
+	gen.Observe(true)
+	assert.Equal(t, 4, gen.Length(), "a success should reset the collision count")
+
+	gen.Observe(true)
+	assert.Equal(t, 5, gen.Length())
+	assert.Len(t, gen.Generate(), 5)
+
+	gen.Observe(true)
+	gen.Observe(true)
+	assert.Equal(t, 5, gen.Length(), "length should not grow past the max")
+}
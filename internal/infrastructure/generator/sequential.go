@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/bits"
+	"url-shortener/internal/domain"
+)
+
+// maxSequentialBits bounds the code space so the Feistel network, which works
+// on an even number of bits, always fits in a uint64.
+const maxSequentialBits = 62
+
+const feistelRounds = 4
+
+type SequentialConfig struct {
+	MinLength int    // codes are left-padded to at least this length
+	Alphabet  string // digits of the encoding, in order
+	Key       []byte // when set, IDs are permuted so codes don't reveal the order of links
+}
+
+// SequentialShortCodeGenerator turns IDs from a persistent sequence into
+// codes, so generated codes never collide with each other. Codes only grow
+// longer once the IDs no longer fit in MinLength digits.
+//
+// With a key, the ID is first run through a keyed permutation of all codes
+// of its length. The permutation is a bijection, so uniqueness is preserved,
+// but the key must not change once codes have been handed out: codes produced
+// under a different key can collide with existing ones.
+type SequentialShortCodeGenerator struct {
+	seq       domain.IDSequence
+	alphabet  string
+	minLength int
+	key       []byte
+}
+
+func NewSequentialShortCodeGenerator(seq domain.IDSequence, cfg SequentialConfig) (domain.ShortCodeGenerator, error) {
+	if cfg.MinLength == 0 {
+		cfg.MinLength = ShortCodeLength
+	}
+	if cfg.Alphabet == "" {
+		cfg.Alphabet = DefaultAlphabet
+	}
+
+	if err := validateAlphabet(cfg.Alphabet); err != nil {
+		return nil, err
+	}
+	if cfg.MinLength < 1 {
+		return nil, fmt.Errorf("%w: length must be at least 1", ErrInvalidConfig)
+	}
+	if _, ok := codeSpace(len(cfg.Alphabet), cfg.MinLength); !ok {
+		return nil, fmt.Errorf("%w: length %d is too long for a %d character alphabet", ErrInvalidConfig, cfg.MinLength, len(cfg.Alphabet))
+	}
+
+	return &SequentialShortCodeGenerator{
+		seq:       seq,
+		alphabet:  cfg.Alphabet,
+		minLength: cfg.MinLength,
+		key:       cfg.Key,
+	}, nil
+}
+
+func (g *SequentialShortCodeGenerator) GenerateContext(ctx context.Context) (string, error) {
+	id, err := g.seq.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return g.Encode(id)
+}
+
+// Generate is GenerateContext without a way to report failures; it returns
+// an empty code when the sequence is unavailable.
+func (g *SequentialShortCodeGenerator) Generate() string {
+	code, err := g.GenerateContext(context.Background())
+	if err != nil {
+		log.Printf("Error generating short code: %v", err)
+		return ""
+	}
+	return code
+}
+
+// Encode returns the code for id. Distinct IDs always get distinct codes.
+func (g *SequentialShortCodeGenerator) Encode(id uint64) (string, error) {
+	base := uint64(len(g.alphabet))
+
+	length := g.minLength
+	space, _ := codeSpace(len(g.alphabet), length)
+	for id >= space {
+		length++
+		var ok bool
+		if space, ok = codeSpace(len(g.alphabet), length); !ok {
+			return "", fmt.Errorf("%w: id %d does not fit in the code space", domain.ErrShortCodeUnavailable, id)
+		}
+	}
+
+	if len(g.key) > 0 {
+		id = g.permute(id, space)
+	}
+
+	code := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		code[i] = g.alphabet[id%base]
+		id /= base
+	}
+	return string(code), nil
+}
+
+// permute maps x in [0, space) to another value in [0, space) with a Feistel
+// network over the smallest even number of bits that covers space. Results
+// outside the range are fed back in (cycle walking) until one lands inside,
+// which keeps the mapping a bijection on [0, space).
+func (g *SequentialShortCodeGenerator) permute(x, space uint64) uint64 {
+	width := bits.Len64(space - 1)
+	width += width % 2
+	width = max(width, 2)
+
+	for {
+		x = g.feistel(x, width)
+		if x < space {
+			return x
+		}
+	}
+}
+
+func (g *SequentialShortCodeGenerator) feistel(x uint64, width int) uint64 {
+	half := width / 2
+	mask := uint64(1)<<half - 1
+
+	left, right := x>>half, x&mask
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^(g.round(round, width, right)&mask)
+	}
+	return left<<half | right
+}
+
+func (g *SequentialShortCodeGenerator) round(round, width int, value uint64) uint64 {
+	var buf [10]byte
+	buf[0] = byte(round)
+	buf[1] = byte(width)
+	binary.BigEndian.PutUint64(buf[2:], value)
+
+	mac := hmac.New(sha256.New, g.key)
+	mac.Write(buf[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// codeSpace returns base^length, the number of codes of the given length,
+// and false when it exceeds what the generator can permute.
+func codeSpace(base, length int) (uint64, bool) {
+	space := uint64(1)
+	for i := 0; i < length; i++ {
+		hi, lo := bits.Mul64(space, uint64(base))
+		if hi != 0 || lo > 1<<maxSequentialBits {
+			return 0, false
+		}
+		space = lo
+	}
+	return space, true
+}
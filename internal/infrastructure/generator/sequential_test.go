@@ -0,0 +1,143 @@
+package generator_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/generator"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSequential(t *testing.T, seq domain.IDSequence, cfg generator.SequentialConfig) *generator.SequentialShortCodeGenerator {
+	t.Helper()
+	if seq == nil {
+		seq = repository.NewMemoryIDSequence()
+	}
+	gen, err := generator.NewSequentialShortCodeGenerator(seq, cfg)
+	require.NoError(t, err)
+	return gen.(*generator.SequentialShortCodeGenerator)
+}
+
+func TestSequentialShortCodeGenerator_Base62(t *testing.T) {
+	gen := newSequential(t, nil, generator.SequentialConfig{})
+
+	tests := []struct {
+		id   uint64
+		code string
+	}{
+		{1, "000001"},
+		{61, "00000z"},
+		{62, "000010"},
+		{56800235583, "zzzzzz"},
+		{56800235584, "1000000"},
+	}
+
+	for _, tt := range tests {
+		code, err := gen.Encode(tt.id)
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "id %d", tt.id)
+	}
+}
+
+func TestSequentialShortCodeGenerator_GenerateContext(t *testing.T) {
+	gen := newSequential(t, nil, generator.SequentialConfig{MinLength: 3})
+	ctx := context.Background()
+
+	for _, want := range []string{"001", "002", "003"} {
+		code, err := gen.GenerateContext(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, want, code)
+	}
+}
+
+func TestSequentialShortCodeGenerator_ObfuscatedIsBijective(t *testing.T) {
+	// A small alphabet makes the codes cross several lengths, and 3^n is never
+	// a power of two, so cycle walking is exercised at every length.
+	gen := newSequential(t, nil, generator.SequentialConfig{
+		MinLength: 2,
+		Alphabet:  "abc",
+		Key:       []byte("secret"),
+	})
+
+	seen := make(map[string]uint64)
+	for id := uint64(0); id < 3*3*3*3*3; id++ {
+		code, err := gen.Encode(id)
+		require.NoError(t, err)
+		assert.Empty(t, strings.Trim(code, "abc"))
+
+		prev, dup := seen[code]
+		require.False(t, dup, "ids %d and %d both map to %s", prev, id, code)
+		seen[code] = id
+	}
+}
+
+func TestSequentialShortCodeGenerator_ObfuscatedHidesOrder(t *testing.T) {
+	plain := newSequential(t, nil, generator.SequentialConfig{})
+	keyed := newSequential(t, nil, generator.SequentialConfig{Key: []byte("secret")})
+	other := newSequential(t, nil, generator.SequentialConfig{Key: []byte("another")})
+
+	var sequential int
+	for id := uint64(1); id <= 100; id++ {
+		p, err := plain.Encode(id)
+		require.NoError(t, err)
+		k, err := keyed.Encode(id)
+		require.NoError(t, err)
+		o, err := other.Encode(id)
+		require.NoError(t, err)
+
+		assert.Len(t, k, len(p))
+		assert.NotEqual(t, k, o)
+		if next, _ := keyed.Encode(id + 1); next > k {
+			sequential++
+		}
+
+		again, err := keyed.Encode(id)
+		require.NoError(t, err)
+		assert.Equal(t, k, again, "encoding must be deterministic")
+	}
+	assert.Less(t, sequential, 80, "obfuscated codes should not follow the id order")
+}
+
+func TestSequentialShortCodeGenerator_Exhausted(t *testing.T) {
+	gen := newSequential(t, nil, generator.SequentialConfig{})
+
+	_, err := gen.Encode(1 << 63)
+	assert.ErrorIs(t, err, domain.ErrShortCodeUnavailable)
+}
+
+type failingSequence struct{}
+
+func (failingSequence) NextID(ctx context.Context) (uint64, error) {
+	return 0, errors.New("storage down")
+}
+
+func TestSequentialShortCodeGenerator_SequenceError(t *testing.T) {
+	gen := newSequential(t, failingSequence{}, generator.SequentialConfig{})
+
+	_, err := gen.GenerateContext(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, gen.Generate())
+}
+
+func TestNewSequentialShortCodeGenerator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  generator.SequentialConfig
+	}{
+		{"repeated character", generator.SequentialConfig{Alphabet: "aa"}},
+		{"negative length", generator.SequentialConfig{MinLength: -1}},
+		{"too long", generator.SequentialConfig{MinLength: 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generator.NewSequentialShortCodeGenerator(repository.NewMemoryIDSequence(), tt.cfg)
+			assert.ErrorIs(t, err, generator.ErrInvalidConfig)
+		})
+	}
+}
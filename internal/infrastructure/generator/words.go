@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"url-shortener/internal/domain"
+)
+
+const (
+	DefaultWordDigits    = 2
+	DefaultMaxWordDigits = 6
+)
+
+// The word lists avoid homophones and tricky spellings so codes survive being
+// read out over the phone.
+var (
+	adjectives = []string{
+		"able", "agile", "amber", "ample", "azure", "bold", "brave", "brisk",
+		"calm", "candid", "civic", "clever", "cosmic", "crisp", "curly", "daring",
+		"eager", "early", "fancy", "fluffy", "fond", "frank", "fresh", "gentle",
+		"giant", "glad", "golden", "grand", "happy", "hardy", "honest", "humble",
+		"jolly", "keen", "kind", "lively", "loyal", "lucky", "mellow", "merry",
+		"mighty", "modest", "neat", "noble", "polite", "proud", "quick", "quiet",
+		"rapid", "ready", "royal", "rustic", "shiny", "silver", "smart", "snowy",
+		"solid", "sunny", "swift", "tidy", "vivid", "warm", "witty", "zesty",
+	}
+	animals = []string{
+		"badger", "beaver", "bison", "camel", "cheetah", "cobra", "condor", "coyote",
+		"crane", "dingo", "dolphin", "donkey", "eagle", "falcon", "ferret", "finch",
+		"gecko", "gibbon", "giraffe", "gopher", "heron", "hippo", "husky", "ibex",
+		"iguana", "jackal", "jaguar", "koala", "lemur", "leopard", "lion", "llama",
+		"lobster", "magpie", "marmot", "moose", "otter", "owl", "panda", "panther",
+		"parrot", "pelican", "penguin", "puffin", "python", "rabbit", "raven", "robin",
+		"salmon", "shark", "sloth", "sparrow", "squid", "swan", "tiger", "toucan",
+		"turtle", "walrus", "weasel", "wombat", "zebra", "bobcat", "octopus", "mantis",
+	}
+)
+
+type WordConfig struct {
+	Digits             int // initial number of digits after the words
+	MaxDigits          int // the number never grows past this many digits
+	CollisionThreshold int // consecutive collisions that trigger another digit
+}
+
+// WordShortCodeGenerator produces lowercase codes such as brave-otter-42. The
+// words carry most of the entropy; the number grows by a digit whenever
+// collisions show the current space is getting crowded.
+type WordShortCodeGenerator struct {
+	lengthGrowth
+}
+
+func NewWordShortCodeGenerator(cfg WordConfig) (domain.ShortCodeGenerator, error) {
+	if cfg.Digits == 0 {
+		cfg.Digits = DefaultWordDigits
+	}
+	if cfg.MaxDigits == 0 {
+		cfg.MaxDigits = max(DefaultMaxWordDigits, cfg.Digits)
+	}
+	if cfg.CollisionThreshold == 0 {
+		cfg.CollisionThreshold = DefaultCollisionThreshold
+	}
+
+	if cfg.Digits < 1 || cfg.MaxDigits < cfg.Digits {
+		return nil, fmt.Errorf("%w: digits must be at least 1 and no more than the max digits", ErrInvalidConfig)
+	}
+	if cfg.CollisionThreshold < 1 {
+		return nil, fmt.Errorf("%w: collision threshold must be positive", ErrInvalidConfig)
+	}
+
+	return &WordShortCodeGenerator{
+		lengthGrowth: lengthGrowth{
+			length:    cfg.Digits,
+			maxLength: cfg.MaxDigits,
+			threshold: cfg.CollisionThreshold,
+		},
+	}, nil
+}
+
+func (g *WordShortCodeGenerator) Generate() string {
+	return adjectives[randomIndex(len(adjectives))] + "-" +
+		animals[randomIndex(len(animals))] + "-" +
+		randomString("0123456789", g.current())
+}
+
+// Digits returns the number of digits currently generated.
+func (g *WordShortCodeGenerator) Digits() int {
+	return g.current()
+}
+
+func randomIndex(n int) int {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic("failed to read random bytes: " + err.Error())
+	}
+	return int(i.Int64())
+}
@@ -0,0 +1,61 @@
+package generator_test
+
+import (
+	"regexp"
+	"testing"
+	"url-shortener/internal/infrastructure/generator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWordGenerator(t *testing.T, cfg generator.WordConfig) *generator.WordShortCodeGenerator {
+	t.Helper()
+	gen, err := generator.NewWordShortCodeGenerator(cfg)
+	require.NoError(t, err)
+	return gen.(*generator.WordShortCodeGenerator)
+}
+
+func TestWordShortCodeGenerator_Generate(t *testing.T) {
+	gen := newWordGenerator(t, generator.WordConfig{})
+	pattern := regexp.MustCompile(`^[a-z]+-[a-z]+-[0-9]{2}$`)
+
+	codes := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code := gen.Generate()
+		assert.Regexp(t, pattern, code)
+		codes[code] = true
+	}
+	assert.Greater(t, len(codes), 90, "Should generate mostly unique codes")
+}
+
+func TestWordShortCodeGenerator_WordLists(t *testing.T) {
+	// Ambiguous letters are fine inside words; what matters is that every
+	// word is plain lowercase and no word appears twice.
+	lowercase := regexp.MustCompile(`^[a-z]+$`)
+	seen := make(map[string]bool)
+	for _, word := range append(append([]string{}, generator.Adjectives...), generator.Animals...) {
+		assert.Regexp(t, lowercase, word)
+		assert.False(t, seen[word], "%q is listed twice", word)
+		seen[word] = true
+	}
+}
+
+func TestWordShortCodeGenerator_GrowsOnCollisions(t *testing.T) {
+	gen := newWordGenerator(t, generator.WordConfig{Digits: 2, MaxDigits: 3, CollisionThreshold: 1})
+
+	gen.Observe(true)
+	assert.Equal(t, 3, gen.Digits())
+	assert.Regexp(t, `-[0-9]{3}$`, gen.Generate())
+
+	gen.Observe(true)
+	assert.Equal(t, 3, gen.Digits(), "digits should not grow past the max")
+}
+
+func TestNewWordShortCodeGenerator_InvalidConfig(t *testing.T) {
+	_, err := generator.NewWordShortCodeGenerator(generator.WordConfig{Digits: 4, MaxDigits: 3})
+	assert.ErrorIs(t, err, generator.ErrInvalidConfig)
+
+	_, err = generator.NewWordShortCodeGenerator(generator.WordConfig{CollisionThreshold: -1})
+	assert.ErrorIs(t, err, generator.ErrInvalidConfig)
+}
@@ -0,0 +1,10 @@
+package oidc
+
+import "time"
+
+// ExpireKeys makes the next unknown key ID refetch the key set right away.
+func (p *Provider) ExpireKeys() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keysFetched = time.Time{}
+}
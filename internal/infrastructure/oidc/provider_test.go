@@ -0,0 +1,340 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"maps"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/infrastructure/oidc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	clientID     = "shortener"
+	clientSecret = "s3cret"
+	redirectURL  = "https://sho.rt/auth/oidc/callback"
+)
+
+// mockProvider is a minimal OpenID Connect provider. Its authorization
+// endpoint logs everyone in as the configured claims and its token endpoint
+// enforces PKCE.
+type mockProvider struct {
+	*httptest.Server
+	t *testing.T
+
+	mu     sync.Mutex
+	kid    string
+	signer crypto.Signer
+	claims map[string]any
+	grants map[string]mockGrant // by code
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	m := &mockProvider{t: t, grants: make(map[string]mockGrant)}
+	m.rotateKey("rsa1", mustRSAKey(t))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize?prompt=login",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	mux.HandleFunc("/jwks", m.jwks)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	m.claims = map[string]any{
+		"iss":            m.URL,
+		"sub":            "user-1",
+		"aud":            clientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"groups":         []string{"engineering", "staff"},
+	}
+	return m
+}
+
+func (m *mockProvider) rotateKey(kid string, signer crypto.Signer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kid, m.signer = kid, signer
+}
+
+func (m *mockProvider) setClaims(changes map[string]any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	maps.Copy(m.claims, changes)
+}
+
+func (m *mockProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	assert.Equal(m.t, "login", q.Get("prompt"), "existing query parameters are kept")
+	assert.Equal(m.t, "code", q.Get("response_type"))
+	assert.Equal(m.t, clientID, q.Get("client_id"))
+	assert.Equal(m.t, redirectURL, q.Get("redirect_uri"))
+	assert.Equal(m.t, "openid email profile", q.Get("scope"))
+	assert.Equal(m.t, "S256", q.Get("code_challenge_method"))
+
+	code := "code-" + q.Get("state")
+	m.mu.Lock()
+	m.grants[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	m.mu.Unlock()
+
+	http.Redirect(w, r, redirectURL+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+}
+
+func (m *mockProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != clientID || secret != clientSecret {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+	assert.Equal(m.t, "authorization_code", r.PostFormValue("grant_type"))
+	assert.Equal(m.t, redirectURL, r.PostFormValue("redirect_uri"))
+
+	m.mu.Lock()
+	grant, ok := m.grants[r.PostFormValue("code")]
+	delete(m.grants, r.PostFormValue("code"))
+	m.mu.Unlock()
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "bad code or verifier"})
+		return
+	}
+
+	m.mu.Lock()
+	claims := maps.Clone(m.claims)
+	m.mu.Unlock()
+	if _, set := claims["nonce"]; !set {
+		claims["nonce"] = grant.nonce
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "unused",
+		"token_type":   "Bearer",
+		"id_token":     m.sign(claims),
+	})
+}
+
+func (m *mockProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := map[string]string{"kid": m.kid, "use": "sig"}
+	switch pub := m.signer.Public().(type) {
+	case *rsa.PublicKey:
+		key["kty"] = "RSA"
+		key["n"] = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		key["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		point, err := pub.Bytes()
+		require.NoError(m.t, err)
+		key["kty"] = "EC"
+		key["crv"] = "P-256"
+		key["x"] = base64.RawURLEncoding.EncodeToString(point[1:33])
+		key["y"] = base64.RawURLEncoding.EncodeToString(point[33:])
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []any{map[string]string{"kty": "oct", "kid": "hmac"}, key},
+	})
+}
+
+func (m *mockProvider) sign(claims map[string]any) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	alg := "RS256"
+	if _, ok := m.signer.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": m.kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hashed := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch key := m.signer.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+		require.NoError(m.t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, hashed[:])
+		require.NoError(m.t, err)
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// login runs the browser's part of the flow and returns the code the
+// provider redirected back with.
+func (m *mockProvider) login(t *testing.T, authURL string) string {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query().Get("code")
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func newProvider(t *testing.T, m *mockProvider) *oidc.Provider {
+	t.Helper()
+	p, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:    m.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	})
+	require.NoError(t, err)
+	return p.(*oidc.Provider)
+}
+
+func TestProvider_Login(t *testing.T) {
+	m := newMockProvider(t)
+	p := newProvider(t, m)
+	ctx := context.Background()
+
+	code := m.login(t, p.AuthCodeURL("state1", "nonce1", "verifier1"))
+	identity, err := p.Exchange(ctx, code, "verifier1", "nonce1")
+	require.NoError(t, err)
+	assert.Equal(t, m.URL, identity.Issuer)
+	assert.Equal(t, "user-1", identity.Subject)
+	assert.Equal(t, "ada@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, []string{"engineering", "staff"}, identity.Groups)
+
+	_, err = p.Exchange(ctx, code, "verifier1", "nonce1")
+	assert.ErrorContains(t, err, "invalid_grant", "codes are single use")
+
+	code = m.login(t, p.AuthCodeURL("state2", "nonce2", "verifier2"))
+	_, err = p.Exchange(ctx, code, "stolen code, other verifier", "nonce2")
+	assert.ErrorContains(t, err, "invalid_grant", "PKCE binds the code to the verifier")
+}
+
+func TestProvider_InvalidIDTokens(t *testing.T) {
+	m := newMockProvider(t)
+	p := newProvider(t, m)
+
+	tests := []struct {
+		name    string
+		claims  map[string]any
+		nonce   string
+		wantErr string
+	}{
+		{name: "other issuer", claims: map[string]any{"iss": "https://evil.example"}, wantErr: "issued by"},
+		{name: "other audience", claims: map[string]any{"aud": "someone-else"}, wantErr: "another client"},
+		{name: "authorized party", claims: map[string]any{"aud": []string{clientID, "api"}, "azp": "api"}, wantErr: "another client"},
+		{name: "expired", claims: map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}, wantErr: "expired"},
+		{name: "replayed", claims: map[string]any{"nonce": "an old nonce"}, wantErr: "nonce mismatch"},
+		{name: "no subject", claims: map[string]any{"sub": ""}, wantErr: "no subject"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.mu.Lock()
+			saved := maps.Clone(m.claims)
+			m.mu.Unlock()
+			m.setClaims(tt.claims)
+			defer func() {
+				m.mu.Lock()
+				m.claims = saved
+				m.mu.Unlock()
+			}()
+
+			code := m.login(t, p.AuthCodeURL("state", "nonce", "verifier"))
+			_, err := p.Exchange(context.Background(), code, "verifier", "nonce")
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestProvider_KeyRotation(t *testing.T) {
+	m := newMockProvider(t)
+	p := newProvider(t, m)
+	ctx := context.Background()
+
+	code := m.login(t, p.AuthCodeURL("state", "nonce", "verifier"))
+	_, err := p.Exchange(ctx, code, "verifier", "nonce")
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	m.rotateKey("ec1", ecKey)
+
+	code = m.login(t, p.AuthCodeURL("state", "nonce", "verifier"))
+	_, err = p.Exchange(ctx, code, "verifier", "nonce")
+	assert.ErrorContains(t, err, "unknown signing key", "the key set is not refetched on every unknown key")
+
+	p.ExpireKeys()
+	m.setClaims(map[string]any{"email_verified": "true", "groups": "staff"})
+	code = m.login(t, p.AuthCodeURL("state", "nonce", "verifier"))
+	identity, err := p.Exchange(ctx, code, "verifier", "nonce")
+	require.NoError(t, err)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, []string{"staff"}, identity.Groups)
+}
+
+func TestProvider_ForgedSignature(t *testing.T) {
+	m := newMockProvider(t)
+	p := newProvider(t, m)
+	ctx := context.Background()
+
+	code := m.login(t, p.AuthCodeURL("state", "nonce", "verifier"))
+	_, err := p.Exchange(ctx, code, "verifier", "nonce")
+	require.NoError(t, err)
+
+	// A token signed with another key under the known key's ID.
+	m.rotateKey("rsa1", mustRSAKey(t))
+	code = m.login(t, p.AuthCodeURL("state", "nonce", "verifier"))
+	_, err = p.Exchange(ctx, code, "verifier", "nonce")
+	assert.ErrorContains(t, err, "signature")
+}
+
+func TestNewProvider_IssuerMismatch(t *testing.T) {
+	m := newMockProvider(t)
+
+	_, err := oidc.NewProvider(context.Background(), oidc.Config{IssuerURL: m.URL + "/", ClientID: clientID})
+	assert.ErrorContains(t, err, "issuer")
+
+	_, err = oidc.NewProvider(context.Background(), oidc.Config{IssuerURL: m.URL + "/missing", ClientID: clientID})
+	assert.ErrorContains(t, err, "status 404")
+}
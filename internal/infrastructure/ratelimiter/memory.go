@@ -7,11 +7,15 @@ import (
 	"url-shortener/internal/domain"
 )
 
+// MemoryRateLimiter counts requests in fixed windows. It is the cheapest
+// algorithm, but a client can make up to twice the limit by spending it at the
+// end of one window and again at the start of the next.
 type MemoryRateLimiter struct {
 	mu       sync.RWMutex
 	buckets  map[string]*bucket
 	limit    int
 	window   time.Duration
+	now      func() time.Time
 	cleanup  *time.Ticker
 	stopChan chan struct{}
 }
@@ -21,11 +25,13 @@ type bucket struct {
 	resetTime time.Time
 }
 
-func NewMemoryRateLimiter(limit int, window time.Duration) domain.RateLimiter {
+func NewMemoryRateLimiter(limit int, window time.Duration, opts ...Option) domain.RateLimiter {
+	o := newOptions(opts)
 	rl := &MemoryRateLimiter{
 		buckets:  make(map[string]*bucket),
 		limit:    limit,
 		window:   window,
+		now:      o.now,
 		stopChan: make(chan struct{}),
 	}
 
@@ -36,26 +42,51 @@ func NewMemoryRateLimiter(limit int, window time.Duration) domain.RateLimiter {
 }
 
 func (rl *MemoryRateLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return allow(rl.Take(ctx, identifier))
+}
+
+func (rl *MemoryRateLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
+	now := rl.now()
 	b, exists := rl.buckets[identifier]
 
 	if !exists || now.After(b.resetTime) {
-		rl.buckets[identifier] = &bucket{
-			count:     1,
-			resetTime: now.Add(rl.window),
-		}
-		return true, nil
+		b = &bucket{resetTime: now.Add(rl.window)}
+		rl.buckets[identifier] = b
 	}
 
-	if b.count >= rl.limit {
-		return false, nil
+	if b.count < rl.limit {
+		b.count++
+		return fixedWindowResult(true, rl.limit, b.count, b.resetTime.Sub(now)), nil
+	}
+	return fixedWindowResult(false, rl.limit, b.count, b.resetTime.Sub(now)), nil
+}
+
+func (rl *MemoryRateLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	now := rl.now()
+	b, exists := rl.buckets[identifier]
+	if !exists || now.After(b.resetTime) {
+		return fixedWindowResult(true, rl.limit, 0, 0), nil
 	}
+	return fixedWindowResult(b.count < rl.limit, rl.limit, b.count, b.resetTime.Sub(now)), nil
+}
 
-	b.count++
-	return true, nil
+func fixedWindowResult(allowed bool, limit, count int, reset time.Duration) *domain.RateLimitResult {
+	result := &domain.RateLimitResult{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: max(0, limit-count),
+		Reset:     reset,
+	}
+	if result.Remaining == 0 {
+		result.RetryAfter = reset
+	}
+	return result
 }
 
 func (rl *MemoryRateLimiter) cleanupExpired() {
@@ -63,7 +94,7 @@ func (rl *MemoryRateLimiter) cleanupExpired() {
 		select {
 		case <-rl.cleanup.C:
 			rl.mu.Lock()
-			now := time.Now()
+			now := rl.now()
 			for id, b := range rl.buckets {
 				if now.After(b.resetTime) {
 					delete(rl.buckets, id)
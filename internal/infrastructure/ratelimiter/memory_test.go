@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 	"time"
+	"url-shortener/internal/domain"
 )
 
 func TestMemoryRateLimiter_Allow(t *testing.T) {
@@ -66,6 +67,63 @@ func TestMemoryRateLimiter_Allow(t *testing.T) {
 	}
 }
 
+func TestMemoryRateLimiter_Allow_WindowBoundary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "limit resets when the window ends",
+			steps: []step{
+				{offset: 0, requests: 11, allowed: 10},
+				{offset: 60 * time.Second, requests: 1, allowed: 0},
+				{offset: 61 * time.Second, requests: 11, allowed: 10},
+			},
+		},
+		{
+			// The weakness the other algorithms fix: 20 requests get
+			// through within two seconds.
+			name: "double burst across a window boundary",
+			steps: []step{
+				{offset: 0, requests: 1, allowed: 1},
+				{offset: 59 * time.Second, requests: 9, allowed: 9},
+				{offset: 61 * time.Second, requests: 10, allowed: 10},
+				{offset: 120 * time.Second, requests: 10, allowed: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			rl := NewMemoryRateLimiter(10, time.Minute, WithClock(clock.Now)).(*MemoryRateLimiter)
+			defer rl.Close()
+
+			runSteps(t, rl, clock, tt.steps)
+		})
+	}
+}
+
+func TestMemoryRateLimiter_Take(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	rl := NewMemoryRateLimiter(10, time.Minute, WithClock(clock.Now)).(*MemoryRateLimiter)
+	defer rl.Close()
+
+	runTakeSteps(t, rl, clock, []takeStep{
+		{offset: 0, requests: 1, want: domain.RateLimitResult{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Minute}},
+		{offset: 15 * time.Second, requests: 9, want: domain.RateLimitResult{Allowed: true, Limit: 10, Reset: 45 * time.Second, RetryAfter: 45 * time.Second}},
+		{offset: 30 * time.Second, requests: 1, want: domain.RateLimitResult{Limit: 10, Reset: 30 * time.Second, RetryAfter: 30 * time.Second}},
+		{offset: 61 * time.Second, requests: 1, want: domain.RateLimitResult{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Minute}},
+	})
+}
+
 func TestMemoryRateLimiter_Allow_DifferentIdentifiers(t *testing.T) {
 	t.Parallel()
 
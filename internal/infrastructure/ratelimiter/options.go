@@ -0,0 +1,68 @@
+package ratelimiter
+
+import (
+	"sync"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+type Option func(*options)
+
+type options struct {
+	now func() time.Time
+}
+
+// WithClock replaces time.Now, letting tests move time forward by hand.
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{now: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// sweeper runs fn every interval until it is closed.
+type sweeper struct {
+	ticker *time.Ticker
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func startSweeper(interval time.Duration, fn func()) *sweeper {
+	s := &sweeper{
+		ticker: time.NewTicker(interval),
+		stop:   make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-s.ticker.C:
+				fn()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *sweeper) Close() {
+	s.once.Do(func() {
+		s.ticker.Stop()
+		close(s.stop)
+	})
+}
+
+// allow adapts Take to the Allow method every limiter offers.
+func allow(result *domain.RateLimitResult, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	return result.Allowed, nil
+}
@@ -0,0 +1,190 @@
+package ratelimiter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// epoch is aligned to the minute so fixed windows start with the tests.
+var epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: epoch}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(offset time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = epoch.Add(offset)
+}
+
+// step sends requests at offset past epoch and expects allowed of them to
+// get through.
+type step struct {
+	offset   time.Duration
+	requests int
+	allowed  int
+}
+
+func runSteps(t *testing.T, rl domain.RateLimiter, clock *fakeClock, steps []step) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, s := range steps {
+		clock.Set(s.offset)
+		allowed := 0
+		for i := 0; i < s.requests; i++ {
+			ok, err := rl.Allow(ctx, "test-ip")
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if ok {
+				allowed++
+			}
+		}
+		if allowed != s.allowed {
+			t.Errorf("at +%v: %d of %d requests allowed, want %d", s.offset, allowed, s.requests, s.allowed)
+		}
+	}
+}
+
+// takeStep sends requests at offset past epoch and expects the result of the
+// last one.
+type takeStep struct {
+	offset   time.Duration
+	requests int
+	want     domain.RateLimitResult
+}
+
+func runTakeSteps(t *testing.T, rl domain.RateLimiter, clock *fakeClock, steps []takeStep) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, s := range steps {
+		clock.Set(s.offset)
+		var got *domain.RateLimitResult
+		for i := 0; i < s.requests; i++ {
+			var err error
+			if got, err = rl.Take(ctx, "test-ip"); err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+		}
+		// Durations are compared in seconds, since the Redis scripts round-trip
+		// through floats and milliseconds.
+		got.Reset = got.Reset.Round(time.Second)
+		got.RetryAfter = got.RetryAfter.Round(time.Second)
+		if *got != s.want {
+			t.Errorf("at +%v: Take() = %+v, want %+v", s.offset, *got, s.want)
+		}
+	}
+}
+
+func TestSweeper_Close(t *testing.T) {
+	t.Parallel()
+
+	var runs atomic.Int32
+	s := startSweeper(10*time.Millisecond, func() { runs.Add(1) })
+	time.Sleep(50 * time.Millisecond)
+	s.Close()
+	s.Close()
+
+	// A tick that was already pending may still be handled once.
+	time.Sleep(20 * time.Millisecond)
+	stopped := runs.Load()
+	if stopped == 0 {
+		t.Error("sweeper should run while open")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Error("sweeper should not run after Close")
+	}
+}
+
+func TestRateLimiters_Peek(t *testing.T) {
+	redisClient := func(t *testing.T) *redis.Client {
+		client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+		t.Cleanup(func() { client.Close() })
+		return client
+	}
+	constructors := map[string]func(t *testing.T, clock *fakeClock) domain.RateLimiter{
+		"fixed window": func(t *testing.T, clock *fakeClock) domain.RateLimiter {
+			rl := NewMemoryRateLimiter(3, time.Minute, WithClock(clock.Now))
+			t.Cleanup(rl.(*MemoryRateLimiter).Close)
+			return rl
+		},
+		"sliding window": func(t *testing.T, clock *fakeClock) domain.RateLimiter {
+			rl := NewSlidingWindowRateLimiter(3, time.Minute, WithClock(clock.Now))
+			t.Cleanup(rl.(*SlidingWindowRateLimiter).Close)
+			return rl
+		},
+		"token bucket": func(t *testing.T, clock *fakeClock) domain.RateLimiter {
+			rl := NewTokenBucketRateLimiter(3, time.Minute, WithClock(clock.Now))
+			t.Cleanup(rl.(*TokenBucketRateLimiter).Close)
+			return rl
+		},
+		"redis fixed window": func(t *testing.T, clock *fakeClock) domain.RateLimiter {
+			return NewRedisRateLimiter(redisClient(t), 3, time.Minute)
+		},
+		"redis sliding window": func(t *testing.T, clock *fakeClock) domain.RateLimiter {
+			return NewRedisSlidingWindowRateLimiter(redisClient(t), 3, time.Minute, WithClock(clock.Now))
+		},
+		"redis token bucket": func(t *testing.T, clock *fakeClock) domain.RateLimiter {
+			return NewRedisTokenBucketRateLimiter(redisClient(t), 3, time.Minute, WithClock(clock.Now))
+		},
+	}
+
+	for name, newLimiter := range constructors {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := newFakeClock()
+			clock.Set(10 * time.Second)
+			rl := newLimiter(t, clock)
+
+			peek := func(wantAllowed bool, wantRemaining int) *domain.RateLimitResult {
+				t.Helper()
+				got, err := rl.Peek(ctx, "test-ip")
+				if err != nil {
+					t.Fatalf("Peek() error = %v", err)
+				}
+				if got.Allowed != wantAllowed || got.Remaining != wantRemaining {
+					t.Errorf("Peek() = %+v, want Allowed %v and Remaining %d", *got, wantAllowed, wantRemaining)
+				}
+				return got
+			}
+
+			for i := 0; i < 5; i++ {
+				peek(true, 3)
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := rl.Take(ctx, "test-ip"); err != nil {
+					t.Fatalf("Take() error = %v", err)
+				}
+			}
+			peek(true, 1)
+			if result, err := rl.Take(ctx, "test-ip"); err != nil || !result.Allowed {
+				t.Fatalf("Take() = %+v, %v, want the last request allowed", result, err)
+			}
+			if got := peek(false, 0); got.RetryAfter <= 0 {
+				t.Errorf("Peek() RetryAfter = %v, want positive", got.RetryAfter)
+			}
+		})
+	}
+}
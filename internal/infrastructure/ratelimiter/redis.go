@@ -0,0 +1,231 @@
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"url-shortener/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// incrScript bumps the counter for the current window and starts the window
+// on the first hit, so every replica shares one fixed-window count. It
+// returns the count and the milliseconds left in the window.
+var incrScript = redis.NewScript(`
+local current = redis.call("INCR", KEYS[1])
+if current == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {current, redis.call("PTTL", KEYS[1])}
+`)
+
+type RedisRateLimiter struct {
+	client *redis.Client
+	limit  int
+	window time.Duration
+}
+
+func NewRedisRateLimiter(client *redis.Client, limit int, window time.Duration) domain.RateLimiter {
+	return &RedisRateLimiter{
+		client: client,
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (rl *RedisRateLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return allow(rl.Take(ctx, identifier))
+}
+
+func (rl *RedisRateLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	res, err := incrScript.Run(ctx, rl.client, []string{redisKeyPrefix + identifier}, rl.window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to increment rate limit counter: %w", err)
+	}
+
+	count, ttl := int(res[0]), time.Duration(res[1])*time.Millisecond
+	return fixedWindowResult(count <= rl.limit, rl.limit, count, max(0, ttl)), nil
+}
+
+func (rl *RedisRateLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	key := redisKeyPrefix + identifier
+	var count *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := rl.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Get(ctx, key)
+		ttl = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read rate limit counter: %w", err)
+	}
+
+	n, _ := count.Int()
+	return fixedWindowResult(n < rl.limit, rl.limit, n, max(0, ttl.Val())), nil
+}
+
+// tokenBucketScript refills and spends from a bucket stored as a hash, or
+// only reports the refilled bucket when ARGV[5] is "0". The time comes from
+// the caller so every replica measures refills the same way; a replica whose
+// clock lags never moves the bucket backwards.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * rate)
+	ts = now
+end
+
+if ARGV[5] == "0" then
+	if tokens >= 1 then
+		return {1, tostring(tokens)}
+	end
+	return {0, tostring(tokens)}
+end
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(ts))
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+// RedisTokenBucketRateLimiter is the shared counterpart of
+// TokenBucketRateLimiter.
+type RedisTokenBucketRateLimiter struct {
+	client *redis.Client
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewRedisTokenBucketRateLimiter(client *redis.Client, limit int, window time.Duration, opts ...Option) domain.RateLimiter {
+	o := newOptions(opts)
+	return &RedisTokenBucketRateLimiter{
+		client: client,
+		limit:  limit,
+		window: window,
+		now:    o.now,
+	}
+}
+
+func (rl *RedisTokenBucketRateLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return allow(rl.Take(ctx, identifier))
+}
+
+func (rl *RedisTokenBucketRateLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return rl.run(ctx, identifier, true)
+}
+
+func (rl *RedisTokenBucketRateLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return rl.run(ctx, identifier, false)
+}
+
+func (rl *RedisTokenBucketRateLimiter) run(ctx context.Context, identifier string, spend bool) (*domain.RateLimitResult, error) {
+	ratePerMs := float64(rl.limit) / float64(rl.window.Milliseconds())
+	res, err := tokenBucketScript.Run(ctx, rl.client, []string{redisKeyPrefix + "tb:" + identifier},
+		rl.limit, ratePerMs, rl.now().UnixMilli(), rl.window.Milliseconds(), spendArg(spend)).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update token bucket: %w", err)
+	}
+
+	allowed, _ := res[0].(int64)
+	raw, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token bucket: %w", err)
+	}
+
+	ratePerNs := float64(rl.limit) / float64(rl.window)
+	return tokenBucketResult(allowed == 1, float64(rl.limit), tokens, ratePerNs), nil
+}
+
+// slidingWindowScript checks the weighted count of the previous and current
+// windows before counting the request in the current one, unless ARGV[4] is
+// "0". It returns the decision followed by both counts.
+var slidingWindowScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local previous = tonumber(redis.call("GET", KEYS[2]) or "0")
+if previous * tonumber(ARGV[1]) + current >= tonumber(ARGV[2]) then
+	return {0, current, previous}
+end
+if ARGV[4] == "0" then
+	return {1, current, previous}
+end
+current = redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return {1, current, previous}
+`)
+
+// RedisSlidingWindowRateLimiter is the shared counterpart of
+// SlidingWindowRateLimiter.
+type RedisSlidingWindowRateLimiter struct {
+	client *redis.Client
+	limit  int
+	window time.Duration
+	now    func() time.Time
+}
+
+func NewRedisSlidingWindowRateLimiter(client *redis.Client, limit int, window time.Duration, opts ...Option) domain.RateLimiter {
+	o := newOptions(opts)
+	return &RedisSlidingWindowRateLimiter{
+		client: client,
+		limit:  limit,
+		window: window,
+		now:    o.now,
+	}
+}
+
+func (rl *RedisSlidingWindowRateLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return allow(rl.Take(ctx, identifier))
+}
+
+func (rl *RedisSlidingWindowRateLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return rl.run(ctx, identifier, true)
+}
+
+func (rl *RedisSlidingWindowRateLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return rl.run(ctx, identifier, false)
+}
+
+func (rl *RedisSlidingWindowRateLimiter) run(ctx context.Context, identifier string, spend bool) (*domain.RateLimitResult, error) {
+	now := rl.now()
+	start := now.Truncate(rl.window)
+	index := start.UnixNano() / int64(rl.window)
+	elapsed := now.Sub(start)
+	overlap := 1 - float64(elapsed)/float64(rl.window)
+
+	// The hash tag keeps both windows of an identifier in one cluster slot.
+	prefix := redisKeyPrefix + "sw:{" + identifier + "}:"
+	keys := []string{prefix + strconv.FormatInt(index, 10), prefix + strconv.FormatInt(index-1, 10)}
+
+	res, err := slidingWindowScript.Run(ctx, rl.client, keys,
+		overlap, rl.limit, (2 * rl.window).Milliseconds(), spendArg(spend)).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update sliding window: %w", err)
+	}
+
+	return slidingWindowResult(res[0] == 1, rl.limit, int(res[2]), int(res[1]), elapsed, rl.window), nil
+}
+
+// spendArg tells the scripts whether to count the request or only report.
+func spendArg(spend bool) string {
+	if spend {
+		return "1"
+	}
+	return "0"
+}
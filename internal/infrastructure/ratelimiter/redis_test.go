@@ -0,0 +1,229 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisLimiter(t *testing.T, limit int, window time.Duration) (*RedisRateLimiter, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisRateLimiter(client, limit, window).(*RedisRateLimiter), mr
+}
+
+func TestRedisRateLimiter_Allow(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		requests  int
+		wantAllow bool
+	}{
+		{name: "allow requests within limit", limit: 5, requests: 3, wantAllow: true},
+		{name: "allow exactly limit requests", limit: 5, requests: 5, wantAllow: true},
+		{name: "deny requests exceeding limit", limit: 5, requests: 6, wantAllow: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, _ := newTestRedisLimiter(t, tt.limit, time.Minute)
+			ctx := context.Background()
+
+			var lastAllowed bool
+			for i := 0; i < tt.requests; i++ {
+				allowed, err := rl.Allow(ctx, "test-ip")
+				if err != nil {
+					t.Fatalf("Allow() error = %v", err)
+				}
+				lastAllowed = allowed
+			}
+
+			if lastAllowed != tt.wantAllow {
+				t.Errorf("Allow() = %v, want %v", lastAllowed, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestRedisRateLimiter_WindowReset(t *testing.T) {
+	rl, mr := newTestRedisLimiter(t, 2, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := rl.Allow(ctx, "test-ip"); !allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	if allowed, _ := rl.Allow(ctx, "test-ip"); allowed {
+		t.Fatal("request over the limit should be denied")
+	}
+
+	mr.FastForward(time.Minute)
+
+	if allowed, _ := rl.Allow(ctx, "test-ip"); !allowed {
+		t.Error("request after the window expired should be allowed")
+	}
+}
+
+func TestRedisRateLimiter_SharedAcrossInstances(t *testing.T) {
+	rl, mr := newTestRedisLimiter(t, 2, time.Minute)
+	other := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer other.Close()
+	replica := NewRedisRateLimiter(other, 2, time.Minute)
+	ctx := context.Background()
+
+	rl.Allow(ctx, "test-ip")
+	replica.Allow(ctx, "test-ip")
+
+	if allowed, _ := rl.Allow(ctx, "test-ip"); allowed {
+		t.Error("limit should be shared between replicas")
+	}
+	if allowed, _ := rl.Allow(ctx, "other-ip"); !allowed {
+		t.Error("different identifiers should have separate limits")
+	}
+}
+
+func TestRedisRateLimiter_Error(t *testing.T) {
+	rl, mr := newTestRedisLimiter(t, 2, time.Minute)
+	mr.Close()
+
+	if _, err := rl.Allow(context.Background(), "test-ip"); err == nil {
+		t.Error("Allow() should fail when redis is unreachable")
+	}
+}
+
+func TestRedisRateLimiter_Take(t *testing.T) {
+	rl, mr := newTestRedisLimiter(t, 2, time.Minute)
+	ctx := context.Background()
+
+	tests := []struct {
+		advance time.Duration
+		want    domain.RateLimitResult
+	}{
+		{want: domain.RateLimitResult{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute}},
+		{want: domain.RateLimitResult{Allowed: true, Limit: 2, Reset: time.Minute, RetryAfter: time.Minute}},
+		{advance: 30 * time.Second, want: domain.RateLimitResult{Limit: 2, Reset: 30 * time.Second, RetryAfter: 30 * time.Second}},
+	}
+	for i, tt := range tests {
+		mr.FastForward(tt.advance)
+		got, err := rl.Take(ctx, "test-ip")
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if *got != tt.want {
+			t.Errorf("request %d: Take() = %+v, want %+v", i+1, *got, tt.want)
+		}
+	}
+}
+
+func TestRedisAlgorithms_Take(t *testing.T) {
+	tests := []struct {
+		name  string
+		new   func(client *redis.Client, clock *fakeClock) domain.RateLimiter
+		steps []takeStep
+	}{
+		{
+			name: "token bucket",
+			new: func(client *redis.Client, clock *fakeClock) domain.RateLimiter {
+				return NewRedisTokenBucketRateLimiter(client, 10, time.Minute, WithClock(clock.Now))
+			},
+			steps: tokenBucketQuotaSteps,
+		},
+		{
+			name: "sliding window",
+			new: func(client *redis.Client, clock *fakeClock) domain.RateLimiter {
+				return NewRedisSlidingWindowRateLimiter(client, 10, time.Minute, WithClock(clock.Now))
+			},
+			steps: slidingWindowQuotaSteps,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer client.Close()
+			clock := newFakeClock()
+
+			runTakeSteps(t, tt.new(client, clock), clock, tt.steps)
+		})
+	}
+}
+
+func TestRedisAlgorithms_WindowBoundary(t *testing.T) {
+	tests := []struct {
+		name  string
+		new   func(client *redis.Client, clock *fakeClock) domain.RateLimiter
+		steps []step
+	}{
+		{
+			name: "token bucket",
+			new: func(client *redis.Client, clock *fakeClock) domain.RateLimiter {
+				return NewRedisTokenBucketRateLimiter(client, 10, time.Minute, WithClock(clock.Now))
+			},
+			steps: tokenBucketBoundarySteps,
+		},
+		{
+			name: "sliding window",
+			new: func(client *redis.Client, clock *fakeClock) domain.RateLimiter {
+				return NewRedisSlidingWindowRateLimiter(client, 10, time.Minute, WithClock(clock.Now))
+			},
+			steps: slidingWindowBoundarySteps,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer client.Close()
+			clock := newFakeClock()
+
+			runSteps(t, tt.new(client, clock), clock, tt.steps)
+		})
+	}
+}
+
+func TestRedisAlgorithms_SharedAcrossInstances(t *testing.T) {
+	constructors := map[string]func(*redis.Client, int, time.Duration, ...Option) domain.RateLimiter{
+		"token bucket":   NewRedisTokenBucketRateLimiter,
+		"sliding window": NewRedisSlidingWindowRateLimiter,
+	}
+
+	for name, newLimiter := range constructors {
+		t.Run(name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer client.Close()
+			other := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer other.Close()
+
+			rl := newLimiter(client, 2, time.Minute)
+			replica := newLimiter(other, 2, time.Minute)
+			ctx := context.Background()
+
+			rl.Allow(ctx, "test-ip")
+			replica.Allow(ctx, "test-ip")
+
+			if allowed, _ := rl.Allow(ctx, "test-ip"); allowed {
+				t.Error("limit should be shared between replicas")
+			}
+			if allowed, _ := replica.Allow(ctx, "other-ip"); !allowed {
+				t.Error("different identifiers should have separate limits")
+			}
+
+			mr.Close()
+			if _, err := rl.Allow(ctx, "test-ip"); err == nil {
+				t.Error("Allow() should fail when redis is unreachable")
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// SlidingWindowRateLimiter approximates the number of requests in the window
+// ending now by counting requests per fixed window and weighting the previous
+// window's count by how much of it still overlaps. Unlike a plain fixed
+// window, requests just before and just after a boundary count against each
+// other, while memory stays at two counters per identifier.
+type SlidingWindowRateLimiter struct {
+	mu       sync.Mutex
+	counters map[string]*windowCounter
+	limit    int
+	window   time.Duration
+	now      func() time.Time
+	sweeper  *sweeper
+}
+
+type windowCounter struct {
+	start    time.Time // start of the current fixed window
+	current  int
+	previous int
+}
+
+func NewSlidingWindowRateLimiter(limit int, window time.Duration, opts ...Option) domain.RateLimiter {
+	o := newOptions(opts)
+	rl := &SlidingWindowRateLimiter{
+		counters: make(map[string]*windowCounter),
+		limit:    limit,
+		window:   window,
+		now:      o.now,
+	}
+	rl.sweeper = startSweeper(window, rl.removeIdle)
+	return rl
+}
+
+func (rl *SlidingWindowRateLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return allow(rl.Take(ctx, identifier))
+}
+
+func (rl *SlidingWindowRateLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return rl.count(identifier, true), nil
+}
+
+func (rl *SlidingWindowRateLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return rl.count(identifier, false), nil
+}
+
+// count decides on a request, counting it only when spend is set.
+func (rl *SlidingWindowRateLimiter) count(identifier string, spend bool) *domain.RateLimitResult {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	start := now.Truncate(rl.window)
+
+	// A counter from an earlier window is replaced rather than moved
+	// forward, so that peeking leaves it untouched.
+	c, exists := rl.counters[identifier]
+	switch {
+	case !exists:
+		c = &windowCounter{start: start}
+	case !c.start.Equal(start):
+		next := &windowCounter{start: start}
+		if c.start.Add(rl.window).Equal(start) {
+			next.previous = c.current
+		}
+		c = next
+	}
+	if spend {
+		rl.counters[identifier] = c
+	}
+
+	elapsed := now.Sub(start)
+	allowed := slidingCount(c.previous, c.current, elapsed, rl.window) < float64(rl.limit)
+	if allowed && spend {
+		c.current++
+	}
+	return slidingWindowResult(allowed, rl.limit, c.previous, c.current, elapsed, rl.window)
+}
+
+// slidingWindowResult reports the quota left elapsed into the current fixed
+// window, given the counts of that window and the one before.
+func slidingWindowResult(allowed bool, limit, previous, current int, elapsed, window time.Duration) *domain.RateLimitResult {
+	estimate := slidingCount(previous, current, elapsed, window)
+	result := &domain.RateLimitResult{
+		Allowed: allowed,
+		Limit:   limit,
+		// Requests keep being allowed while the estimate is below the limit.
+		Remaining: max(0, int(math.Ceil(float64(limit)-estimate-1e-9))),
+	}
+
+	untilNext := window - elapsed
+	switch {
+	case current > 0:
+		result.Reset = untilNext + window
+	case previous > 0:
+		result.Reset = untilNext
+	}
+
+	if result.Remaining == 0 {
+		if current < limit {
+			// The previous window fades out far enough within this one.
+			fade := 1 - float64(limit-current)/float64(previous)
+			result.RetryAfter = time.Duration(fade*float64(window)) - elapsed
+		} else {
+			// This window has to become the previous one and fade out.
+			fade := 1 - float64(limit)/float64(current)
+			result.RetryAfter = untilNext + time.Duration(fade*float64(window))
+		}
+		// At the exact moment the estimate still equals the limit.
+		result.RetryAfter += time.Millisecond
+	}
+	return result
+}
+
+// slidingCount estimates the requests in the window ending elapsed into the
+// current fixed window.
+func slidingCount(previous, current int, elapsed, window time.Duration) float64 {
+	overlap := 1 - float64(elapsed)/float64(window)
+	return float64(previous)*overlap + float64(current)
+}
+
+// removeIdle forgets identifiers without requests in the last two windows,
+// which no longer affect any decision.
+func (rl *SlidingWindowRateLimiter) removeIdle() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	cutoff := rl.now().Truncate(rl.window).Add(-rl.window)
+	for id, c := range rl.counters {
+		if c.start.Before(cutoff) {
+			delete(rl.counters, id)
+		}
+	}
+}
+
+func (rl *SlidingWindowRateLimiter) Close() {
+	rl.sweeper.Close()
+}
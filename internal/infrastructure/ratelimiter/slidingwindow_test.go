@@ -0,0 +1,129 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// slidingWindowBoundarySteps spends 10 requests right before a minute
+// boundary and tries 10 more right after it. The previous window still
+// weighs 59/60 of its count, so only one more request fits.
+var slidingWindowBoundarySteps = []step{
+	{offset: 0, requests: 1, allowed: 1},
+	{offset: 59 * time.Second, requests: 9, allowed: 9},
+	{offset: 61 * time.Second, requests: 10, allowed: 1},
+	{offset: 120 * time.Second, requests: 10, allowed: 9},
+}
+
+// slidingWindowQuotaSteps fills a window and waits for it to fade out of
+// the next one.
+var slidingWindowQuotaSteps = []takeStep{
+	{offset: 0, requests: 10, want: domain.RateLimitResult{Allowed: true, Limit: 10, Reset: 2 * time.Minute, RetryAfter: time.Minute}},
+	{offset: 0, requests: 1, want: domain.RateLimitResult{Limit: 10, Reset: 2 * time.Minute, RetryAfter: time.Minute}},
+	{offset: 75 * time.Second, requests: 3, want: domain.RateLimitResult{Allowed: true, Limit: 10, Reset: 105 * time.Second, RetryAfter: 3 * time.Second}},
+	{offset: 90 * time.Second, requests: 1, want: domain.RateLimitResult{Allowed: true, Limit: 10, Remaining: 1, Reset: 90 * time.Second}},
+}
+
+func TestSlidingWindowRateLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		limit int
+		steps []step
+	}{
+		{
+			name:  "allows up to the limit within a window",
+			limit: 5,
+			steps: []step{{offset: 0, requests: 6, allowed: 5}},
+		},
+		{
+			name:  "previous window fades out linearly",
+			limit: 10,
+			steps: []step{
+				{offset: 0, requests: 10, allowed: 10},
+				{offset: 60 * time.Second, requests: 1, allowed: 0},
+				{offset: 75 * time.Second, requests: 4, allowed: 3},
+				{offset: 90 * time.Second, requests: 3, allowed: 2},
+			},
+		},
+		{
+			name:  "windows older than the previous one are forgotten",
+			limit: 10,
+			steps: []step{
+				{offset: 0, requests: 10, allowed: 10},
+				{offset: 120 * time.Second, requests: 11, allowed: 10},
+			},
+		},
+		{
+			name:  "no double burst across a window boundary",
+			limit: 10,
+			steps: slidingWindowBoundarySteps,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			rl := NewSlidingWindowRateLimiter(tt.limit, time.Minute, WithClock(clock.Now)).(*SlidingWindowRateLimiter)
+			defer rl.Close()
+
+			runSteps(t, rl, clock, tt.steps)
+		})
+	}
+}
+
+func TestSlidingWindowRateLimiter_Take(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	rl := NewSlidingWindowRateLimiter(10, time.Minute, WithClock(clock.Now))
+	defer rl.(*SlidingWindowRateLimiter).Close()
+
+	runTakeSteps(t, rl, clock, slidingWindowQuotaSteps)
+}
+
+func TestSlidingWindowRateLimiter_DifferentIdentifiers(t *testing.T) {
+	t.Parallel()
+
+	rl := NewSlidingWindowRateLimiter(1, time.Minute).(*SlidingWindowRateLimiter)
+	defer rl.Close()
+	ctx := context.Background()
+
+	if allowed, _ := rl.Allow(ctx, "ip1"); !allowed {
+		t.Error("first request should be allowed")
+	}
+	if allowed, _ := rl.Allow(ctx, "ip1"); allowed {
+		t.Error("second request should be denied")
+	}
+	if allowed, _ := rl.Allow(ctx, "ip2"); !allowed {
+		t.Error("different identifiers should have separate counters")
+	}
+}
+
+func TestSlidingWindowRateLimiter_RemovesIdleCounters(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	rl := NewSlidingWindowRateLimiter(5, time.Minute, WithClock(clock.Now)).(*SlidingWindowRateLimiter)
+	defer rl.Close()
+
+	rl.Allow(context.Background(), "test-ip")
+
+	clock.Set(90 * time.Second)
+	rl.removeIdle()
+	if len(rl.counters) != 1 {
+		t.Error("a counter that still weighs on the sliding window should be kept")
+	}
+
+	clock.Set(120 * time.Second)
+	rl.removeIdle()
+	if len(rl.counters) != 0 {
+		t.Error("an idle counter should be removed")
+	}
+}
@@ -0,0 +1,117 @@
+package ratelimiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// TokenBucketRateLimiter gives every identifier a bucket of limit tokens that
+// refills continuously at limit per window. A burst can spend the whole
+// bucket, but afterwards requests are spread out at the refill rate, so there
+// is no boundary at which the full limit becomes available twice.
+type TokenBucketRateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	limit   float64
+	rate    float64 // tokens per nanosecond
+	window  time.Duration
+	now     func() time.Time
+	sweeper *sweeper
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucketRateLimiter(limit int, window time.Duration, opts ...Option) domain.RateLimiter {
+	o := newOptions(opts)
+	rl := &TokenBucketRateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		limit:   float64(limit),
+		rate:    float64(limit) / float64(window),
+		window:  window,
+		now:     o.now,
+	}
+	rl.sweeper = startSweeper(window, rl.removeFull)
+	return rl
+}
+
+func (rl *TokenBucketRateLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return allow(rl.Take(ctx, identifier))
+}
+
+func (rl *TokenBucketRateLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	b, exists := rl.buckets[identifier]
+	if !exists {
+		b = &tokenBucket{tokens: rl.limit, last: now}
+		rl.buckets[identifier] = b
+	}
+	rl.refill(b, now)
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return tokenBucketResult(allowed, rl.limit, b.tokens, rl.rate), nil
+}
+
+func (rl *TokenBucketRateLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	b, exists := rl.buckets[identifier]
+	if !exists {
+		return tokenBucketResult(true, rl.limit, rl.limit, rl.rate), nil
+	}
+	peeked := *b
+	rl.refill(&peeked, rl.now())
+	return tokenBucketResult(peeked.tokens >= 1, rl.limit, peeked.tokens, rl.rate), nil
+}
+
+// tokenBucketResult reports a bucket left with tokens, refilling at rate
+// tokens per nanosecond.
+func tokenBucketResult(allowed bool, limit, tokens, rate float64) *domain.RateLimitResult {
+	result := &domain.RateLimitResult{
+		Allowed:   allowed,
+		Limit:     int(limit),
+		Remaining: int(tokens),
+		Reset:     time.Duration(math.Ceil((limit - tokens) / rate)),
+	}
+	if result.Remaining == 0 {
+		result.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate))
+	}
+	return result
+}
+
+func (rl *TokenBucketRateLimiter) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(rl.limit, b.tokens+float64(elapsed)*rl.rate)
+		b.last = now
+	}
+}
+
+// removeFull forgets buckets that have refilled completely, since a new
+// bucket starts out full anyway.
+func (rl *TokenBucketRateLimiter) removeFull() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	for id, b := range rl.buckets {
+		if now.Sub(b.last) >= rl.window {
+			delete(rl.buckets, id)
+		}
+	}
+}
+
+func (rl *TokenBucketRateLimiter) Close() {
+	rl.sweeper.Close()
+}
@@ -0,0 +1,128 @@
+package ratelimiter
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// tokenBucketBoundarySteps spends 10 requests right before a minute boundary
+// and tries 10 more right after it. Only the tokens refilled in between are
+// available, instead of a fresh limit.
+var tokenBucketBoundarySteps = []step{
+	{offset: 0, requests: 1, allowed: 1},
+	{offset: 59 * time.Second, requests: 9, allowed: 9},
+	{offset: 61 * time.Second, requests: 10, allowed: 1},
+	{offset: 120 * time.Second, requests: 11, allowed: 10},
+}
+
+// tokenBucketQuotaSteps drains a bucket that refills one token every 6s.
+var tokenBucketQuotaSteps = []takeStep{
+	{offset: 0, requests: 1, want: domain.RateLimitResult{Allowed: true, Limit: 10, Remaining: 9, Reset: 6 * time.Second}},
+	{offset: 0, requests: 9, want: domain.RateLimitResult{Allowed: true, Limit: 10, Reset: time.Minute, RetryAfter: 6 * time.Second}},
+	{offset: 3 * time.Second, requests: 1, want: domain.RateLimitResult{Limit: 10, Reset: 57 * time.Second, RetryAfter: 3 * time.Second}},
+	{offset: 15 * time.Second, requests: 1, want: domain.RateLimitResult{Allowed: true, Limit: 10, Remaining: 1, Reset: 51 * time.Second}},
+}
+
+func TestTokenBucketRateLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		limit int
+		steps []step
+	}{
+		{
+			name:  "full bucket allows a burst up to the limit",
+			limit: 5,
+			steps: []step{{offset: 0, requests: 6, allowed: 5}},
+		},
+		{
+			name:  "tokens refill at limit per window",
+			limit: 6,
+			steps: []step{
+				{offset: 0, requests: 6, allowed: 6},
+				{offset: 5 * time.Second, requests: 1, allowed: 0},
+				{offset: 11 * time.Second, requests: 2, allowed: 1},
+				{offset: 31 * time.Second, requests: 4, allowed: 2},
+			},
+		},
+		{
+			name:  "refill never exceeds the limit",
+			limit: 3,
+			steps: []step{
+				{offset: 0, requests: 1, allowed: 1},
+				{offset: time.Hour, requests: 4, allowed: 3},
+			},
+		},
+		{
+			name:  "no double burst across a window boundary",
+			limit: 10,
+			steps: tokenBucketBoundarySteps,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			rl := NewTokenBucketRateLimiter(tt.limit, time.Minute, WithClock(clock.Now)).(*TokenBucketRateLimiter)
+			defer rl.Close()
+
+			runSteps(t, rl, clock, tt.steps)
+		})
+	}
+}
+
+func TestTokenBucketRateLimiter_Take(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	rl := NewTokenBucketRateLimiter(10, time.Minute, WithClock(clock.Now))
+	defer rl.(*TokenBucketRateLimiter).Close()
+
+	runTakeSteps(t, rl, clock, tokenBucketQuotaSteps)
+}
+
+func TestTokenBucketRateLimiter_DifferentIdentifiers(t *testing.T) {
+	t.Parallel()
+
+	rl := NewTokenBucketRateLimiter(1, time.Minute).(*TokenBucketRateLimiter)
+	defer rl.Close()
+	ctx := context.Background()
+
+	if allowed, _ := rl.Allow(ctx, "ip1"); !allowed {
+		t.Error("first request should be allowed")
+	}
+	if allowed, _ := rl.Allow(ctx, "ip1"); allowed {
+		t.Error("second request should be denied")
+	}
+	if allowed, _ := rl.Allow(ctx, "ip2"); !allowed {
+		t.Error("different identifiers should have separate buckets")
+	}
+}
+
+func TestTokenBucketRateLimiter_RemovesFullBuckets(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	rl := NewTokenBucketRateLimiter(5, time.Minute, WithClock(clock.Now)).(*TokenBucketRateLimiter)
+	defer rl.Close()
+
+	rl.Allow(context.Background(), "test-ip")
+
+	clock.Set(30 * time.Second)
+	rl.removeFull()
+	if len(rl.buckets) != 1 {
+		t.Error("a bucket that is still refilling should be kept")
+	}
+
+	clock.Set(time.Minute)
+	rl.removeFull()
+	if len(rl.buckets) != 0 {
+		t.Error("a full bucket should be removed")
+	}
+}
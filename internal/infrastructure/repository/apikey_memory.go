@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+var errAPIKeyExists = errors.New("api key already exists")
+
+type MemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[string]*domain.APIKey // by ID
+	byHash map[string]string
+}
+
+func NewMemoryAPIKeyRepository() domain.APIKeyRepository {
+	return &MemoryAPIKeyRepository{
+		keys:   make(map[string]*domain.APIKey),
+		byHash: make(map[string]string),
+	}
+}
+
+func (r *MemoryAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.keys[key.ID]; exists {
+		return errAPIKeyExists
+	}
+	if _, exists := r.byHash[key.Hash]; exists {
+		return errAPIKeyExists
+	}
+
+	stored := *key
+	r.keys[key.ID] = &stored
+	r.byHash[key.Hash] = key.ID
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) FindByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, exists := r.keys[r.byHash[hash]]
+	if !exists {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	found := *key
+	return &found, nil
+}
+
+func (r *MemoryAPIKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*domain.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		found := *key
+		keys = append(keys, &found)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Revoke keeps the time of the first revocation.
+func (r *MemoryAPIKeyRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, exists := r.keys[id]
+	if !exists {
+		return domain.ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &revokedAt
+	}
+	return nil
+}
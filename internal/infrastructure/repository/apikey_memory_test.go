@@ -0,0 +1,57 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryAPIKeyRepository(t *testing.T) {
+	testAPIKeyRepository(t, repository.NewMemoryAPIKeyRepository())
+}
+
+// testAPIKeyRepository is shared by the API key repository implementations.
+func testAPIKeyRepository(t *testing.T, repo domain.APIKeyRepository) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	first := &domain.APIKey{ID: "k1", Name: "ci", OwnerID: "k1", Hash: "hash1", CreatedAt: now.Add(-time.Minute)}
+	second := &domain.APIKey{ID: "k2", Name: "backend", OwnerID: "team", Hash: "hash2", CreatedAt: now, Role: domain.RoleAdmin}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+	assert.Error(t, repo.Create(ctx, &domain.APIKey{ID: "k3", Hash: "hash1", CreatedAt: now}), "hashes are unique")
+
+	found, err := repo.FindByHash(ctx, "hash2")
+	require.NoError(t, err)
+	assert.Equal(t, "k2", found.ID)
+	assert.Equal(t, "backend", found.Name)
+	assert.Equal(t, "team", found.OwnerID)
+	assert.Equal(t, domain.RoleAdmin, found.Role)
+	assert.True(t, now.Equal(found.CreatedAt))
+	assert.Nil(t, found.RevokedAt)
+
+	_, err = repo.FindByHash(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrAPIKeyNotFound)
+
+	require.NoError(t, repo.Revoke(ctx, "k1", now))
+	require.NoError(t, repo.Revoke(ctx, "k1", now.Add(time.Hour)))
+	assert.ErrorIs(t, repo.Revoke(ctx, "missing", now), domain.ErrAPIKeyNotFound)
+
+	found, err = repo.FindByHash(ctx, "hash1")
+	require.NoError(t, err)
+	require.NotNil(t, found.RevokedAt, "revoked keys are still found")
+	assert.Empty(t, found.Role)
+	assert.True(t, now.Equal(*found.RevokedAt), "the first revocation is kept")
+
+	keys, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k1", keys[0].ID)
+	assert.Equal(t, "k2", keys[1].ID)
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisAPIKeyPrefix     = "apikey:"
+	redisAPIKeyHashPrefix = "apikeys:by_hash:"
+	redisAPIKeyIndexKey   = "apikeys:by_created"
+)
+
+// createAPIKeyScript claims the hash and stores the key in one atomic step.
+var createAPIKeyScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("EXISTS", KEYS[2]) == 1 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1])
+redis.call("SET", KEYS[2], ARGV[2])
+redis.call("ZADD", KEYS[3], ARGV[3], ARGV[2])
+return 1
+`)
+
+type redisAPIKey struct {
+	Name      string      `json:"name"`
+	OwnerID   string      `json:"owner_id"`
+	Hash      string      `json:"hash"`
+	CreatedAt time.Time   `json:"created_at"`
+	RevokedAt *time.Time  `json:"revoked_at,omitempty"`
+	Role      domain.Role `json:"role,omitempty"`
+}
+
+type RedisAPIKeyRepository struct {
+	client *redis.Client
+}
+
+func NewRedisAPIKeyRepository(client *redis.Client) domain.APIKeyRepository {
+	return &RedisAPIKeyRepository{client: client}
+}
+
+func (r *RedisAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	payload, err := json.Marshal(redisAPIKey{
+		Name:      key.Name,
+		OwnerID:   key.OwnerID,
+		Hash:      key.Hash,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+		Role:      key.Role,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode api key: %w", err)
+	}
+
+	created, err := createAPIKeyScript.Run(ctx, r.client,
+		[]string{redisAPIKeyPrefix + key.ID, redisAPIKeyHashPrefix + key.Hash, redisAPIKeyIndexKey},
+		payload, key.ID, key.CreatedAt.UnixMilli(),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	if created == 0 {
+		return errAPIKeyExists
+	}
+	return nil
+}
+
+func (r *RedisAPIKeyRepository) FindByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	id, err := r.client.Get(ctx, redisAPIKeyHashPrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	return r.find(ctx, id)
+}
+
+func (r *RedisAPIKeyRepository) find(ctx context.Context, id string) (*domain.APIKey, error) {
+	payload, err := r.client.Get(ctx, redisAPIKeyPrefix+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	return decodeRedisAPIKey(id, payload)
+}
+
+func (r *RedisAPIKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	ids, err := r.client.ZRange(ctx, redisAPIKeyIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	keys := make([]*domain.APIKey, 0, len(ids))
+	for _, id := range ids {
+		key, err := r.find(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Revoke keeps the time of the first revocation.
+func (r *RedisAPIKeyRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	k := redisAPIKeyPrefix + id
+
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		payload, err := tx.Get(ctx, k).Result()
+		if errors.Is(err, redis.Nil) {
+			return domain.ErrAPIKeyNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var stored redisAPIKey
+		if err := json.Unmarshal([]byte(payload), &stored); err != nil {
+			return err
+		}
+		if stored.RevokedAt != nil {
+			return nil
+		}
+		stored.RevokedAt = &revokedAt
+		updated, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, k, updated, 0)
+			return nil
+		})
+		return err
+	}, k)
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+func decodeRedisAPIKey(id, payload string) (*domain.APIKey, error) {
+	var stored redisAPIKey
+	if err := json.Unmarshal([]byte(payload), &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode api key %s: %w", id, err)
+	}
+
+	return &domain.APIKey{
+		ID:        id,
+		Name:      stored.Name,
+		OwnerID:   stored.OwnerID,
+		Hash:      stored.Hash,
+		CreatedAt: stored.CreatedAt,
+		RevokedAt: stored.RevokedAt,
+		Role:      stored.Role,
+	}, nil
+}
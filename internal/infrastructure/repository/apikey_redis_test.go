@@ -0,0 +1,17 @@
+package repository_test
+
+import (
+	"testing"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisAPIKeyRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	testAPIKeyRepository(t, repository.NewRedisAPIKeyRepository(client))
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/sqlstore"
+)
+
+var apiKeyMigrations = []string{
+	`CREATE TABLE api_keys (
+		id         TEXT   PRIMARY KEY,
+		name       TEXT   NOT NULL,
+		owner_id   TEXT   NOT NULL,
+		key_hash   TEXT   NOT NULL UNIQUE,
+		created_at BIGINT NOT NULL,
+		revoked_at BIGINT
+	)`,
+	`ALTER TABLE api_keys ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+}
+
+const apiKeyColumns = "id, name, owner_id, key_hash, created_at, revoked_at, role"
+
+type SQLAPIKeyRepository struct {
+	db      *sql.DB
+	dialect sqlstore.Dialect
+}
+
+// NewSQLAPIKeyRepository stores API keys in db, which is typically shared
+// with the URL repository of the same driver. The caller keeps ownership of
+// db.
+func NewSQLAPIKeyRepository(db *sql.DB, dialect sqlstore.Dialect) (domain.APIKeyRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sqlstore.Migrate(ctx, db, "api_keys", apiKeyMigrations); err != nil {
+		return nil, err
+	}
+
+	return &SQLAPIKeyRepository{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+func (r *SQLAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	_, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		key.ID, key.Name, key.OwnerID, key.Hash, key.CreatedAt.UnixNano(), nullableUnixNano(key.RevokedAt), key.Role,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLAPIKeyRepository) FindByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`),
+		hash,
+	)
+
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *SQLAPIKeyRepository) List(ctx context.Context) ([]*domain.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*domain.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke keeps the time of the first revocation.
+func (r *SQLAPIKeyRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`),
+		revokedAt.UnixNano(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	var (
+		key       domain.APIKey
+		createdAt int64
+		revokedAt sql.NullInt64
+	)
+	if err := row.Scan(&key.ID, &key.Name, &key.OwnerID, &key.Hash, &createdAt, &revokedAt, &key.Role); err != nil {
+		return nil, err
+	}
+
+	key.CreatedAt = time.Unix(0, createdAt)
+	if revokedAt.Valid {
+		t := time.Unix(0, revokedAt.Int64)
+		key.RevokedAt = &t
+	}
+	return &key, nil
+}
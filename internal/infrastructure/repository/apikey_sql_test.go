@@ -0,0 +1,27 @@
+package repository_test
+
+import (
+	"testing"
+	"url-shortener/internal/infrastructure/repository"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLAPIKeyRepository_SQLite(t *testing.T) {
+	urlRepo := newSQLiteRepo(t, ":memory:", 0)
+
+	repo, err := repository.NewSQLAPIKeyRepository(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+
+	testAPIKeyRepository(t, repo)
+}
+
+func TestSQLAPIKeyRepository_Postgres(t *testing.T) {
+	urlRepo := newPostgresRepo(t, 0)
+
+	repo, err := repository.NewSQLAPIKeyRepository(urlRepo.DB(), sqlstore.DialectPostgres)
+	require.NoError(t, err)
+
+	testAPIKeyRepository(t, repo)
+}
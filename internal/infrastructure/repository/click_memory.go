@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+type MemoryClickRepository struct {
+	mu     sync.RWMutex
+	clicks map[string][]time.Time
+}
+
+func NewMemoryClickRepository() domain.ClickRepository {
+	return &MemoryClickRepository{
+		clicks: make(map[string][]time.Time),
+	}
+}
+
+// SaveClicks keeps only the timestamps, which is all Stats needs.
+func (r *MemoryClickRepository) SaveClicks(ctx context.Context, clicks []*domain.Click) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, click := range clicks {
+		r.clicks[click.ShortCode] = append(r.clicks[click.ShortCode], click.Timestamp)
+	}
+	return nil
+}
+
+func (r *MemoryClickRepository) Stats(ctx context.Context, shortCode string, since, from, to time.Time, interval time.Duration) (*domain.ClickStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var total int64
+	counts := make(map[int64]int64)
+	for _, ts := range r.clicks[shortCode] {
+		if ts.Before(since) {
+			continue
+		}
+		total++
+		if ts.Before(from) || !ts.Before(to) {
+			continue
+		}
+		counts[int64(ts.Sub(from)/interval)]++
+	}
+
+	stats := &domain.ClickStats{
+		ShortCode: shortCode,
+		Total:     total,
+		Buckets:   make([]domain.ClickBucket, 0, len(counts)),
+	}
+	for n, count := range counts {
+		stats.Buckets = append(stats.Buckets, domain.ClickBucket{
+			Start: from.Add(time.Duration(n) * interval),
+			Count: count,
+		})
+	}
+	sort.Slice(stats.Buckets, func(i, j int) bool {
+		return stats.Buckets[i].Start.Before(stats.Buckets[j].Start)
+	})
+
+	return stats, nil
+}
@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryClickRepository_Stats(t *testing.T) {
+	testClickRepositoryStats(t, repository.NewMemoryClickRepository())
+}
+
+// testClickRepositoryStats is shared by the click repository implementations.
+func testClickRepositoryStats(t *testing.T, repo domain.ClickRepository) {
+	t.Helper()
+	ctx := context.Background()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	clicks := []*domain.Click{
+		{ShortCode: "abc123", Timestamp: from.Add(-time.Minute)}, // before the range
+		{ShortCode: "abc123", Timestamp: from},
+		{ShortCode: "abc123", Timestamp: from.Add(59 * time.Minute)},
+		{ShortCode: "abc123", Timestamp: from.Add(2*time.Hour + time.Second), Referrer: "https://news.example", UserAgent: "curl/8.0"},
+		{ShortCode: "abc123", Timestamp: from.Add(3 * time.Hour)}, // end is exclusive
+		{ShortCode: "other", Timestamp: from},
+	}
+	require.NoError(t, repo.SaveClicks(ctx, clicks))
+
+	stats, err := repo.Stats(ctx, "abc123", time.Time{}, from, from.Add(3*time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), stats.Total)
+	require.Len(t, stats.Buckets, 2)
+	assert.True(t, from.Equal(stats.Buckets[0].Start))
+	assert.Equal(t, int64(2), stats.Buckets[0].Count)
+	assert.True(t, from.Add(2*time.Hour).Equal(stats.Buckets[1].Start))
+	assert.Equal(t, int64(1), stats.Buckets[1].Count)
+
+	stats, err = repo.Stats(ctx, "missing", time.Time{}, from, from.Add(time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+	assert.Empty(t, stats.Buckets)
+
+	// A code reissued at from+1h does not inherit the earlier link's clicks.
+	stats, err = repo.Stats(ctx, "abc123", from.Add(time.Hour), from, from.Add(3*time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+	require.Len(t, stats.Buckets, 1)
+	assert.True(t, from.Add(2*time.Hour).Equal(stats.Buckets[0].Start))
+	assert.Equal(t, int64(1), stats.Buckets[0].Count)
+}
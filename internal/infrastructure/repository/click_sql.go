@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/sqlstore"
+)
+
+// Timestamps are stored as unix nanoseconds so the same schema and bucket
+// arithmetic work on both SQLite and PostgreSQL.
+var clickMigrations = []string{
+	`CREATE TABLE clicks (
+		short_code TEXT   NOT NULL,
+		clicked_at BIGINT NOT NULL,
+		referrer   TEXT   NOT NULL,
+		user_agent TEXT   NOT NULL,
+		country    TEXT   NOT NULL
+	)`,
+	`CREATE INDEX idx_clicks_short_code_clicked_at ON clicks (short_code, clicked_at)`,
+}
+
+type SQLClickRepository struct {
+	db      *sql.DB
+	dialect sqlstore.Dialect
+}
+
+// NewSQLClickRepository stores clicks in db, which is typically shared with
+// the URL repository of the same driver. The caller keeps ownership of db.
+func NewSQLClickRepository(db *sql.DB, dialect sqlstore.Dialect) (domain.ClickRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sqlstore.Migrate(ctx, db, "clicks", clickMigrations); err != nil {
+		return nil, err
+	}
+
+	return &SQLClickRepository{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+func (r *SQLClickRepository) SaveClicks(ctx context.Context, clicks []*domain.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save clicks: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, r.dialect.Rebind(`
+		INSERT INTO clicks (short_code, clicked_at, referrer, user_agent, country)
+		VALUES (?, ?, ?, ?, ?)`,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to save clicks: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, click := range clicks {
+		if _, err := stmt.ExecContext(ctx,
+			click.ShortCode, click.Timestamp.UnixNano(), click.Referrer, click.UserAgent, click.Country,
+		); err != nil {
+			return fmt.Errorf("failed to save clicks: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save clicks: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLClickRepository) Stats(ctx context.Context, shortCode string, since, from, to time.Time, interval time.Duration) (*domain.ClickStats, error) {
+	stats := &domain.ClickStats{
+		ShortCode: shortCode,
+		Buckets:   []domain.ClickBucket{},
+	}
+
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind(
+		`SELECT COUNT(*) FROM clicks WHERE short_code = ? AND clicked_at >= ?`),
+		shortCode, since.UnixNano(),
+	).Scan(&stats.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	start := from
+	if since.After(start) {
+		start = since
+	}
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(`
+		SELECT (clicked_at - ?) / ? AS bucket, COUNT(*) FROM clicks
+		WHERE short_code = ? AND clicked_at >= ? AND clicked_at < ?
+		GROUP BY bucket
+		ORDER BY bucket`),
+		from.UnixNano(), int64(interval), shortCode, start.UnixNano(), to.UnixNano(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n, count int64
+		if err := rows.Scan(&n, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan click bucket: %w", err)
+		}
+		stats.Buckets = append(stats.Buckets, domain.ClickBucket{
+			Start: from.Add(time.Duration(n) * interval),
+			Count: count,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,38 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"url-shortener/internal/infrastructure/repository"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLClickRepository_SQLite(t *testing.T) {
+	urlRepo := newSQLiteRepo(t, ":memory:", 0)
+
+	repo, err := repository.NewSQLClickRepository(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+
+	testClickRepositoryStats(t, repo)
+}
+
+func TestSQLClickRepository_Postgres(t *testing.T) {
+	urlRepo := newPostgresRepo(t, 0)
+
+	repo, err := repository.NewSQLClickRepository(urlRepo.DB(), sqlstore.DialectPostgres)
+	require.NoError(t, err)
+
+	testClickRepositoryStats(t, repo)
+}
+
+func TestSQLClickRepository_SaveClicksEmpty(t *testing.T) {
+	urlRepo := newSQLiteRepo(t, ":memory:", 0)
+
+	repo, err := repository.NewSQLClickRepository(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+
+	assert.NoError(t, repo.SaveClicks(context.Background(), nil))
+}
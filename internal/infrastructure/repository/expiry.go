@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"time"
+	"url-shortener/internal/domain"
+)
+
+// applyTTL treats the storage TTL as both the default and the maximum
+// lifetime: links without an expiry get the TTL, and links asking for more
+// are capped at it. A zero TTL leaves the caller's choice untouched.
+func applyTTL(url *domain.URL, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	maxExpiresAt := time.Now().Add(ttl)
+	if url.ExpiresAt == nil || url.ExpiresAt.After(maxExpiresAt) {
+		url.ExpiresAt = &maxExpiresAt
+	}
+}
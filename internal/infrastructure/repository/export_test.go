@@ -0,0 +1,7 @@
+package repository
+
+import "context"
+
+func (r *SQLiteURLRepository) CleanupExpired(ctx context.Context) error {
+	return r.cleanupExpired(ctx)
+}
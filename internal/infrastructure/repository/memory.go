@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"sort"
 	"sync"
 	"time"
 	"url-shortener/internal/domain"
@@ -10,6 +11,7 @@ import (
 type MemoryURLRepository struct {
 	mu            sync.RWMutex
 	urls          map[string]*domain.URL
+	byLongURL     map[string]string // canonical long URL to the newest short code
 	ttl           time.Duration
 	cleanupTicker *time.Ticker
 	stopCleanup   chan struct{}
@@ -18,6 +20,7 @@ type MemoryURLRepository struct {
 func NewMemoryURLRepository(ttl time.Duration) domain.URLRepository {
 	repo := &MemoryURLRepository{
 		urls:        make(map[string]*domain.URL),
+		byLongURL:   make(map[string]string),
 		ttl:         ttl,
 		stopCleanup: make(chan struct{}),
 	}
@@ -27,16 +30,29 @@ func NewMemoryURLRepository(ttl time.Duration) domain.URLRepository {
 	return repo
 }
 
-func (r *MemoryURLRepository) Save(ctx context.Context, url *domain.URL) error {
+func (r *MemoryURLRepository) Create(ctx context.Context, url *domain.URL) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if r.ttl > 0 {
-		expiresAt := time.Now().Add(r.ttl)
-		url.ExpiresAt = &expiresAt
+	if existing, exists := r.urls[url.ShortCode]; exists && !existing.IsExpired() {
+		return domain.ErrShortCodeExists
 	}
 
+	applyTTL(url, r.ttl)
+
 	r.urls[url.ShortCode] = url
+	r.indexLongURL(url)
+	return nil
+}
+
+func (r *MemoryURLRepository) Save(ctx context.Context, url *domain.URL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	applyTTL(url, r.ttl)
+
+	r.urls[url.ShortCode] = url
+	r.indexLongURL(url)
 	return nil
 }
 
@@ -45,13 +61,40 @@ func (r *MemoryURLRepository) FindByShortCode(ctx context.Context, shortCode str
 	defer r.mu.RUnlock()
 
 	url, exists := r.urls[shortCode]
-	if !exists {
+	if !exists || url.DeletedAt != nil {
 		return nil, domain.ErrURLNotFound
 	}
 
 	return url, nil
 }
 
+// FindByLongURL checks the indexed link against longURL again, since the
+// index is not updated when a link's destination changes away from it.
+func (r *MemoryURLRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URL, error) {
+	key, err := domain.CanonicalURL(longURL)
+	if err != nil {
+		return nil, domain.ErrURLNotFound
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	url, err := r.findLive(r.byLongURL[key])
+	if err != nil {
+		return nil, err
+	}
+	if current, err := domain.CanonicalURL(url.LongURL); err != nil || current != key {
+		return nil, domain.ErrURLNotFound
+	}
+	return url, nil
+}
+
+func (r *MemoryURLRepository) indexLongURL(url *domain.URL) {
+	if key, err := domain.CanonicalURL(url.LongURL); err == nil {
+		r.byLongURL[key] = url.ShortCode
+	}
+}
+
 func (r *MemoryURLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -60,6 +103,103 @@ func (r *MemoryURLRepository) Exists(ctx context.Context, shortCode string) (boo
 	return exists, nil
 }
 
+func (r *MemoryURLRepository) List(ctx context.Context, opts domain.ListOptions) ([]*domain.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	urls := make([]*domain.URL, 0, len(r.urls))
+	for _, url := range r.urls {
+		if url.IsExpired() || url.DeletedAt != nil {
+			continue
+		}
+		if opts.OwnerID != "" && url.OwnerID != opts.OwnerID {
+			continue
+		}
+		urls = append(urls, url)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		if urls[i].CreatedAt.Equal(urls[j].CreatedAt) {
+			return urls[i].ShortCode < urls[j].ShortCode
+		}
+		return urls[i].CreatedAt.After(urls[j].CreatedAt)
+	})
+
+	return paginate(urls, opts), nil
+}
+
+// Update and Delete store modified copies, since URLs handed out by
+// FindByShortCode may still be in use by callers.
+func (r *MemoryURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, err := r.findLive(url.ShortCode)
+	if err != nil {
+		return err
+	}
+
+	updated := *existing
+	updated.LongURL = url.LongURL
+	updated.RedirectStatus = url.RedirectStatus
+	r.urls[url.ShortCode] = &updated
+	r.indexLongURL(&updated)
+	return nil
+}
+
+func (r *MemoryURLRepository) Moderate(ctx context.Context, url *domain.URL) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, err := r.findLive(url.ShortCode)
+	if err != nil {
+		return err
+	}
+
+	updated := *existing
+	updated.OwnerID = url.OwnerID
+	updated.DisabledAt = url.DisabledAt
+	r.urls[url.ShortCode] = &updated
+	r.indexLongURL(&updated)
+	return nil
+}
+
+func (r *MemoryURLRepository) Delete(ctx context.Context, shortCode string, tombstoneUntil time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, err := r.findLive(shortCode)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tombstone := *existing
+	tombstone.DeletedAt = &now
+	tombstone.ExpiresAt = &tombstoneUntil
+	r.urls[shortCode] = &tombstone
+	return nil
+}
+
+func (r *MemoryURLRepository) findLive(shortCode string) (*domain.URL, error) {
+	url, exists := r.urls[shortCode]
+	if !exists || url.DeletedAt != nil || url.IsExpired() {
+		return nil, domain.ErrURLNotFound
+	}
+	return url, nil
+}
+
+func paginate(urls []*domain.URL, opts domain.ListOptions) []*domain.URL {
+	if opts.Offset >= len(urls) {
+		return []*domain.URL{}
+	}
+	urls = urls[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(urls) {
+		urls = urls[:opts.Limit]
+	}
+	return urls
+}
+
 func (r *MemoryURLRepository) startCleanup() {
 	r.cleanupTicker = time.NewTicker(1 * time.Minute)
 	go func() {
@@ -83,6 +223,11 @@ func (r *MemoryURLRepository) cleanupExpired() {
 			delete(r.urls, code)
 		}
 	}
+	for key, code := range r.byLongURL {
+		if _, exists := r.urls[code]; !exists {
+			delete(r.byLongURL, key)
+		}
+	}
 }
 
 func (r *MemoryURLRepository) Close() {
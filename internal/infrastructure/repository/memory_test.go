@@ -2,12 +2,15 @@ package repository_test
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 	"url-shortener/internal/domain"
 	"url-shortener/internal/infrastructure/repository"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewMemoryURLRepository(t *testing.T) {
@@ -65,6 +68,114 @@ func TestMemoryURLRepository_Save_WithTTL(t *testing.T) {
 	assert.True(t, url.ExpiresAt.After(time.Now()))
 }
 
+func TestMemoryURLRepository_Create(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	ctx := context.Background()
+	first := &domain.URL{ShortCode: "test123", LongURL: "https://example.com/first", CreatedAt: time.Now()}
+	second := &domain.URL{ShortCode: "test123", LongURL: "https://example.com/second", CreatedAt: time.Now()}
+
+	assert.NoError(t, repo.Create(ctx, first))
+	assert.Equal(t, domain.ErrShortCodeExists, repo.Create(ctx, second))
+
+	found, err := repo.FindByShortCode(ctx, "test123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/first", found.LongURL)
+}
+
+func TestMemoryURLRepository_Create_ReplacesExpired(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	ctx := context.Background()
+	expiredTime := time.Now().Add(-1 * time.Hour)
+	expired := &domain.URL{ShortCode: "test123", LongURL: "https://example.com/old", CreatedAt: time.Now(), ExpiresAt: &expiredTime}
+	fresh := &domain.URL{ShortCode: "test123", LongURL: "https://example.com/new", CreatedAt: time.Now()}
+
+	assert.NoError(t, repo.Save(ctx, expired))
+	assert.NoError(t, repo.Create(ctx, fresh))
+
+	found, err := repo.FindByShortCode(ctx, "test123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", found.LongURL)
+}
+
+func TestMemoryURLRepository_Create_Concurrent(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	ctx := context.Background()
+	const workers = 50
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := []string{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			longURL := fmt.Sprintf("https://example.com/%d", i)
+			err := repo.Create(ctx, &domain.URL{ShortCode: "race", LongURL: longURL, CreatedAt: time.Now()})
+			if err == nil {
+				mu.Lock()
+				winners = append(winners, longURL)
+				mu.Unlock()
+				return
+			}
+			assert.Equal(t, domain.ErrShortCodeExists, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, winners, 1, "exactly one concurrent create should succeed")
+	found, err := repo.FindByShortCode(ctx, "race")
+	assert.NoError(t, err)
+	assert.Equal(t, winners[0], found.LongURL, "stored link must belong to the winning create")
+}
+
+func TestMemoryURLRepository_Create_RespectsPresetExpiry(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(24 * time.Hour)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	ctx := context.Background()
+	shorter := time.Now().Add(1 * time.Hour)
+	longer := time.Now().Add(48 * time.Hour)
+
+	tests := []struct {
+		name      string
+		code      string
+		expiresAt *time.Time
+		expected  time.Time
+	}{
+		{name: "default applied", code: "default", expiresAt: nil, expected: time.Now().Add(24 * time.Hour)},
+		{name: "shorter expiry kept", code: "shorter", expiresAt: &shorter, expected: shorter},
+		{name: "longer expiry capped", code: "longer", expiresAt: &longer, expected: time.Now().Add(24 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := &domain.URL{ShortCode: tt.code, LongURL: "https://example.com", CreatedAt: time.Now(), ExpiresAt: tt.expiresAt}
+			assert.NoError(t, repo.Create(ctx, url))
+
+			found, err := repo.FindByShortCode(ctx, tt.code)
+			assert.NoError(t, err)
+			assert.NotNil(t, found.ExpiresAt)
+			assert.WithinDuration(t, tt.expected, *found.ExpiresAt, time.Second)
+		})
+	}
+}
+
+func TestMemoryURLRepository_Create_NoTTLKeepsNever(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	url := &domain.URL{ShortCode: "forever", LongURL: "https://example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(context.Background(), url))
+	assert.Nil(t, url.ExpiresAt)
+}
+
 func TestMemoryURLRepository_FindByShortCode(t *testing.T) {
 	repo := repository.NewMemoryURLRepository(0)
 	defer func() {
@@ -241,3 +352,281 @@ func TestMemoryURLRepository_StartCleanup(t *testing.T) {
 		memRepo.Close()
 	}
 }
+
+func TestMemoryURLRepository_List(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	expired := now.Add(-1 * time.Hour)
+
+	urls := []*domain.URL{
+		{ShortCode: "old", LongURL: "https://example.com/old", CreatedAt: now.Add(-2 * time.Minute)},
+		{ShortCode: "new", LongURL: "https://example.com/new", CreatedAt: now},
+		{ShortCode: "mid", LongURL: "https://example.com/mid", CreatedAt: now.Add(-1 * time.Minute)},
+		{ShortCode: "gone", LongURL: "https://example.com/gone", CreatedAt: now, ExpiresAt: &expired},
+	}
+	for _, u := range urls {
+		assert.NoError(t, repo.Save(ctx, u))
+	}
+
+	all, err := repo.List(ctx, domain.ListOptions{})
+	assert.NoError(t, err)
+	codes := make([]string, 0, len(all))
+	for _, u := range all {
+		codes = append(codes, u.ShortCode)
+	}
+	assert.Equal(t, []string{"new", "mid", "old"}, codes)
+
+	page, err := repo.List(ctx, domain.ListOptions{Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "mid", page[0].ShortCode)
+
+	empty, err := repo.List(ctx, domain.ListOptions{Limit: 10, Offset: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestMemoryURLRepository_ListByOwner(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	testURLRepositoryListByOwner(t, repo)
+}
+
+// testURLRepositoryListByOwner is shared by the URL repository
+// implementations.
+func testURLRepositoryListByOwner(t *testing.T, repo domain.URLRepository) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, u := range []struct{ code, owner string }{
+		{"anon01", ""},
+		{"alice1", "alice"},
+		{"bob001", "bob"},
+		{"alice2", "alice"},
+	} {
+		require.NoError(t, repo.Create(ctx, &domain.URL{
+			ShortCode: u.code,
+			LongURL:   "https://example.com/" + u.code,
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+			OwnerID:   u.owner,
+		}))
+	}
+
+	found, err := repo.FindByShortCode(ctx, "alice1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", found.OwnerID)
+
+	codes := func(opts domain.ListOptions) []string {
+		urls, err := repo.List(ctx, opts)
+		require.NoError(t, err)
+		codes := []string{}
+		for _, u := range urls {
+			codes = append(codes, u.ShortCode)
+		}
+		return codes
+	}
+	assert.Equal(t, []string{"alice2", "alice1"}, codes(domain.ListOptions{OwnerID: "alice"}))
+	assert.Equal(t, []string{"alice1"}, codes(domain.ListOptions{OwnerID: "alice", Limit: 1, Offset: 1}))
+	assert.Equal(t, []string{}, codes(domain.ListOptions{OwnerID: "carol"}))
+	assert.Len(t, codes(domain.ListOptions{}), 4)
+
+	// Editing a link keeps its owner.
+	require.NoError(t, repo.Update(ctx, &domain.URL{ShortCode: "bob001", LongURL: "https://example.com/moved"}))
+	found, err = repo.FindByShortCode(ctx, "bob001")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", found.OwnerID)
+}
+
+func TestMemoryURLRepository_Moderate(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	testURLRepositoryModerate(t, repo)
+}
+
+// testURLRepositoryModerate is shared by the URL repository implementations.
+func testURLRepositoryModerate(t *testing.T, repo domain.URLRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now(), OwnerID: "alice"}))
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.False(t, found.IsDisabled())
+
+	disabledAt := time.Now().Truncate(time.Microsecond)
+	require.NoError(t, repo.Moderate(ctx, &domain.URL{ShortCode: "abc123", OwnerID: "bob", DisabledAt: &disabledAt}))
+	found, err = repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL, "moderating keeps the destination")
+	assert.Equal(t, "bob", found.OwnerID)
+	require.True(t, found.IsDisabled())
+	assert.True(t, disabledAt.Equal(*found.DisabledAt))
+
+	owned, err := repo.List(ctx, domain.ListOptions{OwnerID: "bob"})
+	require.NoError(t, err)
+	assert.Len(t, owned, 1)
+	owned, err = repo.List(ctx, domain.ListOptions{OwnerID: "alice"})
+	require.NoError(t, err)
+	assert.Empty(t, owned, "the previous owner no longer lists a reassigned link")
+
+	// Editing a link keeps it disabled.
+	require.NoError(t, repo.Update(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/moved"}))
+	found, err = repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, found.IsDisabled())
+
+	require.NoError(t, repo.Moderate(ctx, &domain.URL{ShortCode: "abc123", OwnerID: "bob"}))
+	found, err = repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.False(t, found.IsDisabled())
+
+	assert.ErrorIs(t, repo.Moderate(ctx, &domain.URL{ShortCode: "missing"}), domain.ErrURLNotFound)
+}
+
+func TestMemoryURLRepository_Password(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	testURLRepositoryPassword(t, repo)
+}
+
+// testURLRepositoryPassword is shared by the URL repository implementations.
+func testURLRepositoryPassword(t *testing.T, repo domain.URLRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "secret", LongURL: "https://example.com/doc", CreatedAt: time.Now(), PasswordHash: "hash"}))
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "public", LongURL: "https://example.com", CreatedAt: time.Now()}))
+
+	found, err := repo.FindByShortCode(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", found.PasswordHash)
+	found, err = repo.FindByShortCode(ctx, "public")
+	require.NoError(t, err)
+	assert.False(t, found.IsProtected())
+
+	// Editing or moderating a link keeps its password.
+	require.NoError(t, repo.Update(ctx, &domain.URL{ShortCode: "secret", LongURL: "https://example.com/moved"}))
+	require.NoError(t, repo.Moderate(ctx, &domain.URL{ShortCode: "secret", OwnerID: "alice"}))
+	found, err = repo.FindByShortCode(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", found.PasswordHash)
+}
+
+func TestMemoryURLRepository_UpdateDelete(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	testURLRepositoryUpdateDelete(t, repo, nil)
+}
+
+// testURLRepositoryUpdateDelete is shared by the URL repository
+// implementations. lapse, if set, lets backends with their own clock catch up
+// with a tombstone dated in the past.
+func testURLRepositoryUpdateDelete(t *testing.T, repo domain.URLRepository, lapse func()) {
+	t.Helper()
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/typo", CreatedAt: time.Now(), RedirectStatus: 301}))
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, 301, found.RedirectStatus)
+
+	require.NoError(t, repo.Update(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/fixed", RedirectStatus: 307}))
+	found, err = repo.FindByShortCode(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/fixed", found.LongURL)
+	assert.Equal(t, 307, found.RedirectStatus)
+
+	assert.ErrorIs(t, repo.Update(ctx, &domain.URL{ShortCode: "missing", LongURL: "https://example.com"}), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, "missing", time.Now().Add(time.Hour)), domain.ErrURLNotFound)
+
+	require.NoError(t, repo.Delete(ctx, "abc123", time.Now().Add(time.Hour)))
+
+	_, err = repo.FindByShortCode(ctx, "abc123")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	listed, err := repo.List(ctx, domain.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+	exists, err := repo.Exists(ctx, "abc123")
+	require.NoError(t, err)
+	assert.True(t, exists, "tombstone keeps the code taken")
+
+	assert.ErrorIs(t, repo.Update(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com"}), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, "abc123", time.Now().Add(time.Hour)), domain.ErrURLNotFound)
+	assert.ErrorIs(t, repo.Create(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/new", CreatedAt: time.Now()}), domain.ErrShortCodeExists)
+
+	// once the tombstone lapses the code can be reissued
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "lapsed", LongURL: "https://example.com", CreatedAt: time.Now()}))
+	require.NoError(t, repo.Delete(ctx, "lapsed", time.Now().Add(-time.Second)))
+	if lapse != nil {
+		lapse()
+	}
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "lapsed", LongURL: "https://example.com/new", CreatedAt: time.Now()}))
+	found, err = repo.FindByShortCode(ctx, "lapsed")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", found.LongURL)
+}
+
+func TestMemoryURLRepository_FindByLongURL(t *testing.T) {
+	repo := repository.NewMemoryURLRepository(0)
+	defer repo.(*repository.MemoryURLRepository).Close()
+
+	testURLRepositoryFindByLongURL(t, repo)
+}
+
+// testURLRepositoryFindByLongURL is shared by the URL repository
+// implementations.
+func testURLRepositoryFindByLongURL(t *testing.T, repo domain.URLRepository) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+
+	first := &domain.URL{ShortCode: "first1", LongURL: "https://Example.com/a/?b=2&a=1", CreatedAt: now.Add(-2 * time.Minute)}
+	require.NoError(t, repo.Create(ctx, first))
+
+	found, err := repo.FindByLongURL(ctx, "https://example.com:443/a?a=1&b=2")
+	require.NoError(t, err)
+	assert.Equal(t, "first1", found.ShortCode)
+	assert.Equal(t, first.LongURL, found.LongURL, "the stored destination is kept as submitted")
+
+	_, err = repo.FindByLongURL(ctx, "https://example.com/b")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	_, err = repo.FindByLongURL(ctx, "not a url")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	second := &domain.URL{ShortCode: "second", LongURL: "https://example.com/a", CreatedAt: now.Add(-time.Minute)}
+	require.NoError(t, repo.Create(ctx, second))
+
+	found, err = repo.FindByLongURL(ctx, "https://example.com/a")
+	require.NoError(t, err)
+	assert.Equal(t, "second", found.ShortCode, "the newest link should win")
+
+	// Once the newest link points elsewhere it must no longer match.
+	require.NoError(t, repo.Update(ctx, &domain.URL{ShortCode: "second", LongURL: "https://example.com/moved"}))
+	found, err = repo.FindByLongURL(ctx, "https://example.com/a")
+	if err == nil {
+		assert.Equal(t, "first1", found.ShortCode)
+	} else {
+		assert.ErrorIs(t, err, domain.ErrURLNotFound)
+	}
+	found, err = repo.FindByLongURL(ctx, "https://example.com/moved/")
+	require.NoError(t, err)
+	assert.Equal(t, "second", found.ShortCode)
+
+	require.NoError(t, repo.Delete(ctx, "second", now.Add(time.Hour)))
+	_, err = repo.FindByLongURL(ctx, "https://example.com/moved")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+
+	past := now.Add(-time.Second)
+	expired := &domain.URL{ShortCode: "expird", LongURL: "https://example.com/old", CreatedAt: now.Add(-time.Hour), ExpiresAt: &past}
+	require.NoError(t, repo.Save(ctx, expired))
+	_, err = repo.FindByLongURL(ctx, "https://example.com/old")
+	assert.ErrorIs(t, err, domain.ErrURLNotFound)
+}
@@ -0,0 +1,336 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+var postgresMigrations = []string{
+	`CREATE TABLE urls (
+		id         BIGSERIAL   PRIMARY KEY,
+		short_code TEXT        NOT NULL,
+		long_url   TEXT        NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		expires_at TIMESTAMPTZ
+	)`,
+	`CREATE UNIQUE INDEX urls_short_code_key ON urls (short_code)`,
+	`CREATE INDEX urls_expires_at_idx ON urls (expires_at) WHERE expires_at IS NOT NULL`,
+	`CREATE INDEX urls_created_at_idx ON urls (created_at DESC, short_code)`,
+	`ALTER TABLE urls ADD COLUMN deleted_at TIMESTAMPTZ`,
+	`ALTER TABLE urls ADD COLUMN redirect_status INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE urls ADD COLUMN long_url_key TEXT`,
+	`CREATE INDEX urls_long_url_key_idx ON urls (long_url_key, created_at DESC) WHERE long_url_key IS NOT NULL`,
+	`ALTER TABLE urls ADD COLUMN owner_id TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX urls_owner_id_idx ON urls (owner_id, created_at DESC) WHERE owner_id <> ''`,
+	`ALTER TABLE urls ADD COLUMN disabled_at TIMESTAMPTZ`,
+	`ALTER TABLE urls ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+}
+
+const postgresURLColumns = "short_code, long_url, created_at, expires_at, redirect_status, owner_id, disabled_at, password_hash"
+
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type PostgresURLRepository struct {
+	db            *sql.DB
+	ttl           time.Duration
+	cleanupTicker *time.Ticker
+	stopCleanup   chan struct{}
+}
+
+func NewPostgresURLRepository(dsn string, ttl time.Duration, pool PoolConfig) (domain.URLRepository, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	if err := sqlstore.Migrate(ctx, db, "urls", postgresMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	repo := &PostgresURLRepository{
+		db:          db,
+		ttl:         ttl,
+		stopCleanup: make(chan struct{}),
+	}
+
+	repo.startCleanup()
+
+	return repo, nil
+}
+
+func (r *PostgresURLRepository) Create(ctx context.Context, url *domain.URL) error {
+	applyTTL(url, r.ttl)
+
+	// The unique index on short_code turns a concurrent insert of the same
+	// code into a conflict; only an expired row may be taken over.
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO urls (`+postgresURLColumns+`, long_url_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (short_code) DO UPDATE SET
+			long_url = EXCLUDED.long_url,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at,
+			redirect_status = EXCLUDED.redirect_status,
+			long_url_key = EXCLUDED.long_url_key,
+			owner_id = EXCLUDED.owner_id,
+			disabled_at = EXCLUDED.disabled_at,
+			password_hash = EXCLUDED.password_hash,
+			deleted_at = NULL
+		WHERE urls.expires_at IS NOT NULL AND urls.expires_at <= $10`,
+		url.ShortCode, url.LongURL, url.CreatedAt, url.ExpiresAt, url.RedirectStatus, url.OwnerID, url.DisabledAt, url.PasswordHash, longURLKey(url.LongURL), time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert url: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to insert url: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrShortCodeExists
+	}
+
+	return nil
+}
+
+func (r *PostgresURLRepository) Save(ctx context.Context, url *domain.URL) error {
+	applyTTL(url, r.ttl)
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO urls (`+postgresURLColumns+`, long_url_key) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (short_code) DO UPDATE SET
+			long_url = EXCLUDED.long_url,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at,
+			redirect_status = EXCLUDED.redirect_status,
+			long_url_key = EXCLUDED.long_url_key,
+			owner_id = EXCLUDED.owner_id,
+			disabled_at = EXCLUDED.disabled_at,
+			password_hash = EXCLUDED.password_hash,
+			deleted_at = NULL`,
+		url.ShortCode, url.LongURL, url.CreatedAt, url.ExpiresAt, url.RedirectStatus, url.OwnerID, url.DisabledAt, url.PasswordHash, longURLKey(url.LongURL),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save url: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresURLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+postgresURLColumns+` FROM urls
+		WHERE short_code = $1 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $2)`,
+		shortCode, time.Now(),
+	)
+
+	url, err := scanPostgresURL(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	return url, nil
+}
+
+func (r *PostgresURLRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URL, error) {
+	key, err := domain.CanonicalURL(longURL)
+	if err != nil {
+		return nil, domain.ErrURLNotFound
+	}
+
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+postgresURLColumns+` FROM urls
+		WHERE long_url_key = $1 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		key, time.Now(),
+	)
+
+	url, err := scanPostgresURL(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	return url, nil
+}
+
+func (r *PostgresURLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM urls
+			WHERE short_code = $1 AND (expires_at IS NULL OR expires_at > $2)
+		)`,
+		shortCode, time.Now(),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check url existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *PostgresURLRepository) List(ctx context.Context, opts domain.ListOptions) ([]*domain.URL, error) {
+	var limit interface{}
+	if opts.Limit > 0 {
+		limit = opts.Limit // a NULL limit means no limit
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+postgresURLColumns+` FROM urls
+		WHERE deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $1) AND ($2 = '' OR owner_id = $2)
+		ORDER BY created_at DESC, short_code ASC
+		LIMIT $3 OFFSET $4`,
+		time.Now(), opts.OwnerID, limit, opts.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+	defer rows.Close()
+
+	urls := []*domain.URL{}
+	for rows.Next() {
+		url, err := scanPostgresURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url: %w", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (r *PostgresURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE urls SET long_url = $1, redirect_status = $2, long_url_key = $3
+		WHERE short_code = $4 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $5)`,
+		url.LongURL, url.RedirectStatus, longURLKey(url.LongURL), url.ShortCode, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update url: %w", err)
+	}
+
+	return requireAffected(res, "update url")
+}
+
+func (r *PostgresURLRepository) Moderate(ctx context.Context, url *domain.URL) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE urls SET owner_id = $1, disabled_at = $2
+		WHERE short_code = $3 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $4)`,
+		url.OwnerID, url.DisabledAt, url.ShortCode, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to moderate url: %w", err)
+	}
+
+	return requireAffected(res, "moderate url")
+}
+
+// Delete keeps the row as a tombstone; the expiry sweeper removes it once
+// tombstoneUntil has passed and Create may then reuse the code.
+func (r *PostgresURLRepository) Delete(ctx context.Context, shortCode string, tombstoneUntil time.Time) error {
+	now := time.Now()
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE urls SET deleted_at = $1, expires_at = $2
+		WHERE short_code = $3 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > $1)`,
+		now, tombstoneUntil, shortCode,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete url: %w", err)
+	}
+
+	return requireAffected(res, "delete url")
+}
+
+func (r *PostgresURLRepository) startCleanup() {
+	r.cleanupTicker = time.NewTicker(1 * time.Minute)
+	go func() {
+		for {
+			select {
+			case <-r.cleanupTicker.C:
+				if err := r.cleanupExpired(context.Background()); err != nil {
+					log.Printf("Error removing expired urls: %v", err)
+				}
+			case <-r.stopCleanup:
+				return
+			}
+		}
+	}()
+}
+
+func (r *PostgresURLRepository) cleanupExpired(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM urls WHERE expires_at IS NOT NULL AND expires_at <= $1`,
+		time.Now(),
+	)
+	return err
+}
+
+// DB exposes the underlying handle so other stores can share the PostgreSQL
+// connection. It is closed together with the repository.
+func (r *PostgresURLRepository) DB() *sql.DB {
+	return r.db
+}
+
+func (r *PostgresURLRepository) Close() error {
+	if r.cleanupTicker != nil {
+		r.cleanupTicker.Stop()
+	}
+	close(r.stopCleanup)
+	return r.db.Close()
+}
+
+func scanPostgresURL(row rowScanner) (*domain.URL, error) {
+	var (
+		url        domain.URL
+		expiresAt  sql.NullTime
+		disabledAt sql.NullTime
+	)
+	if err := row.Scan(&url.ShortCode, &url.LongURL, &url.CreatedAt, &expiresAt, &url.RedirectStatus, &url.OwnerID, &disabledAt, &url.PasswordHash); err != nil {
+		return nil, err
+	}
+
+	if expiresAt.Valid {
+		url.ExpiresAt = &expiresAt.Time
+	}
+	if disabledAt.Valid {
+		url.DisabledAt = &disabledAt.Time
+	}
+
+	return &url, nil
+}
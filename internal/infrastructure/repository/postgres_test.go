@@ -0,0 +1,242 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	postgresOnce sync.Once
+	postgresDSN  string
+	postgresErr  error
+	stopPostgres func()
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if stopPostgres != nil {
+		stopPostgres()
+	}
+	os.Exit(code)
+}
+
+// postgresTestDSN returns POSTGRES_TEST_DSN when set, otherwise boots a
+// throwaway cluster with the initdb/pg_ctl binaries found on PATH. Tests are
+// skipped when neither is available.
+func postgresTestDSN(t *testing.T) string {
+	t.Helper()
+	if dsn := os.Getenv("POSTGRES_TEST_DSN"); dsn != "" {
+		return dsn
+	}
+
+	postgresOnce.Do(func() {
+		postgresDSN, stopPostgres, postgresErr = startLocalPostgres()
+	})
+	if postgresErr != nil {
+		t.Skipf("postgres not available: %v", postgresErr)
+	}
+	return postgresDSN
+}
+
+func startLocalPostgres() (string, func(), error) {
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return "", nil, errors.New("initdb not found on PATH and POSTGRES_TEST_DSN is not set")
+	}
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		return "", nil, errors.New("pg_ctl not found on PATH and POSTGRES_TEST_DSN is not set")
+	}
+
+	dir, err := os.MkdirTemp("", "url-shortener-pg")
+	if err != nil {
+		return "", nil, err
+	}
+	dataDir := filepath.Join(dir, "data")
+
+	if out, err := exec.Command(initdb, "-D", dataDir, "-U", "postgres", "-A", "trust", "--no-sync").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb failed: %v: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	if out, err := exec.Command(pgCtl, "-D", dataDir, "-o", opts, "-l", filepath.Join(dir, "postgres.log"), "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start failed: %v: %s", err, out)
+	}
+
+	stop := func() {
+		exec.Command(pgCtl, "-D", dataDir, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+
+	return fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port), stop, nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func newPostgresRepo(t *testing.T, ttl time.Duration) *repository.PostgresURLRepository {
+	t.Helper()
+	dsn := postgresTestDSN(t)
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`DROP TABLE IF EXISTS urls, clicks, sequences, api_keys, user_identities, sessions, users, schema_migrations`)
+	require.NoError(t, err)
+	db.Close()
+
+	repo, err := repository.NewPostgresURLRepository(dsn, ttl, repository.PoolConfig{MaxOpenConns: 10, MaxIdleConns: 2})
+	require.NoError(t, err)
+	pgRepo := repo.(*repository.PostgresURLRepository)
+	t.Cleanup(func() { pgRepo.Close() })
+	return pgRepo
+}
+
+func TestPostgresURLRepository_CreateAndFind(t *testing.T) {
+	repo := newPostgresRepo(t, 0)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, url))
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL)
+	assert.WithinDuration(t, url.CreatedAt, found.CreatedAt, time.Millisecond)
+
+	_, err = repo.FindByShortCode(ctx, "missing")
+	assert.Equal(t, domain.ErrURLNotFound, err)
+}
+
+func TestPostgresURLRepository_Create_ConcurrentConflict(t *testing.T) {
+	repo := newPostgresRepo(t, 0)
+	ctx := context.Background()
+
+	const workers = 20
+	var wg sync.WaitGroup
+	results := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results <- repo.Create(ctx, &domain.URL{
+				ShortCode: "race",
+				LongURL:   fmt.Sprintf("https://example.com/%d", i),
+				CreatedAt: time.Now(),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, domain.ErrShortCodeExists, err)
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestPostgresURLRepository_ExpiryAware(t *testing.T) {
+	repo := newPostgresRepo(t, 0)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-1 * time.Hour)
+	assert.NoError(t, repo.Save(ctx, &domain.URL{ShortCode: "old", LongURL: "https://example.com/old", CreatedAt: time.Now(), ExpiresAt: &expired}))
+
+	_, err := repo.FindByShortCode(ctx, "old")
+	assert.Equal(t, domain.ErrURLNotFound, err)
+
+	exists, err := repo.Exists(ctx, "old")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "old", LongURL: "https://example.com/new", CreatedAt: time.Now()}))
+	found, err := repo.FindByShortCode(ctx, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", found.LongURL)
+}
+
+func TestPostgresURLRepository_List(t *testing.T) {
+	repo := newPostgresRepo(t, 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, code := range []string{"old", "mid", "new"} {
+		assert.NoError(t, repo.Create(ctx, &domain.URL{
+			ShortCode: code,
+			LongURL:   "https://example.com/" + code,
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	all, err := repo.List(ctx, domain.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "new", all[0].ShortCode)
+
+	page, err := repo.List(ctx, domain.ListOptions{Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "mid", page[0].ShortCode)
+}
+
+func TestPostgresURLRepository_HonorsContext(t *testing.T) {
+	repo := newPostgresRepo(t, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.FindByShortCode(ctx, "abc123")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPostgresURLRepository_UpdateDelete(t *testing.T) {
+	testURLRepositoryUpdateDelete(t, newPostgresRepo(t, 0), nil)
+}
+
+func TestPostgresURLRepository_FindByLongURL(t *testing.T) {
+	testURLRepositoryFindByLongURL(t, newPostgresRepo(t, 0))
+}
+
+func TestPostgresURLRepository_ListByOwner(t *testing.T) {
+	testURLRepositoryListByOwner(t, newPostgresRepo(t, 0))
+}
+
+func TestPostgresURLRepository_Moderate(t *testing.T) {
+	testURLRepositoryModerate(t, newPostgresRepo(t, 0))
+}
+
+func TestPostgresURLRepository_Password(t *testing.T) {
+	testURLRepositoryPassword(t, newPostgresRepo(t, 0))
+}
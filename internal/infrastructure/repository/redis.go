@@ -0,0 +1,423 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisURLKeyPrefix = "url:"
+	redisIndexKey     = "urls:by_created"
+	redisOwnerKey     = "urls:by_owner:"    // followed by the owner ID, scored like the creation index
+	redisLongURLKey   = "urls:by_long_url:" // followed by a hash of the canonical long URL
+	redisListBatch    = 100
+)
+
+// createScript stores the URL only if the key is absent and records it in the
+// ARGV[5] sorted indexes that follow it in KEYS, and in the long URL index
+// when one more key is given, in the same atomic step. Expired keys are
+// evicted by Redis, so their codes become available again without a sweeper.
+var createScript = redis.NewScript(`
+local indexes = tonumber(ARGV[5])
+local ok
+if ARGV[4] ~= "0" then
+	ok = redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[4])
+else
+	ok = redis.call("SET", KEYS[1], ARGV[1], "NX")
+end
+if not ok then
+	return 0
+end
+for i = 2, indexes + 1 do
+	redis.call("ZADD", KEYS[i], ARGV[2], ARGV[3])
+end
+local long = KEYS[indexes + 2]
+if long then
+	if ARGV[4] ~= "0" then
+		redis.call("SET", long, ARGV[3], "PX", ARGV[4])
+	else
+		redis.call("SET", long, ARGV[3])
+	end
+end
+return 1
+`)
+
+type redisURL struct {
+	LongURL        string     `json:"long_url"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	RedirectStatus int        `json:"redirect_status,omitempty"`
+	OwnerID        string     `json:"owner_id,omitempty"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty"`
+	PasswordHash   string     `json:"password_hash,omitempty"`
+}
+
+type RedisURLRepository struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisURLRepository(client *redis.Client, ttl time.Duration) domain.URLRepository {
+	return &RedisURLRepository{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+func (r *RedisURLRepository) Create(ctx context.Context, url *domain.URL) error {
+	applyTTL(url, r.ttl)
+
+	payload, err := encodeRedisURL(url)
+	if err != nil {
+		return err
+	}
+
+	keys := []string{redisURLKeyPrefix + url.ShortCode, redisIndexKey}
+	if url.OwnerID != "" {
+		keys = append(keys, redisOwnerKey+url.OwnerID)
+	}
+	indexes := len(keys) - 1
+	if key, ok := longURLIndexKey(url.LongURL); ok {
+		keys = append(keys, key)
+	}
+	created, err := createScript.Run(ctx, r.client, keys,
+		payload, url.CreatedAt.UnixMilli(), url.ShortCode, redisExpiryMillis(url.ExpiresAt), indexes,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to insert url: %w", err)
+	}
+	if created == 0 {
+		return domain.ErrShortCodeExists
+	}
+
+	return nil
+}
+
+func (r *RedisURLRepository) Save(ctx context.Context, url *domain.URL) error {
+	applyTTL(url, r.ttl)
+
+	payload, err := encodeRedisURL(url)
+	if err != nil {
+		return err
+	}
+
+	var expiration time.Duration
+	if url.ExpiresAt != nil {
+		expiration = time.Duration(redisExpiryMillis(url.ExpiresAt)) * time.Millisecond
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisURLKeyPrefix+url.ShortCode, payload, expiration)
+		pipe.ZAdd(ctx, redisIndexKey, redisIndexEntry(url))
+		if url.OwnerID != "" {
+			pipe.ZAdd(ctx, redisOwnerKey+url.OwnerID, redisIndexEntry(url))
+		}
+		indexLongURL(ctx, pipe, url)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save url: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisURLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	payload, err := r.client.Get(ctx, redisURLKeyPrefix+shortCode).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	url, err := decodeRedisURL(shortCode, payload)
+	if err != nil {
+		return nil, err
+	}
+	if url.DeletedAt != nil {
+		return nil, domain.ErrURLNotFound
+	}
+	return url, nil
+}
+
+// FindByLongURL follows the long URL index, which may point at a link that
+// has since been deleted or pointed elsewhere, so the link is checked again.
+func (r *RedisURLRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URL, error) {
+	key, ok := longURLIndexKey(longURL)
+	if !ok {
+		return nil, domain.ErrURLNotFound
+	}
+
+	shortCode, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	url, err := r.FindByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if current, ok := longURLIndexKey(url.LongURL); !ok || current != key || url.IsExpired() {
+		return nil, domain.ErrURLNotFound
+	}
+	return url, nil
+}
+
+func (r *RedisURLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+	n, err := r.client.Exists(ctx, redisURLKeyPrefix+shortCode).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check url existence: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// List walks the creation index newest first, or the owner's own index when
+// listing by owner. Index entries whose key has already expired are skipped
+// and pruned along the way, and so are owner index entries left behind by a
+// link that has since changed hands.
+func (r *RedisURLRepository) List(ctx context.Context, opts domain.ListOptions) ([]*domain.URL, error) {
+	index := redisIndexKey
+	if opts.OwnerID != "" {
+		index = redisOwnerKey + opts.OwnerID
+	}
+	urls := []*domain.URL{}
+	skipped := 0
+
+	for start := int64(0); ; start += redisListBatch {
+		codes, err := r.client.ZRevRange(ctx, index, start, start+redisListBatch-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list urls: %w", err)
+		}
+		if len(codes) == 0 {
+			return urls, nil
+		}
+
+		keys := make([]string, len(codes))
+		for i, code := range codes {
+			keys[i] = redisURLKeyPrefix + code
+		}
+		payloads, err := r.client.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list urls: %w", err)
+		}
+
+		var stale []interface{}
+		for i, payload := range payloads {
+			s, ok := payload.(string)
+			if !ok {
+				stale = append(stale, codes[i])
+				continue
+			}
+			url, err := decodeRedisURL(codes[i], s)
+			if err != nil {
+				return nil, err
+			}
+			if opts.OwnerID != "" && (url.OwnerID != opts.OwnerID || url.DeletedAt != nil) {
+				stale = append(stale, codes[i])
+				continue
+			}
+			if skipped < opts.Offset {
+				skipped++
+				continue
+			}
+			urls = append(urls, url)
+			if opts.Limit > 0 && len(urls) == opts.Limit {
+				break
+			}
+		}
+
+		if len(stale) > 0 {
+			if err := r.client.ZRem(ctx, index, stale...).Err(); err != nil {
+				return nil, fmt.Errorf("failed to prune url index: %w", err)
+			}
+			start -= int64(len(stale))
+		}
+		if opts.Limit > 0 && len(urls) == opts.Limit {
+			return urls, nil
+		}
+	}
+}
+
+func (r *RedisURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	return r.modify(ctx, url.ShortCode, func(pipe redis.Pipeliner, key string, stored *domain.URL) error {
+		stored.LongURL = url.LongURL
+		stored.RedirectStatus = url.RedirectStatus
+		payload, err := encodeRedisURL(stored)
+		if err != nil {
+			return err
+		}
+		pipe.SetArgs(ctx, key, payload, redis.SetArgs{KeepTTL: true})
+		indexLongURL(ctx, pipe, stored)
+		return nil
+	})
+}
+
+func (r *RedisURLRepository) Moderate(ctx context.Context, url *domain.URL) error {
+	return r.modify(ctx, url.ShortCode, func(pipe redis.Pipeliner, key string, stored *domain.URL) error {
+		previousOwner := stored.OwnerID
+		stored.OwnerID = url.OwnerID
+		stored.DisabledAt = url.DisabledAt
+		payload, err := encodeRedisURL(stored)
+		if err != nil {
+			return err
+		}
+		pipe.SetArgs(ctx, key, payload, redis.SetArgs{KeepTTL: true})
+		if previousOwner != stored.OwnerID {
+			if previousOwner != "" {
+				pipe.ZRem(ctx, redisOwnerKey+previousOwner, stored.ShortCode)
+			}
+			if stored.OwnerID != "" {
+				pipe.ZAdd(ctx, redisOwnerKey+stored.OwnerID, redisIndexEntry(stored))
+			}
+		}
+		return nil
+	})
+}
+
+// Delete overwrites the link with a tombstone that Redis evicts at
+// tombstoneUntil; until then SET NX in Create keeps the code reserved.
+func (r *RedisURLRepository) Delete(ctx context.Context, shortCode string, tombstoneUntil time.Time) error {
+	return r.modify(ctx, shortCode, func(pipe redis.Pipeliner, key string, stored *domain.URL) error {
+		now := time.Now()
+		stored.DeletedAt = &now
+		stored.ExpiresAt = &tombstoneUntil
+		payload, err := encodeRedisURL(stored)
+		if err != nil {
+			return err
+		}
+		pipe.Set(ctx, key, payload, time.Duration(redisExpiryMillis(stored.ExpiresAt))*time.Millisecond)
+		pipe.ZRem(ctx, redisIndexKey, shortCode)
+		if stored.OwnerID != "" {
+			pipe.ZRem(ctx, redisOwnerKey+stored.OwnerID, shortCode)
+		}
+		return nil
+	})
+}
+
+// modify loads a live link and applies change to it in a transaction that
+// fails if the key is touched concurrently.
+func (r *RedisURLRepository) modify(ctx context.Context, shortCode string, change func(pipe redis.Pipeliner, key string, stored *domain.URL) error) error {
+	key := redisURLKeyPrefix + shortCode
+
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		payload, err := tx.Get(ctx, key).Result()
+		if errors.Is(err, redis.Nil) {
+			return domain.ErrURLNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		stored, err := decodeRedisURL(shortCode, payload)
+		if err != nil {
+			return err
+		}
+		if stored.DeletedAt != nil {
+			return domain.ErrURLNotFound
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return change(pipe, key, stored)
+		})
+		return err
+	}, key)
+	if errors.Is(err, domain.ErrURLNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to modify url: %w", err)
+	}
+
+	return nil
+}
+
+// redisIndexEntry orders url by creation time in the sorted indexes.
+func redisIndexEntry(url *domain.URL) redis.Z {
+	return redis.Z{Score: float64(url.CreatedAt.UnixMilli()), Member: url.ShortCode}
+}
+
+// longURLIndexKey hashes the canonical long URL to keep index keys short.
+func longURLIndexKey(longURL string) (string, bool) {
+	canonical, err := domain.CanonicalURL(longURL)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return redisLongURLKey + hex.EncodeToString(sum[:]), true
+}
+
+// indexLongURL points the long URL index at url for as long as url lives.
+func indexLongURL(ctx context.Context, pipe redis.Pipeliner, url *domain.URL) {
+	key, ok := longURLIndexKey(url.LongURL)
+	if !ok {
+		return
+	}
+	var expiration time.Duration
+	if url.ExpiresAt != nil {
+		expiration = time.Duration(redisExpiryMillis(url.ExpiresAt)) * time.Millisecond
+	}
+	pipe.Set(ctx, key, url.ShortCode, expiration)
+}
+
+func encodeRedisURL(url *domain.URL) (string, error) {
+	payload, err := json.Marshal(redisURL{
+		LongURL:        url.LongURL,
+		CreatedAt:      url.CreatedAt,
+		ExpiresAt:      url.ExpiresAt,
+		DeletedAt:      url.DeletedAt,
+		RedirectStatus: url.RedirectStatus,
+		OwnerID:        url.OwnerID,
+		DisabledAt:     url.DisabledAt,
+		PasswordHash:   url.PasswordHash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode url: %w", err)
+	}
+	return string(payload), nil
+}
+
+func decodeRedisURL(shortCode, payload string) (*domain.URL, error) {
+	var stored redisURL
+	if err := json.Unmarshal([]byte(payload), &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode url %s: %w", shortCode, err)
+	}
+
+	return &domain.URL{
+		ShortCode:      shortCode,
+		LongURL:        stored.LongURL,
+		CreatedAt:      stored.CreatedAt,
+		ExpiresAt:      stored.ExpiresAt,
+		DeletedAt:      stored.DeletedAt,
+		RedirectStatus: stored.RedirectStatus,
+		OwnerID:        stored.OwnerID,
+		DisabledAt:     stored.DisabledAt,
+		PasswordHash:   stored.PasswordHash,
+	}, nil
+}
+
+// redisExpiryMillis converts an absolute expiry into the relative PX argument,
+// with 0 meaning no expiry. Already-expired links get the minimum of 1ms.
+func redisExpiryMillis(expiresAt *time.Time) int64 {
+	if expiresAt == nil {
+		return 0
+	}
+	ms := time.Until(*expiresAt).Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	return ms
+}
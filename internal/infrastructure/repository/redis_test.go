@@ -0,0 +1,214 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRedisRepo(t *testing.T, ttl time.Duration) (domain.URLRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return repository.NewRedisURLRepository(client, ttl), mr
+}
+
+func TestRedisURLRepository_CreateAndFind(t *testing.T) {
+	repo, _ := newRedisRepo(t, 0)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, url))
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL)
+	assert.True(t, url.CreatedAt.Equal(found.CreatedAt))
+	assert.Nil(t, found.ExpiresAt)
+
+	exists, err := repo.Exists(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	_, err = repo.FindByShortCode(ctx, "missing")
+	assert.Equal(t, domain.ErrURLNotFound, err)
+}
+
+func TestRedisURLRepository_Create_ConcurrentConflict(t *testing.T) {
+	repo, _ := newRedisRepo(t, 0)
+	ctx := context.Background()
+
+	const workers = 20
+	var wg sync.WaitGroup
+	results := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results <- repo.Create(ctx, &domain.URL{
+				ShortCode: "race",
+				LongURL:   fmt.Sprintf("https://example.com/%d", i),
+				CreatedAt: time.Now(),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, domain.ErrShortCodeExists, err)
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestRedisURLRepository_NativeTTL(t *testing.T) {
+	repo, mr := newRedisRepo(t, 1*time.Hour)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, url))
+	assert.NotNil(t, url.ExpiresAt)
+
+	ttl := mr.TTL("url:abc123")
+	assert.InDelta(t, float64(time.Hour), float64(ttl), float64(time.Second))
+
+	mr.FastForward(time.Hour)
+
+	_, err := repo.FindByShortCode(ctx, "abc123")
+	assert.Equal(t, domain.ErrURLNotFound, err)
+
+	// the expired code is free again without any cleanup ticker
+	assert.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.org", CreatedAt: time.Now()}))
+}
+
+func TestRedisURLRepository_List(t *testing.T) {
+	repo, mr := newRedisRepo(t, 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, code := range []string{"old", "mid", "new"} {
+		assert.NoError(t, repo.Create(ctx, &domain.URL{
+			ShortCode: code,
+			LongURL:   "https://example.com/" + code,
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+	expiresAt := now.Add(time.Minute)
+	assert.NoError(t, repo.Create(ctx, &domain.URL{
+		ShortCode: "brief",
+		LongURL:   "https://example.com/brief",
+		CreatedAt: now.Add(time.Hour),
+		ExpiresAt: &expiresAt,
+	}))
+
+	all, err := repo.List(ctx, domain.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 4)
+	assert.Equal(t, "brief", all[0].ShortCode)
+
+	mr.FastForward(2 * time.Minute)
+
+	all, err = repo.List(ctx, domain.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "new", all[0].ShortCode)
+
+	members, err := mr.ZMembers("urls:by_created")
+	assert.NoError(t, err)
+	assert.Len(t, members, 3, "expired entries should be pruned from the index")
+
+	page, err := repo.List(ctx, domain.ListOptions{Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "mid", page[0].ShortCode)
+}
+
+func TestRedisURLRepository_UpdateDelete(t *testing.T) {
+	repo, mr := newRedisRepo(t, 0)
+
+	testURLRepositoryUpdateDelete(t, repo, func() { mr.FastForward(time.Second) })
+}
+
+func TestRedisURLRepository_UpdateKeepsTTL(t *testing.T) {
+	repo, mr := newRedisRepo(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}))
+	require.NoError(t, repo.Update(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.org"}))
+
+	assert.InDelta(t, time.Hour, mr.TTL("url:abc123"), float64(time.Second))
+}
+
+func TestRedisURLRepository_FindByLongURL(t *testing.T) {
+	repo, _ := newRedisRepo(t, 0)
+
+	testURLRepositoryFindByLongURL(t, repo)
+}
+
+func TestRedisURLRepository_ListByOwner(t *testing.T) {
+	repo, mr := newRedisRepo(t, 0)
+	testURLRepositoryListByOwner(t, repo)
+
+	members, err := mr.ZMembers("urls:by_owner:alice")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"alice1", "alice2"}, members, "each owner's links are indexed separately")
+}
+
+func TestRedisURLRepository_OwnerIndex(t *testing.T) {
+	repo, mr := newRedisRepo(t, 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	expiresAt := now.Add(time.Minute)
+	for _, u := range []*domain.URL{
+		{ShortCode: "keep01", LongURL: "https://example.com/1", CreatedAt: now, OwnerID: "alice"},
+		{ShortCode: "gone01", LongURL: "https://example.com/2", CreatedAt: now, OwnerID: "alice"},
+		{ShortCode: "brief1", LongURL: "https://example.com/3", CreatedAt: now, OwnerID: "alice", ExpiresAt: &expiresAt},
+		{ShortCode: "moved1", LongURL: "https://example.com/4", CreatedAt: now, OwnerID: "alice"},
+	} {
+		require.NoError(t, repo.Create(ctx, u))
+	}
+
+	require.NoError(t, repo.Delete(ctx, "gone01", now.Add(time.Hour)))
+	require.NoError(t, repo.Moderate(ctx, &domain.URL{ShortCode: "moved1", OwnerID: "bob"}))
+	members, err := mr.ZMembers("urls:by_owner:alice")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"keep01", "brief1"}, members)
+	members, err = mr.ZMembers("urls:by_owner:bob")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"moved1"}, members)
+
+	mr.FastForward(2 * time.Minute)
+	owned, err := repo.List(ctx, domain.ListOptions{OwnerID: "alice"})
+	require.NoError(t, err)
+	require.Len(t, owned, 1)
+	assert.Equal(t, "keep01", owned[0].ShortCode)
+	members, err = mr.ZMembers("urls:by_owner:alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"keep01"}, members, "expired entries should be pruned from the owner index")
+}
+
+func TestRedisURLRepository_Moderate(t *testing.T) {
+	repo, _ := newRedisRepo(t, 0)
+	testURLRepositoryModerate(t, repo)
+}
+
+func TestRedisURLRepository_Password(t *testing.T) {
+	repo, _ := newRedisRepo(t, 0)
+	testURLRepositoryPassword(t, repo)
+}
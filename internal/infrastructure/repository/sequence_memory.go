@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"url-shortener/internal/domain"
+)
+
+// MemoryIDSequence starts over on restart, which only suits storage that
+// forgets its URLs too.
+type MemoryIDSequence struct {
+	last atomic.Uint64
+}
+
+func NewMemoryIDSequence() domain.IDSequence {
+	return &MemoryIDSequence{}
+}
+
+func (s *MemoryIDSequence) NextID(ctx context.Context) (uint64, error) {
+	return s.last.Add(1), nil
+}
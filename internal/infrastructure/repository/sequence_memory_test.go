@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIDSequence checks that IDs start at 1 and stay unique under concurrent
+// use.
+func testIDSequence(t *testing.T, seq domain.IDSequence) {
+	t.Helper()
+	ctx := context.Background()
+
+	first, err := seq.NextID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), first)
+
+	const workers, perWorker = 8, 25
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := map[uint64]bool{first: true}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				id, err := seq.NextID(ctx)
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				assert.False(t, seen[id], "id %d handed out twice", id)
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, workers*perWorker+1)
+	for id := uint64(1); id <= workers*perWorker+1; id++ {
+		assert.True(t, seen[id], "id %d was skipped", id)
+	}
+}
+
+func TestMemoryIDSequence(t *testing.T) {
+	testIDSequence(t, repository.NewMemoryIDSequence())
+}
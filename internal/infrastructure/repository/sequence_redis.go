@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"url-shortener/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisSequenceKey = "seq:" + shortCodeSequence
+
+type RedisIDSequence struct {
+	client *redis.Client
+}
+
+func NewRedisIDSequence(client *redis.Client) domain.IDSequence {
+	return &RedisIDSequence{client: client}
+}
+
+func (s *RedisIDSequence) NextID(ctx context.Context) (uint64, error) {
+	id, err := s.client.Incr(ctx, redisSequenceKey).Uint64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate id: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/sqlstore"
+)
+
+const shortCodeSequence = "short_codes"
+
+var sequenceMigrations = []string{
+	`CREATE TABLE sequences (
+		name  TEXT   PRIMARY KEY,
+		value BIGINT NOT NULL
+	)`,
+}
+
+type SQLIDSequence struct {
+	db      *sql.DB
+	dialect sqlstore.Dialect
+}
+
+// NewSQLIDSequence keeps the short code counter in db, which is typically
+// shared with the URL repository of the same driver. The caller keeps
+// ownership of db.
+func NewSQLIDSequence(db *sql.DB, dialect sqlstore.Dialect) (domain.IDSequence, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sqlstore.Migrate(ctx, db, "sequences", sequenceMigrations); err != nil {
+		return nil, err
+	}
+
+	return &SQLIDSequence{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+// NextID increments the counter in a single statement, so concurrent callers,
+// including other replicas, never see the same value.
+func (s *SQLIDSequence) NextID(ctx context.Context) (uint64, error) {
+	var id uint64
+	err := s.db.QueryRowContext(ctx, s.dialect.Rebind(`
+		INSERT INTO sequences (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = sequences.value + 1
+		RETURNING value`,
+	), shortCodeSequence).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate id: %w", err)
+	}
+	return id, nil
+}
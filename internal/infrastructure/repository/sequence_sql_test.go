@@ -0,0 +1,55 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"url-shortener/internal/infrastructure/repository"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLIDSequence_SQLite(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "urls.db")
+	urlRepo := newSQLiteRepo(t, dsn, 0)
+
+	seq, err := repository.NewSQLIDSequence(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+
+	testIDSequence(t, seq)
+}
+
+func TestSQLIDSequence_SQLiteSurvivesRestart(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "urls.db")
+	ctx := context.Background()
+
+	repo, err := repository.NewSQLiteURLRepository(dsn, 0)
+	require.NoError(t, err)
+	urlRepo := repo.(*repository.SQLiteURLRepository)
+	seq, err := repository.NewSQLIDSequence(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := seq.NextID(ctx)
+		require.NoError(t, err)
+	}
+	require.NoError(t, urlRepo.Close())
+
+	urlRepo = newSQLiteRepo(t, dsn, 0)
+	seq, err = repository.NewSQLIDSequence(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+
+	id, err := seq.NextID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), id)
+}
+
+func TestSQLIDSequence_Postgres(t *testing.T) {
+	urlRepo := newPostgresRepo(t, 0)
+
+	seq, err := repository.NewSQLIDSequence(urlRepo.DB(), sqlstore.DialectPostgres)
+	require.NoError(t, err)
+
+	testIDSequence(t, seq)
+}
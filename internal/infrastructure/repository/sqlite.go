@@ -0,0 +1,356 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var sqliteMigrations = []string{
+	`CREATE TABLE urls (
+		short_code TEXT    PRIMARY KEY,
+		long_url   TEXT    NOT NULL,
+		created_at INTEGER NOT NULL,
+		expires_at INTEGER
+	)`,
+	`CREATE INDEX idx_urls_expires_at ON urls (expires_at)`,
+	`CREATE INDEX idx_urls_created_at ON urls (created_at)`,
+	`ALTER TABLE urls ADD COLUMN deleted_at INTEGER`,
+	`ALTER TABLE urls ADD COLUMN redirect_status INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE urls ADD COLUMN long_url_key TEXT`,
+	`CREATE INDEX idx_urls_long_url_key ON urls (long_url_key, created_at)`,
+	`ALTER TABLE urls ADD COLUMN owner_id TEXT NOT NULL DEFAULT ''`,
+	`CREATE INDEX idx_urls_owner_id ON urls (owner_id, created_at)`,
+	`ALTER TABLE urls ADD COLUMN disabled_at INTEGER`,
+	`ALTER TABLE urls ADD COLUMN password_hash TEXT NOT NULL DEFAULT ''`,
+}
+
+const sqliteURLColumns = "short_code, long_url, created_at, expires_at, redirect_status, owner_id, disabled_at, password_hash"
+
+type SQLiteURLRepository struct {
+	db            *sql.DB
+	ttl           time.Duration
+	cleanupTicker *time.Ticker
+	stopCleanup   chan struct{}
+}
+
+func NewSQLiteURLRepository(dsn string, ttl time.Duration) (domain.URLRepository, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite allows a single writer; serialising through one connection avoids
+	// SQLITE_BUSY errors and keeps ":memory:" databases shared.
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sqlstore.Migrate(ctx, db, "urls", sqliteMigrations); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	repo := &SQLiteURLRepository{
+		db:          db,
+		ttl:         ttl,
+		stopCleanup: make(chan struct{}),
+	}
+
+	repo.startCleanup()
+
+	return repo, nil
+}
+
+func (r *SQLiteURLRepository) Create(ctx context.Context, url *domain.URL) error {
+	applyTTL(url, r.ttl)
+
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO urls (`+sqliteURLColumns+`, long_url_key) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (short_code) DO UPDATE SET
+			long_url = excluded.long_url,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at,
+			redirect_status = excluded.redirect_status,
+			long_url_key = excluded.long_url_key,
+			owner_id = excluded.owner_id,
+			disabled_at = excluded.disabled_at,
+			password_hash = excluded.password_hash,
+			deleted_at = NULL
+		WHERE urls.expires_at IS NOT NULL AND urls.expires_at <= ?`,
+		url.ShortCode, url.LongURL, url.CreatedAt.UnixNano(), nullableUnixNano(url.ExpiresAt), url.RedirectStatus, url.OwnerID, nullableUnixNano(url.DisabledAt), url.PasswordHash, longURLKey(url.LongURL), time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert url: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to insert url: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrShortCodeExists
+	}
+
+	return nil
+}
+
+func (r *SQLiteURLRepository) Save(ctx context.Context, url *domain.URL) error {
+	applyTTL(url, r.ttl)
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO urls (`+sqliteURLColumns+`, long_url_key) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (short_code) DO UPDATE SET
+			long_url = excluded.long_url,
+			created_at = excluded.created_at,
+			expires_at = excluded.expires_at,
+			redirect_status = excluded.redirect_status,
+			long_url_key = excluded.long_url_key,
+			owner_id = excluded.owner_id,
+			disabled_at = excluded.disabled_at,
+			password_hash = excluded.password_hash,
+			deleted_at = NULL`,
+		url.ShortCode, url.LongURL, url.CreatedAt.UnixNano(), nullableUnixNano(url.ExpiresAt), url.RedirectStatus, url.OwnerID, nullableUnixNano(url.DisabledAt), url.PasswordHash, longURLKey(url.LongURL),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save url: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SQLiteURLRepository) FindByShortCode(ctx context.Context, shortCode string) (*domain.URL, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+sqliteURLColumns+` FROM urls
+		WHERE short_code = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`,
+		shortCode, time.Now().UnixNano(),
+	)
+
+	url, err := scanSQLiteURL(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	return url, nil
+}
+
+func (r *SQLiteURLRepository) FindByLongURL(ctx context.Context, longURL string) (*domain.URL, error) {
+	key, err := domain.CanonicalURL(longURL)
+	if err != nil {
+		return nil, domain.ErrURLNotFound
+	}
+
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+sqliteURLColumns+` FROM urls
+		WHERE long_url_key = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		key, time.Now().UnixNano(),
+	)
+
+	url, err := scanSQLiteURL(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find url: %w", err)
+	}
+
+	return url, nil
+}
+
+func (r *SQLiteURLRepository) Exists(ctx context.Context, shortCode string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM urls
+			WHERE short_code = ? AND (expires_at IS NULL OR expires_at > ?)
+		)`,
+		shortCode, time.Now().UnixNano(),
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check url existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *SQLiteURLRepository) List(ctx context.Context, opts domain.ListOptions) ([]*domain.URL, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1 // SQLite treats a negative LIMIT as unbounded
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+sqliteURLColumns+` FROM urls
+		WHERE deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND (? = '' OR owner_id = ?)
+		ORDER BY created_at DESC, short_code ASC
+		LIMIT ? OFFSET ?`,
+		time.Now().UnixNano(), opts.OwnerID, opts.OwnerID, limit, opts.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+	defer rows.Close()
+
+	urls := []*domain.URL{}
+	for rows.Next() {
+		url, err := scanSQLiteURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan url: %w", err)
+		}
+		urls = append(urls, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list urls: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (r *SQLiteURLRepository) Update(ctx context.Context, url *domain.URL) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE urls SET long_url = ?, redirect_status = ?, long_url_key = ?
+		WHERE short_code = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`,
+		url.LongURL, url.RedirectStatus, longURLKey(url.LongURL), url.ShortCode, time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update url: %w", err)
+	}
+
+	return requireAffected(res, "update url")
+}
+
+func (r *SQLiteURLRepository) Moderate(ctx context.Context, url *domain.URL) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE urls SET owner_id = ?, disabled_at = ?
+		WHERE short_code = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`,
+		url.OwnerID, nullableUnixNano(url.DisabledAt), url.ShortCode, time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to moderate url: %w", err)
+	}
+
+	return requireAffected(res, "moderate url")
+}
+
+// Delete keeps the row as a tombstone; the expiry sweeper removes it once
+// tombstoneUntil has passed and Create may then reuse the code.
+func (r *SQLiteURLRepository) Delete(ctx context.Context, shortCode string, tombstoneUntil time.Time) error {
+	now := time.Now()
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE urls SET deleted_at = ?, expires_at = ?
+		WHERE short_code = ? AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)`,
+		now.UnixNano(), tombstoneUntil.UnixNano(), shortCode, now.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete url: %w", err)
+	}
+
+	return requireAffected(res, "delete url")
+}
+
+func (r *SQLiteURLRepository) startCleanup() {
+	r.cleanupTicker = time.NewTicker(1 * time.Minute)
+	go func() {
+		for {
+			select {
+			case <-r.cleanupTicker.C:
+				if err := r.cleanupExpired(context.Background()); err != nil {
+					log.Printf("Error removing expired urls: %v", err)
+				}
+			case <-r.stopCleanup:
+				return
+			}
+		}
+	}()
+}
+
+func (r *SQLiteURLRepository) cleanupExpired(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM urls WHERE expires_at IS NOT NULL AND expires_at <= ?`,
+		time.Now().UnixNano(),
+	)
+	return err
+}
+
+// DB exposes the underlying handle so other stores can share the SQLite
+// connection. It is closed together with the repository.
+func (r *SQLiteURLRepository) DB() *sql.DB {
+	return r.db
+}
+
+func (r *SQLiteURLRepository) Close() error {
+	if r.cleanupTicker != nil {
+		r.cleanupTicker.Stop()
+	}
+	close(r.stopCleanup)
+	return r.db.Close()
+}
+
+// requireAffected maps an UPDATE that matched no live link to ErrURLNotFound.
+func requireAffected(res sql.Result, action string) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	if affected == 0 {
+		return domain.ErrURLNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSQLiteURL(row rowScanner) (*domain.URL, error) {
+	var (
+		url        domain.URL
+		createdAt  int64
+		expiresAt  sql.NullInt64
+		disabledAt sql.NullInt64
+	)
+	if err := row.Scan(&url.ShortCode, &url.LongURL, &createdAt, &expiresAt, &url.RedirectStatus, &url.OwnerID, &disabledAt, &url.PasswordHash); err != nil {
+		return nil, err
+	}
+
+	url.CreatedAt = time.Unix(0, createdAt)
+	if expiresAt.Valid {
+		t := time.Unix(0, expiresAt.Int64)
+		url.ExpiresAt = &t
+	}
+	if disabledAt.Valid {
+		t := time.Unix(0, disabledAt.Int64)
+		url.DisabledAt = &t
+	}
+
+	return &url, nil
+}
+
+// longURLKey is the value indexed for FindByLongURL. Rows stored before the
+// index existed have none and are never matched.
+func longURLKey(longURL string) interface{} {
+	key, err := domain.CanonicalURL(longURL)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+func nullableUnixNano(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UnixNano()
+}
@@ -0,0 +1,173 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLiteRepo(t *testing.T, dsn string, ttl time.Duration) *repository.SQLiteURLRepository {
+	t.Helper()
+	repo, err := repository.NewSQLiteURLRepository(dsn, ttl)
+	require.NoError(t, err)
+	sqliteRepo := repo.(*repository.SQLiteURLRepository)
+	t.Cleanup(func() { sqliteRepo.Close() })
+	return sqliteRepo
+}
+
+func TestSQLiteURLRepository_CreateAndFind(t *testing.T) {
+	repo := newSQLiteRepo(t, ":memory:", 0)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, url))
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL)
+	assert.True(t, url.CreatedAt.Equal(found.CreatedAt))
+	assert.Nil(t, found.ExpiresAt)
+
+	_, err = repo.FindByShortCode(ctx, "missing")
+	assert.Equal(t, domain.ErrURLNotFound, err)
+}
+
+func TestSQLiteURLRepository_Create_Conflict(t *testing.T) {
+	repo := newSQLiteRepo(t, ":memory:", 0)
+	ctx := context.Background()
+
+	assert.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/first", CreatedAt: time.Now()}))
+	err := repo.Create(ctx, &domain.URL{ShortCode: "abc123", LongURL: "https://example.com/second", CreatedAt: time.Now()})
+	assert.Equal(t, domain.ErrShortCodeExists, err)
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/first", found.LongURL)
+}
+
+func TestSQLiteURLRepository_ExpiryAware(t *testing.T) {
+	repo := newSQLiteRepo(t, ":memory:", 0)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-1 * time.Hour)
+	assert.NoError(t, repo.Save(ctx, &domain.URL{ShortCode: "old", LongURL: "https://example.com/old", CreatedAt: time.Now(), ExpiresAt: &expired}))
+
+	_, err := repo.FindByShortCode(ctx, "old")
+	assert.Equal(t, domain.ErrURLNotFound, err)
+
+	exists, err := repo.Exists(ctx, "old")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	list, err := repo.List(ctx, domain.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	// an expired code can be claimed again
+	assert.NoError(t, repo.Create(ctx, &domain.URL{ShortCode: "old", LongURL: "https://example.com/new", CreatedAt: time.Now()}))
+	found, err := repo.FindByShortCode(ctx, "old")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/new", found.LongURL)
+}
+
+func TestSQLiteURLRepository_TTL(t *testing.T) {
+	repo := newSQLiteRepo(t, ":memory:", 1*time.Hour)
+	ctx := context.Background()
+
+	url := &domain.URL{ShortCode: "abc123", LongURL: "https://example.com", CreatedAt: time.Now()}
+	assert.NoError(t, repo.Create(ctx, url))
+	assert.NotNil(t, url.ExpiresAt)
+
+	found, err := repo.FindByShortCode(ctx, "abc123")
+	assert.NoError(t, err)
+	assert.NotNil(t, found.ExpiresAt)
+	assert.True(t, found.ExpiresAt.Equal(*url.ExpiresAt))
+}
+
+func TestSQLiteURLRepository_List(t *testing.T) {
+	repo := newSQLiteRepo(t, ":memory:", 0)
+	ctx := context.Background()
+	now := time.Now()
+
+	for i, code := range []string{"old", "mid", "new"} {
+		assert.NoError(t, repo.Create(ctx, &domain.URL{
+			ShortCode: code,
+			LongURL:   "https://example.com/" + code,
+			CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	all, err := repo.List(ctx, domain.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+	assert.Equal(t, "new", all[0].ShortCode)
+	assert.Equal(t, "old", all[2].ShortCode)
+
+	page, err := repo.List(ctx, domain.ListOptions{Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Len(t, page, 1)
+	assert.Equal(t, "mid", page[0].ShortCode)
+}
+
+func TestSQLiteURLRepository_CleanupExpired(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "links.db")
+	repo := newSQLiteRepo(t, dsn, 0)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-1 * time.Hour)
+	assert.NoError(t, repo.Save(ctx, &domain.URL{ShortCode: "old", LongURL: "https://example.com/old", CreatedAt: time.Now(), ExpiresAt: &expired}))
+	assert.NoError(t, repo.Save(ctx, &domain.URL{ShortCode: "keep", LongURL: "https://example.com/keep", CreatedAt: time.Now()}))
+
+	assert.NoError(t, repo.CleanupExpired(ctx))
+
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM urls").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestSQLiteURLRepository_SurvivesReopen(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "links.db")
+	ctx := context.Background()
+
+	first, err := repository.NewSQLiteURLRepository(dsn, 0)
+	require.NoError(t, err)
+	assert.NoError(t, first.Create(ctx, &domain.URL{ShortCode: "flyer", LongURL: "https://example.com", CreatedAt: time.Now()}))
+	require.NoError(t, first.(*repository.SQLiteURLRepository).Close())
+
+	// reopening re-runs migrations, which must be a no-op on an existing schema
+	second := newSQLiteRepo(t, dsn, 0)
+	found, err := second.FindByShortCode(ctx, "flyer")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", found.LongURL)
+}
+
+func TestSQLiteURLRepository_UpdateDelete(t *testing.T) {
+	testURLRepositoryUpdateDelete(t, newSQLiteRepo(t, ":memory:", 0), nil)
+}
+
+func TestSQLiteURLRepository_FindByLongURL(t *testing.T) {
+	testURLRepositoryFindByLongURL(t, newSQLiteRepo(t, ":memory:", 0))
+}
+
+func TestSQLiteURLRepository_ListByOwner(t *testing.T) {
+	testURLRepositoryListByOwner(t, newSQLiteRepo(t, ":memory:", 0))
+}
+
+func TestSQLiteURLRepository_Moderate(t *testing.T) {
+	testURLRepositoryModerate(t, newSQLiteRepo(t, ":memory:", 0))
+}
+
+func TestSQLiteURLRepository_Password(t *testing.T) {
+	testURLRepositoryPassword(t, newSQLiteRepo(t, ":memory:", 0))
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+type MemoryUserRepository struct {
+	mu         sync.RWMutex
+	users      map[string]*domain.User // by ID
+	byEmail    map[string]string
+	identities map[identityKey]string
+}
+
+type identityKey struct {
+	issuer, subject string
+}
+
+func NewMemoryUserRepository() domain.UserRepository {
+	return &MemoryUserRepository{
+		users:      make(map[string]*domain.User),
+		byEmail:    make(map[string]string),
+		identities: make(map[identityKey]string),
+	}
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byEmail[user.Email]; exists {
+		return domain.ErrUserExists
+	}
+	if _, exists := r.users[user.ID]; exists {
+		return domain.ErrUserExists
+	}
+
+	stored := *user
+	r.users[user.ID] = &stored
+	r.byEmail[user.Email] = user.ID
+	return nil
+}
+
+func (r *MemoryUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return nil, domain.ErrUserNotFound
+	}
+	found := *user
+	return &found, nil
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.mu.RLock()
+	id, exists := r.byEmail[email]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, domain.ErrUserNotFound
+	}
+	return r.FindByID(ctx, id)
+}
+
+func (r *MemoryUserRepository) SetPasswordHash(ctx context.Context, id, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return domain.ErrUserNotFound
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
+func (r *MemoryUserRepository) SetRole(ctx context.Context, id string, role domain.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return domain.ErrUserNotFound
+	}
+	user.Role = role
+	return nil
+}
+
+func (r *MemoryUserRepository) LinkIdentity(ctx context.Context, issuer, subject, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := identityKey{issuer, subject}
+	if _, exists := r.identities[key]; exists {
+		return domain.ErrUserExists
+	}
+	r.identities[key] = userID
+	return nil
+}
+
+func (r *MemoryUserRepository) FindByIdentity(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	r.mu.RLock()
+	id, exists := r.identities[identityKey{issuer, subject}]
+	r.mu.RUnlock()
+
+	if !exists {
+		return nil, domain.ErrUserNotFound
+	}
+	return r.FindByID(ctx, id)
+}
+
+type MemorySessionRepository struct {
+	mu       sync.Mutex
+	sessions map[string]*domain.Session // by hash
+}
+
+func NewMemorySessionRepository() domain.SessionRepository {
+	return &MemorySessionRepository{
+		sessions: make(map[string]*domain.Session),
+	}
+}
+
+func (r *MemorySessionRepository) Create(ctx context.Context, session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.removeExpired()
+	stored := *session
+	r.sessions[session.Hash] = &stored
+	return nil
+}
+
+func (r *MemorySessionRepository) Find(ctx context.Context, hash string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[hash]
+	if !exists || !time.Now().Before(session.ExpiresAt) {
+		return nil, domain.ErrSessionNotFound
+	}
+	found := *session
+	return &found, nil
+}
+
+func (r *MemorySessionRepository) Delete(ctx context.Context, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, hash)
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, session := range r.sessions {
+		if session.UserID == userID {
+			delete(r.sessions, hash)
+		}
+	}
+	return nil
+}
+
+// removeExpired runs on every login, which keeps abandoned sessions from
+// piling up without a background sweeper.
+func (r *MemorySessionRepository) removeExpired() {
+	now := time.Now()
+	for hash, session := range r.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(r.sessions, hash)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	testUserRepository(t, repository.NewMemoryUserRepository())
+}
+
+func TestMemorySessionRepository(t *testing.T) {
+	testSessionRepository(t, repository.NewMemorySessionRepository())
+}
+
+// testUserRepository is shared by the user repository implementations.
+func testUserRepository(t *testing.T, repo domain.UserRepository) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	user := &domain.User{ID: "u1", Email: "ada@example.com", PasswordHash: "hash", CreatedAt: now}
+	require.NoError(t, repo.Create(ctx, user))
+	assert.ErrorIs(t, repo.Create(ctx, &domain.User{ID: "u2", Email: "ada@example.com", CreatedAt: now}), domain.ErrUserExists)
+
+	found, err := repo.FindByEmail(ctx, "ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", found.ID)
+	assert.Equal(t, "hash", found.PasswordHash)
+	assert.True(t, now.Equal(found.CreatedAt))
+
+	found, err = repo.FindByID(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", found.Email)
+
+	_, err = repo.FindByID(ctx, "u2")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	_, err = repo.FindByEmail(ctx, "grace@example.com")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	require.NoError(t, repo.SetPasswordHash(ctx, "u1", ""))
+	found, err = repo.FindByID(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, found.PasswordHash)
+	assert.Equal(t, "ada@example.com", found.Email)
+	assert.ErrorIs(t, repo.SetPasswordHash(ctx, "u2", "hash"), domain.ErrUserNotFound)
+
+	assert.Empty(t, found.Role)
+	require.NoError(t, repo.SetRole(ctx, "u1", domain.RoleAdmin))
+	found, err = repo.FindByEmail(ctx, "ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleAdmin, found.Role)
+	assert.Empty(t, found.PasswordHash, "changing the role keeps the password")
+	assert.ErrorIs(t, repo.SetRole(ctx, "u2", domain.RoleViewer), domain.ErrUserNotFound)
+
+	const issuer = "https://idp.example.com"
+	_, err = repo.FindByIdentity(ctx, issuer, "sub1")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	require.NoError(t, repo.LinkIdentity(ctx, issuer, "sub1", "u1"))
+	assert.ErrorIs(t, repo.LinkIdentity(ctx, issuer, "sub1", "u2"), domain.ErrUserExists)
+
+	found, err = repo.FindByIdentity(ctx, issuer, "sub1")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", found.ID)
+	_, err = repo.FindByIdentity(ctx, "https://other.example.com", "sub1")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound, "subjects are scoped to their issuer")
+}
+
+// testSessionRepository is shared by the session repository implementations.
+func testSessionRepository(t *testing.T, repo domain.SessionRepository) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Millisecond)
+
+	live := &domain.Session{Hash: "live", UserID: "u1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	expired := &domain.Session{Hash: "expired", UserID: "u1", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	require.NoError(t, repo.Create(ctx, live))
+	require.NoError(t, repo.Create(ctx, expired))
+
+	found, err := repo.Find(ctx, "live")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", found.UserID)
+	assert.True(t, now.Equal(found.CreatedAt))
+	assert.True(t, live.ExpiresAt.Equal(found.ExpiresAt))
+
+	_, err = repo.Find(ctx, "expired")
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+	_, err = repo.Find(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+
+	require.NoError(t, repo.Delete(ctx, "live"))
+	require.NoError(t, repo.Delete(ctx, "live"))
+	_, err = repo.Find(ctx, "live")
+	assert.ErrorIs(t, err, domain.ErrSessionNotFound)
+
+	for _, s := range []*domain.Session{
+		{Hash: "laptop", UserID: "u1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "phone", UserID: "u1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "other", UserID: "u2", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		require.NoError(t, repo.Create(ctx, s))
+	}
+	require.NoError(t, repo.DeleteByUser(ctx, "u1"))
+	require.NoError(t, repo.DeleteByUser(ctx, "nobody"))
+	for _, hash := range []string{"laptop", "phone"} {
+		_, err = repo.Find(ctx, hash)
+		assert.ErrorIs(t, err, domain.ErrSessionNotFound, hash)
+	}
+	_, err = repo.Find(ctx, "other")
+	assert.NoError(t, err, "other users stay logged in")
+}
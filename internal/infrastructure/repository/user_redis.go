@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisUserPrefix      = "user:"
+	redisUserEmailPrefix = "users:by_email:"
+	redisIdentityPrefix  = "users:by_identity:"
+	redisSessionPrefix   = "session:"
+	// redisUserSessionsPrefix keys a set of each user's session hashes. It
+	// may still list sessions that ended, whose keys are simply gone.
+	redisUserSessionsPrefix = "sessions:by_user:"
+)
+
+// createUserScript claims the email and stores the user in one atomic step.
+var createUserScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("EXISTS", KEYS[2]) == 1 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1])
+redis.call("SET", KEYS[2], ARGV[2])
+return 1
+`)
+
+type redisUser struct {
+	Email        string      `json:"email"`
+	PasswordHash string      `json:"password_hash"`
+	Role         domain.Role `json:"role,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+type RedisUserRepository struct {
+	client *redis.Client
+}
+
+func NewRedisUserRepository(client *redis.Client) domain.UserRepository {
+	return &RedisUserRepository{client: client}
+}
+
+func (r *RedisUserRepository) Create(ctx context.Context, user *domain.User) error {
+	payload, err := encodeRedisUser(user)
+	if err != nil {
+		return err
+	}
+
+	created, err := createUserScript.Run(ctx, r.client,
+		[]string{redisUserPrefix + user.ID, redisUserEmailPrefix + user.Email},
+		payload, user.ID,
+	).Int()
+	if err != nil {
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+	if created == 0 {
+		return domain.ErrUserExists
+	}
+	return nil
+}
+
+func (r *RedisUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	payload, err := r.client.Get(ctx, redisUserPrefix+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	var stored redisUser
+	if err := json.Unmarshal([]byte(payload), &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode user %s: %w", id, err)
+	}
+	return &domain.User{
+		ID:           id,
+		Email:        stored.Email,
+		PasswordHash: stored.PasswordHash,
+		Role:         stored.Role,
+		CreatedAt:    stored.CreatedAt,
+	}, nil
+}
+
+func (r *RedisUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	id, err := r.client.Get(ctx, redisUserEmailPrefix+email).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	return r.FindByID(ctx, id)
+}
+
+func (r *RedisUserRepository) SetPasswordHash(ctx context.Context, id, hash string) error {
+	return r.update(ctx, id, func(user *domain.User) {
+		user.PasswordHash = hash
+	})
+}
+
+func (r *RedisUserRepository) SetRole(ctx context.Context, id string, role domain.Role) error {
+	return r.update(ctx, id, func(user *domain.User) {
+		user.Role = role
+	})
+}
+
+func (r *RedisUserRepository) update(ctx context.Context, id string, change func(*domain.User)) error {
+	user, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	change(user)
+	payload, err := encodeRedisUser(user)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Set(ctx, redisUserPrefix+id, payload, 0).Err(); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+func encodeRedisUser(user *domain.User) ([]byte, error) {
+	payload, err := json.Marshal(redisUser{
+		Email:        user.Email,
+		PasswordHash: user.PasswordHash,
+		Role:         user.Role,
+		CreatedAt:    user.CreatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user: %w", err)
+	}
+	return payload, nil
+}
+
+func (r *RedisUserRepository) LinkIdentity(ctx context.Context, issuer, subject, userID string) error {
+	linked, err := r.client.SetNX(ctx, redisIdentityKey(issuer, subject), userID, 0).Result()
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	if !linked {
+		return domain.ErrUserExists
+	}
+	return nil
+}
+
+func (r *RedisUserRepository) FindByIdentity(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	id, err := r.client.Get(ctx, redisIdentityKey(issuer, subject)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+	return r.FindByID(ctx, id)
+}
+
+// redisIdentityKey separates issuer and subject with a space, which URLs cannot
+// contain.
+func redisIdentityKey(issuer, subject string) string {
+	return redisIdentityPrefix + issuer + " " + subject
+}
+
+type redisSession struct {
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RedisSessionRepository lets Redis expire sessions on its own.
+type RedisSessionRepository struct {
+	client *redis.Client
+}
+
+func NewRedisSessionRepository(client *redis.Client) domain.SessionRepository {
+	return &RedisSessionRepository{client: client}
+}
+
+func (r *RedisSessionRepository) Create(ctx context.Context, session *domain.Session) error {
+	payload, err := json.Marshal(redisSession{
+		UserID:    session.UserID,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	userSessions := redisUserSessionsPrefix + session.UserID
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisSessionPrefix+session.Hash, payload, ttl)
+		pipe.SAdd(ctx, userSessions, session.Hash)
+		// Sessions all last as long, so the newest one outlives the others.
+		pipe.Expire(ctx, userSessions, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisSessionRepository) Find(ctx context.Context, hash string) (*domain.Session, error) {
+	payload, err := r.client.Get(ctx, redisSessionPrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+
+	var stored redisSession
+	if err := json.Unmarshal([]byte(payload), &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	if !time.Now().Before(stored.ExpiresAt) {
+		return nil, domain.ErrSessionNotFound
+	}
+	return &domain.Session{
+		Hash:      hash,
+		UserID:    stored.UserID,
+		CreatedAt: stored.CreatedAt,
+		ExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+func (r *RedisSessionRepository) Delete(ctx context.Context, hash string) error {
+	if err := r.client.Del(ctx, redisSessionPrefix+hash).Err(); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	userSessions := redisUserSessionsPrefix + userID
+	hashes, err := r.client.SMembers(ctx, userSessions).Result()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	keys := []string{userSessions}
+	for _, hash := range hashes {
+		keys = append(keys, redisSessionPrefix+hash)
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
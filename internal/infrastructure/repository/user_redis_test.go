@@ -0,0 +1,18 @@
+package repository_test
+
+import (
+	"testing"
+	"url-shortener/internal/infrastructure/repository"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisUserRepository(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	testUserRepository(t, repository.NewRedisUserRepository(client))
+	testSessionRepository(t, repository.NewRedisSessionRepository(client))
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/sqlstore"
+)
+
+var userMigrations = []string{
+	`CREATE TABLE users (
+		id            TEXT   PRIMARY KEY,
+		email         TEXT   NOT NULL UNIQUE,
+		password_hash TEXT   NOT NULL,
+		created_at    BIGINT NOT NULL
+	)`,
+	`CREATE TABLE user_identities (
+		issuer     TEXT   NOT NULL,
+		subject    TEXT   NOT NULL,
+		user_id    TEXT   NOT NULL,
+		created_at BIGINT NOT NULL,
+		PRIMARY KEY (issuer, subject)
+	)`,
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+}
+
+var sessionMigrations = []string{
+	`CREATE TABLE sessions (
+		token_hash TEXT   PRIMARY KEY,
+		user_id    TEXT   NOT NULL,
+		created_at BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`,
+	`CREATE INDEX idx_sessions_expires_at ON sessions (expires_at)`,
+	`CREATE INDEX idx_sessions_user_id ON sessions (user_id)`,
+}
+
+const userColumns = "id, email, password_hash, created_at, role"
+
+type SQLUserRepository struct {
+	db      *sql.DB
+	dialect sqlstore.Dialect
+}
+
+// NewSQLUserRepository stores users in db, which is typically shared with
+// the URL repository of the same driver. The caller keeps ownership of db.
+func NewSQLUserRepository(db *sql.DB, dialect sqlstore.Dialect) (domain.UserRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sqlstore.Migrate(ctx, db, "users", userMigrations); err != nil {
+		return nil, err
+	}
+
+	return &SQLUserRepository{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+func (r *SQLUserRepository) Create(ctx context.Context, user *domain.User) error {
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`),
+		user.ID, user.Email, user.PasswordHash, user.CreatedAt.UnixNano(), user.Role,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to insert user: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrUserExists
+	}
+	return nil
+}
+
+func (r *SQLUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	return r.findBy(ctx, "id", id)
+}
+
+func (r *SQLUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.findBy(ctx, "email", email)
+}
+
+func (r *SQLUserRepository) findBy(ctx context.Context, column, value string) (*domain.User, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		SELECT `+userColumns+` FROM users WHERE `+column+` = ?`),
+		value,
+	)
+
+	var (
+		user      domain.User
+		createdAt int64
+	)
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &createdAt, &user.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	user.CreatedAt = time.Unix(0, createdAt)
+	return &user, nil
+}
+
+func (r *SQLUserRepository) SetPasswordHash(ctx context.Context, id, hash string) error {
+	return r.update(ctx, "password_hash", hash, id)
+}
+
+func (r *SQLUserRepository) SetRole(ctx context.Context, id string, role domain.Role) error {
+	return r.update(ctx, "role", string(role), id)
+}
+
+func (r *SQLUserRepository) update(ctx context.Context, column, value, id string) error {
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		UPDATE users SET `+column+` = ? WHERE id = ?`),
+		value, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *SQLUserRepository) LinkIdentity(ctx context.Context, issuer, subject, userID string) error {
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		INSERT INTO user_identities (issuer, subject, user_id, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING`),
+		issuer, subject, userID, time.Now().UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	if affected == 0 {
+		return domain.ErrUserExists
+	}
+	return nil
+}
+
+func (r *SQLUserRepository) FindByIdentity(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	var userID string
+	err := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?`),
+		issuer, subject,
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+	return r.FindByID(ctx, userID)
+}
+
+type SQLSessionRepository struct {
+	db      *sql.DB
+	dialect sqlstore.Dialect
+}
+
+// NewSQLSessionRepository stores sessions in db, like NewSQLUserRepository.
+func NewSQLSessionRepository(db *sql.DB, dialect sqlstore.Dialect) (domain.SessionRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sqlstore.Migrate(ctx, db, "sessions", sessionMigrations); err != nil {
+		return nil, err
+	}
+
+	return &SQLSessionRepository{
+		db:      db,
+		dialect: dialect,
+	}, nil
+}
+
+// Create also removes expired sessions, which keeps abandoned ones from
+// piling up without a background sweeper.
+func (r *SQLSessionRepository) Create(ctx context.Context, session *domain.Session) error {
+	if _, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		DELETE FROM sessions WHERE expires_at <= ?`),
+		time.Now().UnixNano(),
+	); err != nil {
+		return fmt.Errorf("failed to remove expired sessions: %w", err)
+	}
+
+	_, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		INSERT INTO sessions (token_hash, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`),
+		session.Hash, session.UserID, session.CreatedAt.UnixNano(), session.ExpiresAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLSessionRepository) Find(ctx context.Context, hash string) (*domain.Session, error) {
+	row := r.db.QueryRowContext(ctx, r.dialect.Rebind(`
+		SELECT token_hash, user_id, created_at, expires_at FROM sessions
+		WHERE token_hash = ? AND expires_at > ?`),
+		hash, time.Now().UnixNano(),
+	)
+
+	var (
+		session              domain.Session
+		createdAt, expiresAt int64
+	)
+	err := row.Scan(&session.Hash, &session.UserID, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	session.CreatedAt = time.Unix(0, createdAt)
+	session.ExpiresAt = time.Unix(0, expiresAt)
+	return &session, nil
+}
+
+func (r *SQLSessionRepository) Delete(ctx context.Context, hash string) error {
+	if _, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		DELETE FROM sessions WHERE token_hash = ?`),
+		hash,
+	); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+func (r *SQLSessionRepository) DeleteByUser(ctx context.Context, userID string) error {
+	if _, err := r.db.ExecContext(ctx, r.dialect.Rebind(`
+		DELETE FROM sessions WHERE user_id = ?`),
+		userID,
+	); err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package repository_test
+
+import (
+	"testing"
+	"url-shortener/internal/infrastructure/repository"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLUserRepository_SQLite(t *testing.T) {
+	urlRepo := newSQLiteRepo(t, ":memory:", 0)
+
+	users, err := repository.NewSQLUserRepository(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+	sessions, err := repository.NewSQLSessionRepository(urlRepo.DB(), sqlstore.DialectSQLite)
+	require.NoError(t, err)
+
+	testUserRepository(t, users)
+	testSessionRepository(t, sessions)
+}
+
+func TestSQLUserRepository_Postgres(t *testing.T) {
+	urlRepo := newPostgresRepo(t, 0)
+
+	users, err := repository.NewSQLUserRepository(urlRepo.DB(), sqlstore.DialectPostgres)
+	require.NoError(t, err)
+	sessions, err := repository.NewSQLSessionRepository(urlRepo.DB(), sqlstore.DialectPostgres)
+	require.NoError(t, err)
+
+	testUserRepository(t, users)
+	testSessionRepository(t, sessions)
+}
@@ -0,0 +1,120 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"url-shortener/internal/domain"
+)
+
+// HashListScanner flags URLs listed in a local file. Each line holds an entry
+// and, optionally, the threat it is listed for:
+//
+//	# comments and blank lines are ignored
+//	evil.example                 phishing
+//	files.example/dropper.exe    malware
+//	0f3c...e9 (64 hex digits)    malware
+//
+// An entry is a host, matching the host and its subdomains, or a host and
+// path prefix. It may also be given as the SHA-256 of such an expression, so
+// feeds can be shared without publishing the URLs themselves.
+type HashListScanner struct {
+	path    string
+	entries atomic.Pointer[map[[sha256.Size]byte]domain.Threat]
+}
+
+func NewHashListScanner(path string) (*HashListScanner, error) {
+	s := &HashListScanner{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload rereads the list file. On error the previous list stays in effect.
+func (s *HashListScanner) Reload() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to open hash list: %w", err)
+	}
+	defer f.Close()
+
+	entries := make(map[[sha256.Size]byte]domain.Threat)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return fmt.Errorf("invalid entry on line %d of %s", line, s.path)
+		}
+
+		threat := domain.ThreatMalware
+		if len(fields) == 2 {
+			threat = domain.Threat(strings.ToLower(fields[1]))
+		}
+
+		if hash, err := hex.DecodeString(fields[0]); err == nil && len(hash) == sha256.Size {
+			entries[[sha256.Size]byte(hash)] = threat
+			continue
+		}
+		entries[HashExpression(fields[0])] = threat
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read hash list: %w", err)
+	}
+
+	s.entries.Store(&entries)
+	return nil
+}
+
+func (s *HashListScanner) Scan(ctx context.Context, rawURL string) (*domain.ScanResult, error) {
+	entries := *s.entries.Load()
+	for _, expr := range expressions(rawURL) {
+		if threat, ok := entries[HashExpression(expr)]; ok {
+			return &domain.ScanResult{Threat: threat, Detail: "listed in " + s.path}, nil
+		}
+	}
+	return &domain.ScanResult{}, nil
+}
+
+// HashExpression returns the hash a list entry is matched by.
+func HashExpression(expr string) [sha256.Size]byte {
+	expr = strings.ToLower(strings.TrimSuffix(expr, "/"))
+	return sha256.Sum256([]byte(expr))
+}
+
+// expressions lists the host and path prefix combinations of rawURL that a
+// list entry can match, from the most to the least specific.
+func expressions(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	var exprs []string
+
+	segments := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	for i := len(segments); i > 0; i-- {
+		if path := strings.Join(segments[:i], "/"); path != "" {
+			exprs = append(exprs, host+"/"+path)
+		}
+	}
+
+	for {
+		exprs = append(exprs, host)
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return exprs
+		}
+		host = host[i+1:]
+	}
+}
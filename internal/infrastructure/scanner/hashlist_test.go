@@ -0,0 +1,84 @@
+package scanner_test
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeHashList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestHashListScanner_Scan(t *testing.T) {
+	hash := scanner.HashExpression("hidden.example/login")
+	path := writeHashList(t, "# feed\n"+
+		"evil.example phishing\n"+
+		"files.example/payload/ malware\n"+
+		"  \n"+
+		hex.EncodeToString(hash[:])+" phishing\n"+
+		"untyped.example\n")
+
+	s, err := scanner.NewHashListScanner(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		url    string
+		threat domain.Threat
+	}{
+		{url: "https://evil.example/", threat: domain.ThreatPhishing},
+		{url: "https://login.EVIL.example./account?x=1", threat: domain.ThreatPhishing},
+		{url: "http://files.example/payload/setup.exe", threat: domain.ThreatMalware},
+		{url: "http://files.example/payload", threat: domain.ThreatMalware},
+		{url: "https://hidden.example/login/step2", threat: domain.ThreatPhishing},
+		{url: "https://untyped.example", threat: domain.ThreatMalware},
+		{url: "http://files.example/", threat: ""},
+		{url: "http://files.example/payloads/readme", threat: ""},
+		{url: "https://hidden.example/", threat: ""},
+		{url: "https://notevil.example/", threat: ""},
+		{url: "https://example.com/evil.example", threat: ""},
+	}
+	for _, tt := range tests {
+		result, err := s.Scan(context.Background(), tt.url)
+		require.NoError(t, err)
+		assert.Equal(t, tt.threat, result.Threat, tt.url)
+		assert.Equal(t, tt.threat != "", result.Flagged(), tt.url)
+	}
+}
+
+func TestHashListScanner_Reload(t *testing.T) {
+	path := writeHashList(t, "evil.example\n")
+	s, err := scanner.NewHashListScanner(path)
+	require.NoError(t, err)
+
+	flagged := func(rawURL string) bool {
+		result, err := s.Scan(context.Background(), rawURL)
+		require.NoError(t, err)
+		return result.Flagged()
+	}
+	assert.True(t, flagged("https://evil.example"))
+
+	require.NoError(t, os.WriteFile(path, []byte("worse.example\n"), 0o600))
+	require.NoError(t, s.Reload())
+	assert.False(t, flagged("https://evil.example"))
+	assert.True(t, flagged("https://worse.example"))
+
+	require.NoError(t, os.WriteFile(path, []byte("a b c\n"), 0o600))
+	assert.Error(t, s.Reload())
+	assert.True(t, flagged("https://worse.example"), "a broken list should keep the previous one")
+}
+
+func TestNewHashListScanner_MissingFile(t *testing.T) {
+	_, err := scanner.NewHashListScanner(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"url-shortener/internal/domain"
+)
+
+const (
+	DefaultWebhookTimeout = 2 * time.Second
+
+	// maxWebhookResponse bounds how much of a verdict is read.
+	maxWebhookResponse = 64 << 10
+)
+
+type WebhookConfig struct {
+	URL     string        // endpoint the verdicts are requested from
+	Token   string        // sent as a bearer token when set
+	Timeout time.Duration // per request; DefaultWebhookTimeout when zero
+	Client  *http.Client  // http.DefaultClient when nil
+}
+
+// WebhookScanner asks an HTTP service for a verdict on each URL. It POSTs
+//
+//	{"url": "https://example.com/"}
+//
+// and expects a 2xx response such as
+//
+//	{"threat": "phishing", "detail": "reported 2026-10-01"}
+//
+// where an empty or missing threat means the URL is clean.
+type WebhookScanner struct {
+	cfg WebhookConfig
+}
+
+type webhookRequest struct {
+	URL string `json:"url"`
+}
+
+type webhookResponse struct {
+	Threat string `json:"threat"`
+	Detail string `json:"detail"`
+}
+
+func NewWebhookScanner(cfg WebhookConfig) domain.URLScanner {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultWebhookTimeout
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &WebhookScanner{cfg: cfg}
+}
+
+func (s *WebhookScanner) Scan(ctx context.Context, rawURL string) (*domain.ScanResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(webhookRequest{URL: rawURL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode scan request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scan request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call scanner: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("scanner returned status %d", resp.StatusCode)
+	}
+
+	var verdict webhookResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxWebhookResponse)).Decode(&verdict); err != nil {
+		return nil, fmt.Errorf("failed to decode scanner response: %w", err)
+	}
+
+	return &domain.ScanResult{Threat: domain.Threat(verdict.Threat), Detail: verdict.Detail}, nil
+}
@@ -0,0 +1,80 @@
+package scanner_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"url-shortener/internal/domain"
+	"url-shortener/internal/infrastructure/scanner"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookScanner_Scan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			URL string `json:"url"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch req.URL {
+		case "https://phish.example/login":
+			w.Write([]byte(`{"threat": "phishing", "detail": "reported by users"}`))
+		case "https://broken.example/":
+			w.Write([]byte(`not json`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	s := scanner.NewWebhookScanner(scanner.WebhookConfig{URL: server.URL, Token: "s3cret"})
+	ctx := context.Background()
+
+	result, err := s.Scan(ctx, "https://phish.example/login")
+	require.NoError(t, err)
+	assert.True(t, result.Flagged())
+	assert.Equal(t, domain.ThreatPhishing, result.Threat)
+	assert.Equal(t, "reported by users", result.Detail)
+
+	result, err = s.Scan(ctx, "https://example.com/")
+	require.NoError(t, err)
+	assert.False(t, result.Flagged())
+
+	_, err = s.Scan(ctx, "https://broken.example/")
+	assert.Error(t, err)
+
+	unauthorized := scanner.NewWebhookScanner(scanner.WebhookConfig{URL: server.URL})
+	_, err = unauthorized.Scan(ctx, "https://example.com/")
+	assert.ErrorContains(t, err, "status 401")
+}
+
+func TestWebhookScanner_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	s := scanner.NewWebhookScanner(scanner.WebhookConfig{URL: server.URL, Timeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	_, err := s.Scan(context.Background(), "https://example.com/")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
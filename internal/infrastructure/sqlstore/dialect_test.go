@@ -0,0 +1,15 @@
+package sqlstore_test
+
+import (
+	"testing"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect_Rebind(t *testing.T) {
+	query := "SELECT a FROM t WHERE b = ? AND c > ? LIMIT ?"
+
+	assert.Equal(t, query, sqlstore.DialectSQLite.Rebind(query))
+	assert.Equal(t, "SELECT a FROM t WHERE b = $1 AND c > $2 LIMIT $3", sqlstore.DialectPostgres.Rebind(query))
+}
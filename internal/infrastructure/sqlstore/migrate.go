@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	component  TEXT    NOT NULL,
+	version    INTEGER NOT NULL,
+	applied_at BIGINT  NOT NULL,
+	PRIMARY KEY (component, version)
+)`
+
+// Migrate applies the given statements in order for the named component.
+// Each statement is recorded as a version in schema_migrations, so reopening
+// an existing database only runs the statements added since.
+func Migrate(ctx context.Context, db *sql.DB, component string, migrations []string) error {
+	if _, err := db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	row := db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE component = '%s'", component,
+	))
+	if err := row.Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := current; i < len(migrations); i++ {
+		version := i + 1
+		if err := apply(ctx, db, component, version, migrations[i]); err != nil {
+			return fmt.Errorf("failed to apply %s migration %d: %w", component, version, err)
+		}
+	}
+
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, component string, version int, statement string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+
+	record := fmt.Sprintf(
+		"INSERT INTO schema_migrations (component, version, applied_at) VALUES ('%s', %d, %d)",
+		component, version, time.Now().Unix(),
+	)
+	if _, err := tx.ExecContext(ctx, record); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,53 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"url-shortener/internal/infrastructure/sqlstore"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	ctx := context.Background()
+	migrations := []string{
+		`CREATE TABLE widgets (id INTEGER PRIMARY KEY)`,
+	}
+
+	require.NoError(t, sqlstore.Migrate(ctx, db, "widgets", migrations))
+	// running again must not re-apply the CREATE TABLE
+	require.NoError(t, sqlstore.Migrate(ctx, db, "widgets", migrations))
+
+	migrations = append(migrations, `ALTER TABLE widgets ADD COLUMN name TEXT`)
+	require.NoError(t, sqlstore.Migrate(ctx, db, "widgets", migrations))
+
+	_, err = db.Exec(`INSERT INTO widgets (id, name) VALUES (1, 'gear')`)
+	assert.NoError(t, err)
+
+	var version int
+	require.NoError(t, db.QueryRow(`SELECT MAX(version) FROM schema_migrations WHERE component = 'widgets'`).Scan(&version))
+	assert.Equal(t, 2, version)
+}
+
+func TestMigrate_FailedStatementRollsBack(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	ctx := context.Background()
+	err = sqlstore.Migrate(ctx, db, "broken", []string{`CREATE TABLE ok (id INTEGER)`, `NOT VALID SQL`})
+	assert.Error(t, err)
+
+	var version int
+	require.NoError(t, db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE component = 'broken'`).Scan(&version))
+	assert.Equal(t, 1, version)
+}
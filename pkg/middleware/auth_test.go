@@ -0,0 +1,161 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubAuthenticator map[string]*domain.APIKey
+
+func (s stubAuthenticator) Authenticate(ctx context.Context, secret string) (*domain.APIKey, error) {
+	if secret == "broken" {
+		return nil, errors.New("database unavailable")
+	}
+	key, ok := s[secret]
+	if !ok {
+		return nil, domain.ErrUnauthorized
+	}
+	return key, nil
+}
+
+func TestAPIKeyAuthMiddleware(t *testing.T) {
+	auth := stubAuthenticator{
+		"usk_valid": {ID: "key1", OwnerID: "owner1"},
+		"usk_admin": {ID: "key2", OwnerID: "owner2", Role: domain.RoleAdmin},
+	}
+	required := []string{"POST /api/v1/links"}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		header        http.Header
+		wantStatus    int
+		wantPrincipal *domain.Principal
+	}{
+		{
+			name:       "anonymous request on optional route",
+			method:     http.MethodGet,
+			path:       "/api/v1/links/abc",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:          "bearer token",
+			method:        http.MethodGet,
+			path:          "/api/v1/links/abc",
+			header:        http.Header{"Authorization": {"Bearer usk_valid"}},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+		},
+		{
+			name:          "lowercase bearer scheme",
+			method:        http.MethodPost,
+			path:          "/api/v1/links",
+			header:        http.Header{"Authorization": {"bearer usk_valid"}},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+		},
+		{
+			name:          "X-API-Key header",
+			method:        http.MethodPost,
+			path:          "/api/v1/links",
+			header:        http.Header{"X-Api-Key": {"usk_valid"}},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"},
+		},
+		{
+			name:          "key role",
+			method:        http.MethodGet,
+			path:          "/api/v1/links",
+			header:        http.Header{"Authorization": {"Bearer usk_admin"}},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: &domain.Principal{OwnerID: "owner2", APIKeyID: "key2", Role: domain.RoleAdmin},
+		},
+		{
+			name:       "missing key on required route",
+			method:     http.MethodPost,
+			path:       "/api/v1/links",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "other authorization scheme on required route",
+			method:     http.MethodPost,
+			path:       "/api/v1/links",
+			header:     http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid key on optional route",
+			method:     http.MethodGet,
+			path:       "/api/v1/links/abc",
+			header:     http.Header{"X-Api-Key": {"usk_wrong"}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "authenticator failure",
+			method:     http.MethodGet,
+			path:       "/api/v1/links/abc",
+			header:     http.Header{"X-Api-Key": {"broken"}},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *domain.Principal
+			handler := middleware.APIKeyAuthMiddleware(auth, middleware.WithRequiredRoutes(required))(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got = domain.PrincipalFromContext(r.Context())
+				}))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantPrincipal, got)
+			if tt.wantStatus == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", rr.Header().Get("WWW-Authenticate"))
+				assert.JSONEq(t, `{"error":"unauthorized","message":"A valid API key is required"}`, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeyIdentity(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := middleware.APIKeyIdentity(req)
+	assert.False(t, ok)
+
+	ctx := domain.ContextWithPrincipal(req.Context(), &domain.Principal{OwnerID: "owner1", APIKeyID: "key1"})
+	id, ok := middleware.APIKeyIdentity(req.WithContext(ctx))
+	assert.True(t, ok)
+	assert.Equal(t, "key1", id)
+}
+
+func TestAPIKeyAuthMiddleware_LoggedIn(t *testing.T) {
+	user := &domain.Principal{OwnerID: "u1", UserID: "u1"}
+	var got *domain.Principal
+	handler := middleware.APIKeyAuthMiddleware(stubAuthenticator{}, middleware.WithRequiredRoutes([]string{"POST /shorten"}))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = domain.PrincipalFromContext(r.Context())
+		}))
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	req = req.WithContext(domain.ContextWithPrincipal(req.Context(), user))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "a login satisfies required routes")
+	assert.Equal(t, user, got)
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ClientIPMiddleware resolves the client address once per request, so that
+// logging, tracing and rate limiting agree on it. Only proxyHeader is read,
+// the one header the trusted proxies set: X-Forwarded-For, Forwarded or
+// X-Real-IP. Any other forwarding header may have come from the client. It
+// is only believed when the peer is one of the trusted proxies, and is walked
+// from the right: the first address not belonging to a trusted proxy is the
+// client, since everything left of it could have been sent by the client.
+func ClientIPMiddleware(trustedProxies []netip.Prefix, proxyHeader string) func(http.Handler) http.Handler {
+	proxyHeader = http.CanonicalHeaderKey(proxyHeader)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if addr, ok := resolveClientIP(r, trustedProxies, proxyHeader); ok {
+				r = r.WithContext(context.WithValue(r.Context(), clientIPKey{}, addr))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the address resolved by ClientIPMiddleware, falling back
+// to the peer address when the middleware did not run.
+func ClientIP(r *http.Request) string {
+	if addr, ok := clientAddr(r); ok {
+		return addr.String()
+	}
+	return r.RemoteAddr
+}
+
+func clientAddr(r *http.Request) (netip.Addr, bool) {
+	if addr, ok := r.Context().Value(clientIPKey{}).(netip.Addr); ok {
+		return addr, true
+	}
+	return peerAddr(r)
+}
+
+func resolveClientIP(r *http.Request, trustedProxies []netip.Prefix, proxyHeader string) (netip.Addr, bool) {
+	addr, ok := peerAddr(r)
+	if !ok || !trusted(addr, trustedProxies) {
+		return addr, ok
+	}
+
+	var hops []string
+	switch proxyHeader {
+	case "Forwarded":
+		hops = forwardedFor(r.Header.Values("Forwarded"))
+	case "X-Forwarded-For":
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+	case "X-Real-Ip":
+		if v := r.Header.Get("X-Real-IP"); v != "" {
+			hops = []string{v}
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseHop(hops[i])
+		if !ok {
+			// Obfuscated or garbled, so the proxy that added it is as far
+			// back as we can tell.
+			break
+		}
+		addr = hop
+		if !trusted(addr, trustedProxies) {
+			break
+		}
+	}
+	return addr, true
+}
+
+// forwardedFor extracts the for= parameters of RFC 7239 Forwarded headers,
+// one per proxy hop.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			hop := ""
+			for _, pair := range strings.Split(element, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				if strings.EqualFold(name, "for") {
+					hop = strings.Trim(value, `"`)
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// parseHop accepts an address with or without a port, in brackets for IPv6.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.TrimSpace(hop)
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		hop = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(hop, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func trusted(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func peerAddr(r *http.Request) (netip.Addr, bool) {
+	return parseHop(r.RemoteAddr)
+}
@@ -0,0 +1,163 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"url-shortener/pkg/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIPMiddleware(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		header     string // the proxy header; X-Forwarded-For when empty
+		remoteAddr string
+		headers    http.Header
+		want       string
+	}{
+		{
+			name:       "peer without port",
+			remoteAddr: "192.168.1.1:12345",
+			want:       "192.168.1.1",
+		},
+		{
+			name:       "IPv6 peer without port",
+			remoteAddr: "[2001:db8::1]:443",
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "forwarding headers ignored without trusted proxies",
+			remoteAddr: "192.168.1.1:12345",
+			headers:    http.Header{"X-Forwarded-For": {"1.2.3.4"}, "X-Real-Ip": {"1.2.3.5"}},
+			want:       "192.168.1.1",
+		},
+		{
+			name:       "forwarding headers ignored from untrusted peer",
+			trusted:    trusted,
+			remoteAddr: "192.168.1.1:12345",
+			headers:    http.Header{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "192.168.1.1",
+		},
+		{
+			name:       "X-Forwarded-For from trusted proxy",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"X-Forwarded-For": {"1.2.3.4"}},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "spoofed entries left of the client are skipped",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4"}},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "chain of trusted proxies",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"X-Forwarded-For": {"6.6.6.6, 1.2.3.4", "10.0.0.2"}},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "only trusted proxies",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "garbled entry stops the walk",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"X-Forwarded-For": {"1.2.3.4, bogus"}},
+			want:       "10.0.0.1",
+		},
+		{
+			name:       "Forwarded header",
+			trusted:    trusted,
+			header:     "Forwarded",
+			remoteAddr: "[fd00::1]:443",
+			headers: http.Header{"Forwarded": {
+				`for=6.6.6.6;proto=https, for="[2001:db8:cafe::17]:4711";by=10.0.0.9, for=10.0.0.2:80`,
+			}},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:       "client-supplied Forwarded ignored when the proxy sets X-Forwarded-For",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"Forwarded": {"For=1.2.3.4"}, "X-Forwarded-For": {"5.6.7.8"}},
+			want:       "5.6.7.8",
+		},
+		{
+			name:       "client-supplied X-Forwarded-For ignored when the proxy sets Forwarded",
+			trusted:    trusted,
+			header:     "forwarded",
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"Forwarded": {"for=5.6.7.8"}, "X-Forwarded-For": {"1.2.3.4"}},
+			want:       "5.6.7.8",
+		},
+		{
+			name:       "obfuscated Forwarded identifier",
+			trusted:    trusted,
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"Forwarded": {"for=_hidden, for=10.0.0.2"}},
+			want:       "10.0.0.2",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			trusted:    trusted,
+			header:     "X-Real-IP",
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"X-Real-Ip": {"1.2.3.4"}, "X-Forwarded-For": {"5.6.7.8"}},
+			want:       "1.2.3.4",
+		},
+		{
+			name:       "X-Real-IP ignored when the proxy sets X-Forwarded-For",
+			trusted:    trusted,
+			remoteAddr: "10.0.0.1:12345",
+			headers:    http.Header{"X-Real-Ip": {"1.2.3.4"}},
+			want:       "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			header := tt.header
+			if header == "" {
+				header = "X-Forwarded-For"
+			}
+			handler := middleware.ClientIPMiddleware(tt.trusted, header)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = middleware.ClientIP(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header[k] = v
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientIP_WithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+	assert.Equal(t, "192.168.1.1", middleware.ClientIP(req))
+
+	req.RemoteAddr = "@"
+	assert.Equal(t, "@", middleware.ClientIP(req))
+}
@@ -22,7 +22,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			"%s %s %s %d %v",
 			r.Method,
 			r.URL.Path,
-			r.RemoteAddr,
+			ClientIP(r),
 			wrapped.statusCode,
 			duration,
 		)
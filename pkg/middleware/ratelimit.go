@@ -1,24 +1,103 @@
 package middleware
 
 import (
+	"log"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
 	"url-shortener/internal/domain"
 )
 
+type IdentityClass string
+
+const (
+	IdentityAnonymous   IdentityClass = "anonymous"   // identified by client IP
+	IdentityAPIKey      IdentityClass = "api_key"     // identified by the API key
+	IdentityAllowlisted IdentityClass = "allowlisted" // client IP inside an allowlisted CIDR
+)
+
+// RateLimitPolicy limits the requests it matches. Policies are tried in
+// order and the first match decides; requests no policy matches are not
+// limited.
+type RateLimitPolicy struct {
+	Name string // keeps the budgets of different policies apart
+
+	// Routes are patterns like "POST /shorten" or "/api/v1/links/". The
+	// method is optional, and a path ending in "/" matches everything below
+	// it. No routes match every request.
+	Routes []string
+
+	Identities []IdentityClass    // no identities match every client
+	Limiter    domain.RateLimiter // nil lets matching requests through
+}
+
+type RateLimitOption func(*rateLimitOptions)
+
+type rateLimitOptions struct {
+	allowlist []netip.Prefix
+	apiKey    func(r *http.Request) (string, bool)
+}
+
+// WithAllowlist classifies clients in these networks as allowlisted. The
+// address is the one ClientIPMiddleware resolved, which only believes
+// forwarding headers from trusted proxies.
+func WithAllowlist(prefixes []netip.Prefix) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		o.allowlist = prefixes
+	}
+}
+
+// WithAPIKeyIdentity classifies requests as API key clients when fn returns
+// a stable identifier for a verified key.
+func WithAPIKeyIdentity(fn func(r *http.Request) (string, bool)) RateLimitOption {
+	return func(o *rateLimitOptions) {
+		o.apiKey = fn
+	}
+}
+
 func RateLimitingMiddleware(limiter domain.RateLimiter) func(http.Handler) http.Handler {
+	return PolicyRateLimitingMiddleware([]RateLimitPolicy{{Limiter: limiter}})
+}
+
+// PolicyRateLimitingMiddleware applies the first policy matching each
+// request's route and client.
+func PolicyRateLimitingMiddleware(policies []RateLimitPolicy, opts ...RateLimitOption) func(http.Handler) http.Handler {
+	var o rateLimitOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			identifier := extractIdentifier(r)
+			class, key := o.identify(r)
+
+			policy := matchPolicy(policies, r, class)
+			if policy == nil || policy.Limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-			allowed, err := limiter.Allow(r.Context(), identifier)
+			identifier := key
+			if policy.Name != "" {
+				identifier = policy.Name + ":" + key
+			}
+
+			// A limiter outage lets requests through rather than taking the
+			// service down with it, but must not go unnoticed.
+			result, err := policy.Limiter.Take(r.Context(), identifier)
 			if err != nil {
+				log.Printf("Warning: rate limiting skipped for policy %q: %v", policy.Name, err)
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			if !allowed {
+			setRateLimitHeaders(w.Header(), result)
+
+			if !result.Allowed {
 				w.Header().Set("Content-Type", "application/json")
-				w.Header().Set("Retry-After", "60")
+				w.Header().Set("Retry-After", strconv.FormatInt(max(1, ceilSeconds(result.RetryAfter)), 10))
 				http.Error(w, `{"error":"Rate limit exceeded","message":"Too many requests. Please try again later."}`, http.StatusTooManyRequests)
 				return
 			}
@@ -28,14 +107,84 @@ func RateLimitingMiddleware(limiter domain.RateLimiter) func(http.Handler) http.
 	}
 }
 
-func extractIdentifier(r *http.Request) string {
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		return forwarded
+// setRateLimitHeaders reports the quota in the fields of the IETF RateLimit
+// header draft.
+func setRateLimitHeaders(h http.Header, result *domain.RateLimitResult) {
+	h.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+}
+
+// ceilSeconds rounds up so clients never come back too early.
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+func (o *rateLimitOptions) identify(r *http.Request) (IdentityClass, string) {
+	if len(o.allowlist) > 0 {
+		if addr, ok := clientAddr(r); ok && trusted(addr, o.allowlist) {
+			return IdentityAllowlisted, addr.String()
+		}
+	}
+
+	if o.apiKey != nil {
+		if id, ok := o.apiKey(r); ok {
+			return IdentityAPIKey, "key:" + id
+		}
+	}
+
+	return IdentityAnonymous, ClientIP(r)
+}
+
+func matchPolicy(policies []RateLimitPolicy, r *http.Request, class IdentityClass) *RateLimitPolicy {
+	for i := range policies {
+		p := &policies[i]
+		if matchIdentity(p.Identities, class) && matchRoutes(p.Routes, r) {
+			return p
+		}
+	}
+	return nil
+}
+
+func matchIdentity(classes []IdentityClass, class IdentityClass) bool {
+	if len(classes) == 0 {
+		return true
+	}
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func matchRoutes(routes []string, r *http.Request) bool {
+	if len(routes) == 0 {
+		return true
 	}
+	for _, route := range routes {
+		if MatchRoute(route, r.Method, r.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
 
-	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-		return realIP
+// MatchRoute reports whether a request matches a pattern such as
+// "POST /shorten" or "/api/v1/links/".
+func MatchRoute(pattern, method, path string) bool {
+	if m, p, ok := strings.Cut(pattern, " "); ok {
+		if !strings.EqualFold(m, method) {
+			return false
+		}
+		pattern = strings.TrimSpace(p)
 	}
 
-	return r.RemoteAddr
+	if strings.HasSuffix(pattern, "/") {
+		return strings.HasPrefix(path, pattern)
+	}
+	return path == pattern
 }
@@ -1,11 +1,19 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"os"
 	"strings"
 	"testing"
 	"time"
+	"url-shortener/internal/domain"
 	"url-shortener/internal/infrastructure/ratelimiter"
 )
 
@@ -48,9 +56,48 @@ func TestRateLimitingMiddleware_Allow(t *testing.T) {
 		t.Errorf("got body %q, want %q", gotBody, expectedBody)
 	}
 
-	// Check Retry-After header
-	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "60" {
-		t.Errorf("got Retry-After %q, want %q", retryAfter, "60")
+	// Retry-After follows the window instead of a fixed minute
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("got Retry-After %q, want %q", retryAfter, "1")
+	}
+}
+
+func TestRateLimitingMiddleware_Headers(t *testing.T) {
+	t.Parallel()
+
+	handler := RateLimitingMiddleware(newTestLimiter(t, 2))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{status: http.StatusOK, remaining: "1"},
+		{status: http.StatusOK, remaining: "0"},
+		{status: http.StatusTooManyRequests, remaining: "0", retryAfter: "60"},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.status {
+			t.Errorf("request %d: got status %d, want %d", i+1, rr.Code, tt.status)
+		}
+		want := map[string]string{
+			"RateLimit-Limit":     "2",
+			"RateLimit-Remaining": tt.remaining,
+			"RateLimit-Reset":     "60",
+			"Retry-After":         tt.retryAfter,
+		}
+		for name, value := range want {
+			if got := rr.Header().Get(name); got != value {
+				t.Errorf("request %d: got %s %q, want %q", i+1, name, got, value)
+			}
+		}
 	}
 }
 
@@ -89,9 +136,11 @@ func TestRateLimitingMiddleware_XForwardedFor(t *testing.T) {
 	rl := ratelimiter.NewMemoryRateLimiter(2, 1*time.Second)
 	defer rl.(*ratelimiter.MemoryRateLimiter).Close()
 
-	handler := RateLimitingMiddleware(rl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	// The three peers are trusted proxies forwarding for the same client
+	trusted := []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+	handler := ClientIPMiddleware(trusted, "X-Forwarded-For")(RateLimitingMiddleware(rl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
-	}))
+	})))
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req.Header.Set("X-Forwarded-For", "10.0.0.1")
@@ -127,6 +176,19 @@ func TestRateLimitingMiddleware_XForwardedFor(t *testing.T) {
 	if rr3.Code != http.StatusTooManyRequests {
 		t.Errorf("got status %d, want %d", rr3.Code, http.StatusTooManyRequests)
 	}
+
+	// An untrusted peer cannot pick its identity, nor dodge its own limit
+	for i := 0; i < 3; i++ {
+		header := http.Header{"X-Forwarded-For": {fmt.Sprintf("10.9.9.%d", i)}}
+		got := serveStatus(handler, http.MethodGet, "/", "203.0.113.7:4000", header)
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if got != want {
+			t.Errorf("spoofed request %d: got status %d, want %d", i+1, got, want)
+		}
+	}
 }
 
 func TestRateLimitingMiddleware_XRealIP(t *testing.T) {
@@ -151,55 +213,204 @@ func TestRateLimitingMiddleware_XRealIP(t *testing.T) {
 	}
 }
 
-func TestExtractIdentifier(t *testing.T) {
+func newTestLimiter(t *testing.T, limit int) domain.RateLimiter {
+	t.Helper()
+	rl := ratelimiter.NewMemoryRateLimiter(limit, time.Minute)
+	t.Cleanup(rl.(*ratelimiter.MemoryRateLimiter).Close)
+	return rl
+}
+
+func serveStatus(handler http.Handler, method, path, remoteAddr string, header http.Header) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestPolicyRateLimitingMiddleware_Routes(t *testing.T) {
 	t.Parallel()
 
+	policies := []RateLimitPolicy{
+		{Name: "create", Routes: []string{"POST /shorten", "POST /api/v1/links"}, Limiter: newTestLimiter(t, 2)},
+		{Name: "qrcode", Routes: []string{"GET /qrcode/"}},
+		{Name: "default", Limiter: newTestLimiter(t, 5)},
+	}
+	handler := PolicyRateLimitingMiddleware(policies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	const client = "192.168.1.1:12345"
+
 	tests := []struct {
-		name     string
-		request  *http.Request
-		wantPref string // preferred identifier (X-Forwarded-For or X-Real-IP)
+		method string
+		path   string
+		want   int
 	}{
-		{
-			name: "X-Forwarded-For takes priority",
-			request: func() *http.Request {
-				req := httptest.NewRequest(http.MethodGet, "/", nil)
-				req.Header.Set("X-Forwarded-For", "10.0.0.1")
-				req.Header.Set("X-Real-IP", "10.0.0.2")
-				req.RemoteAddr = "192.168.1.1:12345"
-				return req
-			}(),
-			wantPref: "10.0.0.1",
-		},
-		{
-			name: "X-Real-IP used when X-Forwarded-For not present",
-			request: func() *http.Request {
-				req := httptest.NewRequest(http.MethodGet, "/", nil)
-				req.Header.Set("X-Real-IP", "10.0.0.2")
-				req.RemoteAddr = "192.168.1.1:12345"
-				return req
-			}(),
-			wantPref: "10.0.0.2",
-		},
-		{
-			name: "RemoteAddr used as fallback",
-			request: func() *http.Request {
-				req := httptest.NewRequest(http.MethodGet, "/", nil)
-				req.RemoteAddr = "192.168.1.1:12345"
-				return req
-			}(),
-			wantPref: "192.168.1.1:12345",
-		},
+		{http.MethodPost, "/shorten", http.StatusOK},
+		{http.MethodPost, "/api/v1/links", http.StatusOK},
+		{http.MethodPost, "/shorten", http.StatusTooManyRequests},
+		{http.MethodPost, "/api/v1/links", http.StatusTooManyRequests},
+		{http.MethodGet, "/api/v1/links", http.StatusOK},
+		{http.MethodGet, "/abc123", http.StatusOK},
+		{http.MethodGet, "/abc123", http.StatusOK},
+		{http.MethodGet, "/qrcode/abc123", http.StatusOK},
+		{http.MethodGet, "/qrcode/abc123", http.StatusOK},
+		{http.MethodGet, "/qrcode/abc123", http.StatusOK},
+		{http.MethodGet, "/", http.StatusOK},
+		{http.MethodGet, "/shorten", http.StatusOK},
+		{http.MethodGet, "/abc123", http.StatusTooManyRequests},
 	}
+	for i, tt := range tests {
+		if got := serveStatus(handler, tt.method, tt.path, client, nil); got != tt.want {
+			t.Errorf("request %d (%s %s): got status %d, want %d", i+1, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyRateLimitingMiddleware_Identities(t *testing.T) {
+	t.Parallel()
 
+	shared := newTestLimiter(t, 1)
+	policies := []RateLimitPolicy{
+		{Name: "trusted", Identities: []IdentityClass{IdentityAllowlisted}},
+		{Name: "api", Identities: []IdentityClass{IdentityAPIKey}, Limiter: newTestLimiter(t, 3)},
+		{Name: "anonymous", Identities: []IdentityClass{IdentityAnonymous}, Limiter: shared},
+	}
+	apiKey := func(r *http.Request) (string, bool) {
+		key := r.Header.Get("X-API-Key")
+		return key, key == "valid"
+	}
+	handler := PolicyRateLimitingMiddleware(policies,
+		WithAllowlist([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}),
+		WithAPIKeyIdentity(apiKey),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	withKey := func(key string) http.Header { return http.Header{"X-Api-Key": {key}} }
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       int
+	}{
+		{"anonymous within limit", "192.168.1.1:1000", nil, http.StatusOK},
+		{"anonymous over limit", "192.168.1.1:1000", nil, http.StatusTooManyRequests},
+		{"other anonymous client", "192.168.1.2:1000", nil, http.StatusOK},
+		{"allowlisted is unlimited", "10.1.2.3:1000", nil, http.StatusOK},
+		{"allowlisted again", "10.1.2.3:1001", nil, http.StatusOK},
+		{"allowlist ignores forwarded headers", "192.168.1.3:1000", http.Header{"X-Forwarded-For": {"10.1.2.3"}}, http.StatusOK},
+		{"spoofed client is limited", "192.168.1.3:1000", http.Header{"X-Forwarded-For": {"10.1.2.3"}}, http.StatusTooManyRequests},
+		{"api key has its own budget", "192.168.1.1:1004", withKey("valid"), http.StatusOK},
+		{"api key second request", "192.168.1.1:1005", withKey("valid"), http.StatusOK},
+		{"api key third request", "192.168.1.9:1000", withKey("valid"), http.StatusOK},
+		{"api key over limit", "192.168.1.9:1001", withKey("valid"), http.StatusTooManyRequests},
+		{"unverified key counts as anonymous", "192.168.1.1:1000", withKey("forged"), http.StatusTooManyRequests},
+	}
 	for _, tt := range tests {
-		tt := tt
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
+		if got := serveStatus(handler, http.MethodGet, "/", tt.remoteAddr, tt.header); got != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
 
-			got := extractIdentifier(tt.request)
-			if got != tt.wantPref {
-				t.Errorf("extractIdentifier() = %q, want %q", got, tt.wantPref)
-			}
-		})
+func TestPolicyRateLimitingMiddleware_SeparateBudgets(t *testing.T) {
+	t.Parallel()
+
+	// Policies sharing one limiter still count separately.
+	shared := newTestLimiter(t, 1)
+	handler := PolicyRateLimitingMiddleware([]RateLimitPolicy{
+		{Name: "create", Routes: []string{"POST /shorten"}, Limiter: shared},
+		{Name: "default", Limiter: shared},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if got := serveStatus(handler, http.MethodPost, "/shorten", "192.168.1.1:1", nil); got != http.StatusOK {
+		t.Errorf("create: got status %d, want %d", got, http.StatusOK)
+	}
+	if got := serveStatus(handler, http.MethodGet, "/abc", "192.168.1.1:1", nil); got != http.StatusOK {
+		t.Errorf("redirect: got status %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestPolicyRateLimitingMiddleware_NoMatch(t *testing.T) {
+	t.Parallel()
+
+	handler := PolicyRateLimitingMiddleware([]RateLimitPolicy{
+		{Name: "create", Routes: []string{"POST /shorten"}, Limiter: newTestLimiter(t, 1)},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 3; i++ {
+		if got := serveStatus(handler, http.MethodGet, "/abc", "192.168.1.1:1", nil); got != http.StatusOK {
+			t.Errorf("request %d: got status %d, want %d", i+1, got, http.StatusOK)
+		}
+	}
+}
+
+// failingLimiter stands in for a rate limiter whose store is down.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, identifier string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func (failingLimiter) Take(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingLimiter) Peek(ctx context.Context, identifier string) (*domain.RateLimitResult, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestPolicyRateLimitingMiddleware_LimiterError(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	handler := PolicyRateLimitingMiddleware([]RateLimitPolicy{
+		{Name: "create", Limiter: failingLimiter{}},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if got := serveStatus(handler, http.MethodPost, "/shorten", "192.168.1.1:1", nil); got != http.StatusOK {
+		t.Errorf("got status %d, want %d", got, http.StatusOK)
+	}
+	if got := logs.String(); !strings.Contains(got, `Warning: rate limiting skipped for policy "create": connection refused`) {
+		t.Errorf("got log %q, want a warning naming the policy", got)
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		method  string
+		path    string
+		want    bool
+	}{
+		{"/shorten", http.MethodPost, "/shorten", true},
+		{"/shorten", http.MethodGet, "/shorten", true},
+		{"/shorten", http.MethodPost, "/shorten/x", false},
+		{"POST /shorten", http.MethodPost, "/shorten", true},
+		{"post /shorten", http.MethodPost, "/shorten", true},
+		{"POST /shorten", http.MethodGet, "/shorten", false},
+		{"/api/v1/links/", http.MethodGet, "/api/v1/links/abc", true},
+		{"/api/v1/links/", http.MethodGet, "/api/v1/links", false},
+		{"GET /", http.MethodGet, "/abc123", true},
+		{"/", http.MethodDelete, "/api/v1/links/abc", true},
+	}
+	for _, tt := range tests {
+		if got := MatchRoute(tt.pattern, tt.method, tt.path); got != tt.want {
+			t.Errorf("MatchRoute(%q, %q, %q) = %v, want %v", tt.pattern, tt.method, tt.path, got, tt.want)
+		}
 	}
 }
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"url-shortener/internal/domain"
+)
+
+// SessionAuthenticator resolves a session cookie to the logged in user,
+// returning domain.ErrUnauthorized for unknown or expired sessions.
+type SessionAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*domain.User, error)
+}
+
+// SessionMiddleware makes the user logged in with the named cookie the
+// request's domain.Principal, so their links belong to them. A stale or
+// forged cookie leaves the request anonymous; pages that need a login send
+// visitors to it themselves.
+func SessionMiddleware(auth SessionAuthenticator, cookieName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(cookieName)
+			if err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := auth.Authenticate(r.Context(), cookie.Value)
+			if errors.Is(err, domain.ErrUnauthorized) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				log.Printf("Error authenticating session: %v", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			ctx := domain.ContextWithPrincipal(r.Context(), &domain.Principal{
+				OwnerID: user.ID,
+				UserID:  user.ID,
+				Email:   user.Email,
+				Role:    user.Role,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
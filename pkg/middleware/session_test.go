@@ -0,0 +1,91 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"url-shortener/internal/domain"
+	"url-shortener/pkg/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubSessions map[string]*domain.User
+
+func (s stubSessions) Authenticate(ctx context.Context, token string) (*domain.User, error) {
+	if token == "broken" {
+		return nil, errors.New("database unavailable")
+	}
+	user, ok := s[token]
+	if !ok {
+		return nil, domain.ErrUnauthorized
+	}
+	return user, nil
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	sessions := stubSessions{
+		"valid": {ID: "u1", Email: "ada@example.com"},
+		"admin": {ID: "u2", Email: "grace@example.com", Role: domain.RoleAdmin},
+	}
+
+	tests := []struct {
+		name          string
+		cookie        *http.Cookie
+		wantStatus    int
+		wantPrincipal *domain.Principal
+	}{
+		{
+			name:       "no cookie",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:          "valid session",
+			cookie:        &http.Cookie{Name: "session", Value: "valid"},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: &domain.Principal{OwnerID: "u1", UserID: "u1", Email: "ada@example.com"},
+		},
+		{
+			name:          "user role",
+			cookie:        &http.Cookie{Name: "session", Value: "admin"},
+			wantStatus:    http.StatusOK,
+			wantPrincipal: &domain.Principal{OwnerID: "u2", UserID: "u2", Email: "grace@example.com", Role: domain.RoleAdmin},
+		},
+		{
+			name:       "other cookie",
+			cookie:     &http.Cookie{Name: "theme", Value: "valid"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "stale session stays anonymous",
+			cookie:     &http.Cookie{Name: "session", Value: "expired"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "authenticator failure",
+			cookie:     &http.Cookie{Name: "session", Value: "broken"},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *domain.Principal
+			handler := middleware.SessionMiddleware(sessions, "session")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = domain.PrincipalFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantPrincipal, got)
+		})
+	}
+}
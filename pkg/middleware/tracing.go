@@ -24,6 +24,7 @@ func TracingMiddleware(next http.Handler) http.Handler {
 				semconv.URLScheme(r.URL.Scheme),
 				semconv.ServerAddress(r.Host),
 				semconv.UserAgentOriginal(r.UserAgent()),
+				semconv.ClientAddress(ClientIP(r)),
 			),
 		)
 		defer span.End()